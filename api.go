@@ -2,6 +2,7 @@
 package beat
 
 import (
+	"context"
 	"time"
 
 	"github.com/uniyakcom/beat/core"
@@ -17,15 +18,116 @@ type Event = core.Event
 // Handler 导出Handler类型
 type Handler = core.Handler
 
+// SubInfo 导出订阅描述类型
+type SubInfo = core.SubInfo
+
 // PanicHandler 导出PanicHandler类型
 type PanicHandler = core.PanicHandler
 
+// PanicInfoHandler 导出带订阅信息的 panic 回调类型
+type PanicInfoHandler = core.PanicInfoHandler
+
+// ErrorHandler 导出ErrorHandler类型
+type ErrorHandler = core.ErrorHandler
+
+// HandlerError 导出单个 handler 的失败记录
+type HandlerError = core.HandlerError
+
+// EmitError 导出同步发布的 handler 失败汇总（ErrorRunAll）
+type EmitError = core.EmitError
+
+// SubOption 导出订阅选项类型
+type SubOption = core.SubOption
+
+// Completion 导出异步发布完成句柄（EmitAwait 返回）
+type Completion = core.Completion
+
+// Awaiter 导出可等待处理完成的 Bus 接口（bus.(beat.Awaiter)）
+type Awaiter = core.Awaiter
+
+// Requester 导出支持请求/回复的 Bus 接口（bus.(beat.Requester)）
+type Requester = core.Requester
+
+// Retainer 导出支持保留（sticky）事件的 Bus 接口（bus.(beat.Retainer)）
+type Retainer = core.Retainer
+
+// Delayer 导出支持延迟/定时发布的 Bus 接口（bus.(beat.Delayer)）
+type Delayer = core.Delayer
+
+// CancelFunc 导出延迟发布的取消函数（EmitAfter / EmitAt 返回）
+type CancelFunc = core.CancelFunc
+
+// DelayedPendingError 导出 Drain 时未发布的延迟事件（DelayReport 策略）
+type DelayedPendingError = core.DelayedPendingError
+
+// TrySubscriber 导出返回订阅错误的 Bus 接口（bus.(beat.TrySubscriber)）
+type TrySubscriber = core.TrySubscriber
+
+// GroupSubscriber 导出支持订阅组的 Bus 接口（bus.(beat.GroupSubscriber)）
+type GroupSubscriber = core.GroupSubscriber
+
+// GroupBalance 导出订阅组均衡策略类型
+type GroupBalance = core.GroupBalance
+
+// RatePolicy 导出发布侧限流策略类型
+type RatePolicy = core.RatePolicy
+
+// SubRateMode 导出订阅侧限流模式类型
+type SubRateMode = core.SubRateMode
+
+// Operator 导出订阅算子接口（实现见 operator 包）
+type Operator = core.Operator
+
+// PatternSyntax 导出 pattern 语法类型
+type PatternSyntax = core.PatternSyntax
+
+// Pattern 导出编译后的 pattern
+type Pattern = core.Pattern
+
+// CompilePattern 按默认语法（DotSyntax）校验并编译 pattern；其他语法用 PatternSyntax.Compile
+//
+// 用法:
+//
+//	if _, err := beat.CompilePattern(userInput); err != nil {
+//	    return err // errors.Is(err, beat.ErrBadPattern)
+//	}
+func CompilePattern(pattern string) (*Pattern, error) {
+	return core.CompilePattern(pattern)
+}
+
+// ScalePolicy 导出弹性 worker 配置（仅 Async 实现生效）
+type ScalePolicy = core.ScalePolicy
+
+// ScaleEvent 导出 worker 数调整记录
+type ScaleEvent = core.ScaleEvent
+
+// ScaleObserver 导出 worker 数调整回调类型
+type ScaleObserver = core.ScaleObserver
+
+// Resizer 导出支持运行期调整 worker 数的 Bus 接口（bus.(beat.Resizer)）
+type Resizer = core.Resizer
+
+// QueueStat 导出单个队列的积压与延迟
+type QueueStat = core.QueueStat
+
+// QueueStatter 导出支持按队列查询积压与延迟的 Bus 接口（bus.(beat.QueueStatter)）
+type QueueStatter = core.QueueStatter
+
+// LatencyStatter 导出支持按 pattern 查询 handler 耗时分布的 Bus 接口（bus.(beat.LatencyStatter)）
+type LatencyStatter = core.LatencyStatter
+
 // Profile 导出Profile
 type Profile = optimize.Profile
 
 // Auto 导出Auto配置
 type Auto = optimize.Auto
 
+// Opt 导出Profile修改函数（ForXxx 可选参数）
+type Opt = optimize.Opt
+
+// Stage 导出Pipeline阶段类型（Flow 模式）
+type Stage = core.Stage
+
 // ═══════════════════════════════════════════════════════════════════
 // 第零层：New() 零配置入口
 // ═══════════════════════════════════════════════════════════════════
@@ -38,8 +140,8 @@ type Auto = optimize.Auto
 //
 //	bus, _ := event.New()
 //	defer bus.Close()
-func New() (Bus, error) {
-	return Option(optimize.AutoDetect())
+func New(opts ...Opt) (Bus, error) {
+	return Option(optimize.AutoDetect().Apply(opts...))
 }
 
 // ═══════════════════════════════════════════════════════════════════
@@ -49,22 +151,29 @@ func New() (Bus, error) {
 // ForSync 创建同步直调 Bus
 // 用途: RPC调用、API中间件、权限验证
 // 性能: Emit ~15ns/op，UnsafeEmit ~4.4ns/op，error 返回，零开销
-func ForSync() (Bus, error) {
-	return Option(optimize.Sync())
+func ForSync(opts ...Opt) (Bus, error) {
+	return Option(optimize.Sync().Apply(opts...))
 }
 
 // ForAsync 创建 Per-P SPSC 异步高吞吐 Bus
 // 用途: 发布订阅、日志聚合、实时推送、高频交易
 // 性能: ~33ns/op 单线程，~32ns/op 高并发，零 CAS，零分配
-func ForAsync() (Bus, error) {
-	return Option(optimize.Async())
+func ForAsync(opts ...Opt) (Bus, error) {
+	return Option(optimize.Async().Apply(opts...))
 }
 
 // ForFlow 创建多阶段 Pipeline 流处理 Bus
 // 用途: 实时ETL、窗口聚合、批量数据加载
 // 性能: ~70ns/op 单线程，多阶段 Pipeline，per-shard 精准唤醒
-func ForFlow() (Bus, error) {
-	return Option(optimize.Flow())
+//
+// 用法:
+//
+//	bus, _ := beat.ForFlow(
+//	    beat.WithStages(dropEmpty, enrich),
+//	    beat.WithStageErrorPolicy(beat.StageRouteError, onStageErr),
+//	)
+func ForFlow(opts ...Opt) (Bus, error) {
+	return Option(optimize.Flow().Apply(opts...))
 }
 
 // ═══════════════════════════════════════════════════════════════════
@@ -126,6 +235,39 @@ func On(pattern string, handler Handler) uint64 {
 	return defaultBus.On(pattern, handler)
 }
 
+// OnWith 包级带选项订阅事件（Sync 语义）
+//
+// 用法:
+//
+//	beat.OnWith("order.*", handle, beat.WithErrorSink(func(err error, evt *beat.Event, sub beat.SubInfo) {
+//	    log.Printf("order handler %d failed on %s: %v", sub.ID, evt.Type, err)
+//	}))
+func OnWith(pattern string, handler Handler, opts ...SubOption) uint64 {
+	return defaultBus.(core.OptionSubscriber).OnWith(pattern, handler, opts...)
+}
+
+// TryOn 包级带选项订阅事件（Sync 语义），pattern 语法无效时返回包装 ErrBadPattern 的错误
+//
+// 用法:
+//
+//	if _, err := beat.TryOn(userPattern, handle); err != nil {
+//	    return err // errors.Is(err, beat.ErrBadPattern)
+//	}
+func TryOn(pattern string, handler Handler, opts ...SubOption) (uint64, error) {
+	return defaultBus.(core.TrySubscriber).TryOn(pattern, handler, opts...)
+}
+
+// OnGroup 包级以订阅组（竞争消费者）订阅事件，同组成员中只有一个处理每个事件
+//
+// 用法:
+//
+//	for i := 0; i < 4; i++ {
+//	    beat.OnGroup("job.*", "workers", runJob)
+//	}
+func OnGroup(pattern, group string, handler Handler, opts ...SubOption) uint64 {
+	return defaultBus.(core.GroupSubscriber).OnGroup(pattern, group, handler, opts...)
+}
+
 // Off 包级取消订阅
 func Off(id uint64) {
 	defaultBus.Off(id)
@@ -140,6 +282,15 @@ func Emit(evt *Event) error {
 	return defaultBus.Emit(evt)
 }
 
+// EmitCtx 包级携带 context 发布事件（Sync 语义，ctx 结束后不再调用剩余 handler）
+//
+// 用法:
+//
+//	err := beat.EmitCtx(r.Context(), &beat.Event{Type: "user.created", Data: []byte("alice")})
+func EmitCtx(ctx context.Context, evt *Event) error {
+	return defaultBus.(core.ContextEmitter).EmitCtx(ctx, evt)
+}
+
 // UnsafeEmit 包级发布事件（零保护，极致性能，不捕获 handler panic）
 // 注意: 不更新 Stats().Emitted 计数，以实现最低开销。
 func UnsafeEmit(evt *Event) error {
@@ -151,6 +302,48 @@ func EmitMatch(evt *Event) error {
 	return defaultBus.EmitMatch(evt)
 }
 
+// EmitMatchCtx 包级携带 context 发布事件（支持通配符匹配）
+func EmitMatchCtx(ctx context.Context, evt *Event) error {
+	return defaultBus.(core.ContextEmitter).EmitMatchCtx(ctx, evt)
+}
+
+// EmitAfter 包级延迟发布：d 后以 Emit 语义发布，返回取消函数
+//
+// 用法:
+//
+//	cancel := beat.EmitAfter(15*time.Minute, &beat.Event{Type: "reminder.due", ID: taskID})
+//	defer cancel()
+func EmitAfter(d time.Duration, evt *Event) CancelFunc {
+	return defaultBus.(core.Delayer).EmitAfter(d, evt)
+}
+
+// EmitAt 包级定时发布：在 t 时刻以 Emit 语义发布，返回取消函数
+func EmitAt(t time.Time, evt *Event) CancelFunc {
+	return defaultBus.(core.Delayer).EmitAt(t, evt)
+}
+
+// Request 包级请求/回复（Sync 语义，handler 同步执行，返回第一个回复）
+//
+// 用法:
+//
+//	beat.On("auth.check", func(e *beat.Event) error {
+//	    return beat.Reply(e, &beat.Event{Data: []byte("ok")})
+//	})
+//	resp, err := beat.Request(ctx, &beat.Event{Type: "auth.check", Data: token})
+func Request(ctx context.Context, evt *Event) (*Event, error) {
+	return defaultBus.(core.Requester).Request(ctx, evt)
+}
+
+// RequestAll 包级请求/回复，收集全部回复
+func RequestAll(ctx context.Context, evt *Event) ([]*Event, error) {
+	return defaultBus.(core.Requester).RequestAll(ctx, evt)
+}
+
+// Reply 回复请求事件（handler 内调用，req 为 handler 收到的请求事件）
+func Reply(req, resp *Event) error {
+	return core.Reply(req, resp)
+}
+
 // UnsafeEmitMatch 包级发布事件（通配符匹配，零保护，极致性能）
 // 注意: 不更新 Stats() 计数，以实现最低开销。
 func UnsafeEmitMatch(evt *Event) error {
@@ -0,0 +1,96 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+)
+
+// Completion 异步发布完成句柄（EmitAwait / EmitMatchAwait 返回）
+// 事件的所有匹配 handler 执行完毕（或事件被丢弃、入队失败）后完成，
+// 并携带期间收集到的全部 handler error（panic 以 error 形式记录）。
+//
+// 用法:
+//
+//	c := bus.(core.Awaiter).EmitAwait(ctx, evt)
+//	if err := c.Wait(ctx); err != nil {
+//	    log.Println("handlers failed:", err)
+//	}
+type Completion struct {
+	done     chan struct{}
+	resolved atomic.Bool
+	mu       sync.Mutex
+	errs     []error
+}
+
+// NewCompletion 创建未完成的句柄（由 Bus 实现调用）
+func NewCompletion() *Completion {
+	return &Completion{done: make(chan struct{})}
+}
+
+// ResolvedCompletion 创建已完成的句柄（同步执行完毕或入队失败时使用，err 可为 nil）
+func ResolvedCompletion(err error) *Completion {
+	c := NewCompletion()
+	c.AddError(err)
+	c.Resolve()
+	return c
+}
+
+// AddError 记录一个 handler error（nil 忽略；并发安全，Resolve 之后调用无效）
+func (c *Completion) AddError(err error) {
+	if err == nil || c.resolved.Load() {
+		return
+	}
+	c.mu.Lock()
+	c.errs = append(c.errs, err)
+	c.mu.Unlock()
+}
+
+// Resolve 标记完成并唤醒等待者（由 Bus 实现调用，仅首次生效）
+func (c *Completion) Resolve() {
+	if c.resolved.CompareAndSwap(false, true) {
+		close(c.done)
+	}
+}
+
+// Done 返回完成信号通道
+func (c *Completion) Done() <-chan struct{} {
+	return c.done
+}
+
+// Wait 阻塞直到完成或 ctx 结束
+// 完成时返回 Err()；ctx 先结束时返回 ctx.Err()。
+func (c *Completion) Wait(ctx context.Context) error {
+	select {
+	case <-c.done:
+		return c.Err()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Err 返回合并后的 handler error（errors.Join；无错误或尚未完成时为 nil）
+func (c *Completion) Err() error {
+	if !c.resolved.Load() {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return errors.Join(c.errs...)
+}
+
+// Errors 返回各 handler error 的副本（尚未完成时为 nil）
+func (c *Completion) Errors() []error {
+	if !c.resolved.Load() {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.errs) == 0 {
+		return nil
+	}
+	out := make([]error, len(c.errs))
+	copy(out, c.errs)
+	return out
+}
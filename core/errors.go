@@ -0,0 +1,81 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ErrorMode 同步 Emit 遇到 handler 失败时的处理方式（Sync Bus 同步模式）
+type ErrorMode uint8
+
+const (
+	// ErrorFailFast 第一个 handler 失败（error 或 panic）即停止分发，返回该 error（默认）
+	ErrorFailFast ErrorMode = iota
+	// ErrorRunAll 调用全部匹配 handler，结束后以 *EmitError 汇总返回所有失败（无失败返回 nil）
+	ErrorRunAll
+)
+
+// HandlerError 单个 handler 的失败记录
+type HandlerError struct {
+	Sub   SubInfo // 失败的订阅
+	Event *Event  // 触发失败的事件（EmitBatch 时用于区分事件）
+	Err   error   // handler 返回的 error；Panic 时为包含 recover 值的 error
+	Panic bool    // 是否为捕获的 handler panic
+}
+
+// Error 实现 error（Panic 时 Err 本身已标明 "handler panic"）
+func (e *HandlerError) Error() string {
+	return fmt.Sprintf("handler %d (%s): %v", e.Sub.ID, e.Sub.Pattern, e.Err)
+}
+
+// Unwrap 返回 handler 的原始 error
+func (e *HandlerError) Unwrap() error {
+	return e.Err
+}
+
+// EmitError 一次同步发布（或一批）中全部 handler 失败的汇总（ErrorRunAll）
+// 与 errors.Join 相同实现 Unwrap() []error：errors.Is/As 可匹配任一 handler 的 error，
+// errors.As(err, &*HandlerError) 取得第一条失败记录。
+//
+// 用法:
+//
+//	var ee *beat.EmitError
+//	if errors.As(bus.Emit(evt), &ee) {
+//	    for _, f := range ee.Failures {
+//	        log.Printf("rule %s failed: %v", f.Sub.Pattern, f.Err)
+//	    }
+//	}
+type EmitError struct {
+	Failures []*HandlerError // 按调用顺序排列
+}
+
+// Error 实现 error（每条失败一行）
+func (e *EmitError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "beat: %d handler(s) failed", len(e.Failures))
+	for _, f := range e.Failures {
+		b.WriteString("\n")
+		b.WriteString(f.Error())
+	}
+	return b.String()
+}
+
+// Unwrap 返回每条失败记录（供 errors.Is/As 遍历）
+func (e *EmitError) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, f := range e.Failures {
+		errs[i] = f
+	}
+	return errs
+}
+
+// DelayedPendingError Drain（DelayReport 策略）时尚未到期、未发布的延迟事件
+// 调用方可据此持久化事件，重启后重新调度。
+type DelayedPendingError struct {
+	Events []*Event // 按到期时间排序
+}
+
+// Error 实现 error
+func (e *DelayedPendingError) Error() string {
+	return fmt.Sprintf("beat: %d delayed event(s) not emitted", len(e.Events))
+}
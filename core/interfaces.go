@@ -2,37 +2,196 @@
 package core
 
 import (
+	"context"
+	"errors"
 	"time"
+
+	"github.com/uniyakcom/beat/util"
 )
 
+// ErrQueueFull 队列满且在超时内未能入队（OverflowBlockTimeout 策略）
+var ErrQueueFull = errors.New("beat: queue full")
+
+// ErrClosed Bus 已关闭，等待中的操作无法完成（Barrier 返回）
+var ErrClosed = errors.New("beat: bus closed")
+
 // Event 事件（字段按大小降序排列 → 减少编译器padding → 最小化结构体体积）
 // Hot fields (Type, Data) 在前，Cold fields (Metadata, Timestamp) 在后
 type Event struct {
 	Data      []byte            // 24 bytes (hot: 事件数据，读频率最高)
 	Type      string            // 16 bytes (hot: 事件类型，用于路由)
 	ID        string            // 16 bytes (warm)
+	Key       string            // 16 bytes (warm: 分区键，Async 有序模式按此路由，空=不保序)
 	Source    string            // 16 bytes (cold)
 	Metadata  map[string]string // 8 bytes  (cold: map指针，含GC扫描开销)
 	Timestamp time.Time         // 24 bytes (cold: 含wall+ext+loc指针)
+
+	ctx   context.Context // 16 bytes (cold: EmitCtx 设置，handler 经 Context() 读取)
+	done  *Completion     // 8 bytes  (cold: EmitAwait 设置，消费端处理完毕后 Resolve)
+	reply Replier         // 16 bytes (cold: Request 设置，handler 经 Reply 回复)
+}
+
+// Context 返回事件关联的 context（未设置时返回 context.Background()）
+// 由 EmitCtx / EmitMatchCtx 设置，handler 可据此感知截止时间、取消信号与链路追踪值。
+func (e *Event) Context() context.Context {
+	if e.ctx != nil {
+		return e.ctx
+	}
+	return context.Background()
+}
+
+// SetContext 设置事件关联的 context（nil 表示清除）
+func (e *Event) SetContext(ctx context.Context) {
+	e.ctx = ctx
+}
+
+// Completion 返回事件关联的完成句柄（非 EmitAwait 发布时为 nil）
+func (e *Event) Completion() *Completion {
+	return e.done
+}
+
+// SetCompletion 关联完成句柄（由 Bus 实现在 EmitAwait 中调用；nil 表示清除）
+func (e *Event) SetCompletion(c *Completion) {
+	e.done = c
 }
 
 // Handler 事件处理器
 type Handler func(*Event) error
 
+// SubInfo 订阅描述（回调中用于定位出错的订阅）
+type SubInfo struct {
+	ID      uint64 // On 返回的订阅 ID
+	Pattern string // 订阅模式
+	Group   string // 订阅组（OnGroup 订阅，否则为空）
+}
+
 // PanicHandler panic 回调（可选，用户注册后接收 panic 通知）
+// 需要出错的订阅信息时使用 PanicInfoHandler。
 type PanicHandler func(recovered interface{}, evt *Event)
 
+// PanicInfoHandler 带订阅信息的 panic 回调
+// recovered 为 recover() 返回值，evt 为触发 panic 的事件，sub 为出错的订阅。
+// Flow Stage 内的 panic 不属于任何订阅：evt 为 nil，sub 为零值。
+// 回调在 handler 所在 goroutine 同步执行，应保持轻量且不得 panic。
+type PanicInfoHandler func(recovered interface{}, evt *Event, sub SubInfo)
+
+// PanicInfo 将 PanicHandler 适配为 PanicInfoHandler（忽略订阅信息；h 为 nil 时返回 nil）
+func PanicInfo(h PanicHandler) PanicInfoHandler {
+	if h == nil {
+		return nil
+	}
+	return func(recovered interface{}, evt *Event, _ SubInfo) { h(recovered, evt) }
+}
+
+// ErrorHandler handler 错误回调（可选，接收每一次 handler 返回的 error）
+// 与 PanicInfoHandler 相同，回调在 handler 所在 goroutine 同步执行，应保持轻量。
+// On / OnWith 的 pattern 语法无效时也会回调一次：err 包装 ErrBadPattern，evt 为 nil，sub 仅含 Pattern。
+type ErrorHandler func(err error, evt *Event, sub SubInfo)
+
+// Stage Pipeline 处理阶段（Flow 模式）
+// 接收一个批次，返回交给下一阶段/订阅者的批次：
+//   - 可原地过滤（batch[:n]）、替换元素（转换/富化）或返回新切片
+//   - 返回空批次表示整批丢弃，后续阶段与订阅者均不再执行
+//   - 返回 error 时按 StageErrorPolicy 处理
+type Stage func(batch []*Event) ([]*Event, error)
+
+// StageErrorPolicy Stage 返回 error 时的批次处理策略
+type StageErrorPolicy uint8
+
+const (
+	// StageSkipBatch 丢弃整批（默认）：后续阶段与订阅者均不执行
+	StageSkipBatch StageErrorPolicy = iota
+	// StageRouteError 交给 StageErrorHandler 处理后丢弃整批
+	StageRouteError
+	// StageContinue 忽略错误继续后续阶段（阶段返回 nil 批次时沿用其输入批次）
+	StageContinue
+)
+
+// StageErrorHandler Stage 错误回调（StageRouteError 策略下调用）
+// batch 为出错阶段的输入批次，仅在回调期间有效，需保留时请复制。
+type StageErrorHandler func(err error, batch []*Event)
+
+// OverflowPolicy 队列（SPSC ring）满时的处理策略（Async / Sync 异步模式）
+type OverflowPolicy uint8
+
+const (
+	// OverflowBlock 阻塞重试直到有空位（默认）
+	OverflowBlock OverflowPolicy = iota
+	// OverflowBlockTimeout 阻塞重试至超时，超时后 Emit 返回 ErrQueueFull 并计入 Dropped
+	OverflowBlockTimeout
+	// OverflowDropNewest 丢弃当前发布的事件并计入 Dropped，Emit 立即返回
+	OverflowDropNewest
+	// OverflowDropOldest 写入与 ring 等长的溢出队列，溢出队列也满时丢弃其中最旧的事件并计入 Dropped
+	OverflowDropOldest
+	// OverflowSpill 写入无界溢出队列，永不阻塞、永不丢弃（内存随积压增长）
+	OverflowSpill
+)
+
+// DelayPolicy Drain 时对尚未到期的延迟事件（EmitAfter / EmitAt）的处理方式
+// Close（及 Drain(0)）总是丢弃未到期事件。
+type DelayPolicy uint8
+
+const (
+	// DelayFire 按到期顺序立即发布全部未到期事件，再排空队列（默认）
+	DelayFire DelayPolicy = iota
+	// DelayReport 不发布未到期事件，Drain 以 *DelayedPendingError 返回它们
+	DelayReport
+)
+
+// CancelFunc 取消延迟发布；事件尚未发布时取消成功返回 true，已发布或已取消返回 false
+type CancelFunc func() bool
+
+// ScalePolicy 弹性 worker 配置（仅 Async 实现生效，Max>0 时开启）
+// 采样间隔内 ring 积压持续增长且 worker 几乎不泊车时加一个 worker；
+// 积压为零且 worker 大部分时间泊车时减一个 worker。加减各需连续多次采样满足条件（滞回），避免抖动。
+type ScalePolicy struct {
+	Min      int           // worker 下限（0=1）
+	Max      int           // worker 上限（0=不开启弹性伸缩）
+	Interval time.Duration // 采样间隔（0=100ms，<0 关闭自动伸缩，只通过 Resizer.Resize 手动调整）
+	Observer ScaleObserver // 伸缩回调（可为 nil）
+}
+
+// ScaleEvent 一次 worker 数调整
+type ScaleEvent struct {
+	From, To int     // 调整前后的 worker 数
+	Reason   string  // "manual"（Resize 调用）/ "backlog"（积压扩容）/ "idle"（空闲缩容）
+	Backlog  int64   // 决策时的 ring 积压总数
+	Idle     float64 // 决策时采样间隔内 worker 泊车时间占比（0~1，manual 时为 0）
+}
+
+// ScaleObserver worker 数调整回调（自动伸缩时在采样 goroutine 中调用，Resize 时在调用方 goroutine 中调用）
+type ScaleObserver func(ScaleEvent)
+
 // Stats 事件总线运行时统计
 type Stats struct {
 	Emitted   int64 // 已发布事件总数
 	Processed int64 // 已处理事件总数（handler 执行完成）
 	Panics    int64 // handler panic 次数
-	Depth     int64 // 当前队列积压深度（仅 Ring Buffer 实现有值）
+	Depth     int64 // 当前积压深度（Ring Buffer 积压 + 未到期的延迟事件）
+	Errors    int64 // handler 返回 error 次数（不含 panic）
+	Dropped   int64 // 因溢出策略丢弃（或超时未能入队）的事件数
+
+	Duplicates int64 // 按 Event.ID 去重丢弃的重复事件数（beat.WithDedup）
+
+	RateLimited        int64 // 发布侧限流命中次数（拒绝、丢弃与延迟发布均计入）
+	HandlerRateLimited int64 // 订阅侧限流命中次数（丢弃或合并的 handler 调用）
+
+	Filtered int64 // 被订阅负载谓词（WithWhere）过滤、未交给 handler 的调用次数
+
+	MatchCacheHits      int64 // 通配符匹配结果缓存命中次数（无通配符订阅时的精确快速路径不经过缓存）
+	MatchCacheMisses    int64 // 缓存未命中、遍历 Trie 的次数
+	MatchCacheEvictions int64 // 缓存满时淘汰的条目数（见 WithMatchCacheSize）
+
+	Retained      int64 // 当前保留（sticky）事件数（每个事件类型最多一个）
+	RetainedBytes int64 // 保留事件 Data 总字节数
+
+	// ErrorsByPattern 按订阅模式分组的 handler error 次数（无错误时为 nil）
+	ErrorsByPattern map[string]int64
 }
 
 // Bus 事件总线接口
 type Bus interface {
-	// On 订阅事件，返回订阅ID
+	// On 订阅事件，返回订阅ID（pattern 语法无效时不订阅并返回 0，可先用 CompilePattern 校验）
 	On(pattern string, handler Handler) uint64
 
 	// Off 取消订阅
@@ -106,6 +265,149 @@ type ErrorReporter interface {
 	ClearError()
 }
 
+// PanicNotifier 支持运行时注册 panic 回调的 Bus（三种实现均支持）
+// 单个 handler panic 被捕获后，回调收到 recover 值、事件（及订阅信息）；
+// 同一事件的其余 handler 仍照常执行（Sync 同步 Emit 除外：panic 以 error 返回，终止本次分发）。
+// 两种回调共用一个槽位，后注册的生效。
+//
+// 用法:
+//
+//	if pn, ok := bus.(core.PanicNotifier); ok {
+//	    pn.SetPanicInfoHandler(func(r interface{}, evt *core.Event, sub core.SubInfo) {
+//	        log.Printf("handler %d (%s) panic on %s: %v", sub.ID, sub.Pattern, evt.Type, r)
+//	    })
+//	}
+type PanicNotifier interface {
+	// SetPanicHandler 注册 panic 回调（nil 表示取消）
+	SetPanicHandler(h PanicHandler)
+	// SetPanicInfoHandler 注册带订阅信息的 panic 回调（nil 表示取消）
+	SetPanicInfoHandler(h PanicInfoHandler)
+}
+
+// ErrorNotifier 支持运行时注册错误回调的 Bus（三种实现均支持）
+// 每个返回 error 的 handler 都会触发一次回调，不受 ErrorReporter 单槽位覆盖影响。
+//
+// 用法:
+//
+//	if en, ok := bus.(core.ErrorNotifier); ok {
+//	    en.SetErrorHandler(func(err error, evt *core.Event, sub core.SubInfo) {
+//	        log.Printf("handler %d (%s) failed on %s: %v", sub.ID, sub.Pattern, evt.Type, err)
+//	    })
+//	}
+type ErrorNotifier interface {
+	// SetErrorHandler 注册错误回调（nil 表示取消）
+	SetErrorHandler(h ErrorHandler)
+}
+
+// OptionSubscriber 支持带选项订阅的 Bus（三种实现均支持）
+//
+// 用法:
+//
+//	if os, ok := bus.(core.OptionSubscriber); ok {
+//	    os.OnWith("order.*", handle, core.WithErrorSink(onOrderErr))
+//	}
+type OptionSubscriber interface {
+	// OnWith 带选项订阅事件，返回订阅ID（可用 Off 取消）
+	OnWith(pattern string, handler Handler, opts ...SubOption) uint64
+}
+
+// TrySubscriber 支持返回订阅错误的 Bus（三种实现均支持）
+// On / OnWith 遇到语法无效的 pattern 只返回 0（错误经 ErrorHandler 上报，evt 为 nil）；
+// TryOn 同时返回包装 ErrBadPattern 的错误，适合订阅用户输入的 pattern。
+//
+// 用法:
+//
+//	id, err := bus.(core.TrySubscriber).TryOn(userPattern, handle)
+//	if errors.Is(err, core.ErrBadPattern) {
+//	    return err
+//	}
+type TrySubscriber interface {
+	// TryOn 带选项订阅事件，返回订阅ID；pattern 语法无效时不订阅，返回 0 与包装 ErrBadPattern 的错误
+	TryOn(pattern string, handler Handler, opts ...SubOption) (uint64, error)
+}
+
+// ContextEmitter 支持携带 context 发布的 Bus（三种实现均支持）
+// ctx 随事件传递给 handler（Event.Context()）；ctx 已结束时不发布并返回 ctx.Err()。
+//   - Sync 同步模式: 每个 handler 调用前检查 ctx，结束后不再调用剩余 handler 并返回 ctx.Err()
+//   - Async / Sync 异步模式: ring 满需要等待时，ctx 结束即放弃入队并返回 ctx.Err()
+//
+// 用法:
+//
+//	if ce, ok := bus.(core.ContextEmitter); ok {
+//	    ctx, cancel := context.WithTimeout(r.Context(), 50*time.Millisecond)
+//	    defer cancel()
+//	    err := ce.EmitCtx(ctx, evt)
+//	}
+type ContextEmitter interface {
+	// EmitCtx 携带 context 发布事件
+	EmitCtx(ctx context.Context, evt *Event) error
+	// EmitMatchCtx 携带 context 发布事件（支持通配符匹配）
+	EmitMatchCtx(ctx context.Context, evt *Event) error
+}
+
+// Awaiter 支持等待处理完成的 Bus（三种实现均支持）
+//   - EmitAwait / EmitMatchAwait: 发布并返回完成句柄，所有匹配 handler 执行完毕后完成
+//   - Barrier: 等待调用前已发布的全部事件处理完毕（不关闭 Bus）
+//
+// 句柄同时携带全部 handler error；入队失败、被溢出策略丢弃时以对应 error 完成。
+// Close 时仍在队列中的事件不会完成，等待方应使用带超时的 ctx。
+//
+// 用法:
+//
+//	aw := bus.(core.Awaiter)
+//	c := aw.EmitAwait(ctx, &core.Event{Type: "order.created"})
+//	if err := c.Wait(ctx); err != nil { ... }
+//
+//	_ = aw.Barrier(ctx) // 此前发布的事件均已处理
+type Awaiter interface {
+	// EmitAwait 发布事件（Emit 语义）并返回完成句柄，ctx 语义同 EmitCtx（可为 nil）
+	EmitAwait(ctx context.Context, evt *Event) *Completion
+	// EmitMatchAwait 发布事件（EmitMatch 语义）并返回完成句柄
+	EmitMatchAwait(ctx context.Context, evt *Event) *Completion
+	// Barrier 等待调用前已发布的事件全部处理完毕，ctx 结束时返回 ctx.Err()
+	Barrier(ctx context.Context) error
+}
+
+// Retainer 支持保留（sticky）事件的 Bus（三种实现均支持）
+// 每个事件类型保留最新一个事件的副本；之后注册的匹配订阅（含通配符 pattern）在 On 返回前
+// 于调用方 goroutine 内立即收到保留事件，handler error/panic 照常上报。
+// 除 EmitRetain 外，Bus 级配置的保留 pattern（beat.WithRetain）匹配的事件发布时自动保留。
+// 订阅组成员不接收保留事件；与并发发布竞争时，新订阅可能先收到更新的实时事件。
+//
+// 用法:
+//
+//	rt := bus.(core.Retainer)
+//	rt.EmitRetain(&core.Event{Type: "config.updated", Data: cfg})
+//	bus.On("config.*", apply) // 立即收到 config.updated
+type Retainer interface {
+	// EmitRetain 保留事件副本后发布（Emit 语义）
+	EmitRetain(evt *Event) error
+	// Retained 返回类型匹配 pattern 的保留事件副本（支持通配符，按类型排序）
+	Retained(pattern string) []*Event
+	// ClearRetained 清除类型匹配 pattern 的保留事件，返回清除数量
+	ClearRetained(pattern string) int
+}
+
+// Delayer 支持延迟/定时发布的 Bus（三种实现均支持）
+// 事件挂在每个 Bus 一个的分层时间轮上（精度 1ms），到期后由时间轮 goroutine 以 Emit 语义发布：
+// 大量未到期事件只占用时间轮槽位，不为每个事件创建 goroutine 或 time.Timer。
+//   - 未到期事件计入 Stats().Depth；Drain 时按 DelayPolicy 发布或返回，Close 时丢弃
+//   - Sync 同步模式下 handler 在时间轮 goroutine 中执行，耗时 handler 会推迟其后到期的事件
+//   - 到期前不得修改 evt
+//
+// 用法:
+//
+//	dl := bus.(core.Delayer)
+//	cancel := dl.EmitAfter(30*time.Minute, &core.Event{Type: "order.timeout", ID: orderID})
+//	// 订单已支付
+//	cancel()
+type Delayer interface {
+	// EmitAfter d 后发布事件（d <= 0 时于下一个时间轮刻度发布）
+	EmitAfter(d time.Duration, evt *Event) CancelFunc
+	// EmitAt 在 t 时刻发布事件（t 已过去时于下一个时间轮刻度发布）
+	EmitAt(t time.Time, evt *Event) CancelFunc
+}
+
 // Prewarmer 支持预热的 Bus（如 Sync 模式）
 //
 // 用法:
@@ -129,3 +431,66 @@ type BatchStatter interface {
 	// BatchStats 返回批处理统计（已处理事件数, 批次数）
 	BatchStats() (processed, batches uint64)
 }
+
+// Resizer 支持运行期调整 worker 数的 Bus（Async 实现）
+// 未开启弹性伸缩（ScalePolicy.Max=0）时 worker 数固定，Resize 不生效并返回当前值。
+//
+// 用法:
+//
+//	if rs, ok := bus.(core.Resizer); ok {
+//	    rs.Resize(8) // 按 [Min, Max] 截断
+//	}
+type Resizer interface {
+	// Resize 将 worker 数调整为 n（按 [Min, Max] 截断），返回调整后的 worker 数
+	Resize(n int) int
+	// Workers 返回当前 worker 数
+	Workers() int
+}
+
+// QueueStat 单个队列的积压与延迟（QueueStatter 返回）
+// 时间戳为每 32 个入队采样一个，OldestAge / Latency* 均基于采样元素；溢出队列中的元素不采样。
+// 延迟只给出分位数摘要（自创建起累计），不携带完整直方图，逐队列轮询时无需复制分桶。
+type QueueStat struct {
+	Kind       string        // 队列类型: "ring"（Per-P ring）/ "keyed"（按键有序 ring）/ "shared"（共享 ring）/ "shard"（Flow 分片）
+	Index      int           // 同类队列中的序号
+	Depth      int64         // 当前积压（含溢出队列）
+	Capacity   int64         // ring 容量（不含溢出队列）
+	OldestAge  time.Duration // 积压中最早一个采样事件已等待的时长（无采样事件积压时为 0）
+	Samples    uint64        // 已记录延迟的采样事件数
+	LatencyP50 time.Duration // 入队→取出分发延迟的中位数（无采样时为 0）
+	LatencyP99 time.Duration // 入队→取出分发延迟的第 99 百分位
+	LatencyMax time.Duration // 入队→取出分发延迟的最大值（精确）
+}
+
+// QueueStatter 支持按队列查询积压与延迟的 Bus（Async / Sync 异步模式 / Flow 实现）
+// 用于在 ring 写满、发布侧开始阻塞或溢出之前发现积压增长；Sync 同步模式无队列，返回空切片。
+//
+// 用法:
+//
+//	if qs, ok := bus.(core.QueueStatter); ok {
+//	    for _, q := range qs.QueueStats() {
+//	        if q.Depth > q.Capacity/2 || q.LatencyP99 > 10*time.Millisecond { ... }
+//	    }
+//	}
+type QueueStatter interface {
+	// QueueStats 返回各队列的当前状态（开销与队列数成正比，勿在热路径调用）
+	QueueStats() []QueueStat
+}
+
+// LatencyStatter 支持按订阅 pattern 查询 handler 耗时分布的 Bus（三种实现；需 beat.WithHandlerLatency 开启）
+// 未开启时 HandlerLatency 返回 nil。
+//
+// 用法:
+//
+//	if ls, ok := bus.(core.LatencyStatter); ok {
+//	    for pattern, h := range ls.HandlerLatency() {
+//	        log.Printf("%s n=%d p99=%v", pattern, h.Count, h.Percentile(99))
+//	    }
+//	    ls.ResetHandlerLatency() // 按统计周期清零
+//	}
+type LatencyStatter interface {
+	// HandlerLatency 返回各订阅 pattern 的 handler 耗时分布（自创建或上次清零起累计）
+	HandlerLatency() map[string]util.HistogramSnapshot
+	// ResetHandlerLatency 清零全部 pattern 的耗时分布
+	ResetHandlerLatency()
+}
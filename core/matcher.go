@@ -2,89 +2,242 @@
 package core
 
 import (
+	"maps"
 	"sync"
 	"sync/atomic"
 	"unsafe"
+
+	"github.com/uniyakcom/beat/util"
 )
 
 const matchCacheShards = 16
 
-// maxTrieDepth 最大 Trie 深度（固定数组大小，避免 Remove 中 make 分配）
+// DefaultMatchCacheSize 匹配结果缓存默认容量（条目数，各分片合计）
+const DefaultMatchCacheSize = 4096
+
+// maxTrieDepth 最大 Trie 深度（pattern 段数上限，同时限制匹配递归深度）
 const maxTrieDepth = 16
 
 // TrieMatcher 基于 Trie 树的高性能匹配器（导出具体类型，热路径避免接口开销）
-// 支持 user.* (单层通配) 和 user.** (多层通配) 以及 user.created (精确)
-// 内置 sharded match-cache：精确O(1) + 热点eventType缓存结果
+// 支持 user.created (精确)、user.* (单层通配)、user.** / a.**.z (多层通配，可在任意位置)、
+// user.*ed (段内通配) 与 order.{created,paid} (备选)；分隔符与通配符记号由 PatternSyntax 决定
+// 内置 sharded match-cache：精确O(1) + 热点eventType缓存结果（容量有界，CLOCK 淘汰）
 //
 // 优化: 前缀哈希分桶 — 根节点 children 按首段哈希分组，
 // 通配符匹配时减少遍历范围 O(n) → O(n/k)
+//
+// 并发模型（RCU，与各 Bus 的订阅快照相同）:
+//   - Trie 节点发布后不可变，根节点经 atomic.Pointer 发布；Match / HasMatch 全程无锁
+//   - Add / Remove 由 mu 串行化，复制根到目标节点的路径生成新 Trie（未改动的子树共享），
+//     再原子替换根节点；读者要么看到旧 Trie，要么看到新 Trie
 type TrieMatcher struct {
-	root *node
+	root   atomic.Pointer[node] // 当前 Trie 快照（不可变）
+	syntax PatternSyntax
+
+	mu   sync.Mutex // 串行化写者（Add / Remove），读者不加锁
+	pool sync.Pool  // 重用 []string 切片，减少分配
 
-	mu   sync.RWMutex
-	pool sync.Pool // 重用 []string 切片，减少分配
+	// 已注册的通配符 pattern 数（含重复注册）
+	// 为 0 时精确匹配快速路径才成立，否则 eventType 可能同时命中通配符 pattern：
+	// 快速路径只返回字面 pattern，会让 EmitMatch 漏掉 user.* 等同时命中的通配符订阅
+	wild atomic.Int64
 
-	// 精确匹配快速路径（sync.Map: eventType → true）
+	// 精确匹配快速路径（sync.Map: 已注册的字面 pattern → true）
+	// 按引用计数维护：重复注册的字面 pattern 在最后一次 Remove 时才删除，此前仍走快速路径
 	exact sync.Map
 
 	// --- cache line boundary ---
 	_ [unsafe.Sizeof(sync.Map{}) % 64]byte
 
 	// match结果缓存（sharded避免竞争）
-	cache    [matchCacheShards]matchCacheShard
-	cacheVer atomic.Uint64 // Add/Remove 时递增使缓存失效
+	cache      [matchCacheShards]matchCacheShard
+	cacheVer   atomic.Uint64 // Add/Remove 时递增使缓存失效
+	shardLimit int           // 每个分片的条目上限（0 表示不缓存）
+
+	hits      *util.PerCPUCounter // 缓存命中（热路径，per-CPU 分散写入）
+	misses    atomic.Int64        // 缓存未命中（慢路径）
+	evictions atomic.Int64        // 淘汰条目数
 }
 
+// matchCacheShard 缓存分片：查找经 sync.Map 无锁读取，写入与淘汰由 mu 串行化
+//
+// 淘汰采用 CLOCK：条目被命中时置引用位；分片满时指针扫过环，
+// 清除引用位跳过最近命中的条目，淘汰第一个未命中或已过期（ver 落后）的条目。
+// 新条目不带引用位，只出现一次的 eventType（如含租户 ID 的类型）最先被淘汰。
 type matchCacheShard struct {
-	m sync.Map // eventType → *matchCacheEntry
-	_ [40]byte // padding to ~64B
+	m    sync.Map // eventType → *matchCacheEntry
+	mu   sync.Mutex
+	ring []*matchCacheEntry // CLOCK 环（len ≤ shardLimit）
+	hand int
+	_    [56]byte // padding to 128B
 }
 
 type matchCacheEntry struct {
-	patterns []string // 匹配到的 patterns 副本
-	ver      uint64   // 写入时的 cacheVer
+	key      string
+	patterns []string    // 匹配到的 patterns 副本
+	ver      uint64      // 写入时的 cacheVer
+	slot     int         // 在 ring 中的位置
+	ref      atomic.Bool // CLOCK 引用位
 }
 
+// node Trie 节点（发布后不可变；写者只修改尚未发布的副本）
 type node struct {
-	children map[string]*node // 24字节
+	children map[string]*node // 24字节 — 字面段
+	one      *node            // 8字节  — 单段通配
+	multi    *node            // 8字节  — 多段通配
+	globs    []*node          // 24字节 — 段内通配 / 备选（按原文区分）
+	seg      *segment         // 8字节  — globs 中的节点: 编译后的段
 	pattern  string           // 16字节
 	refCount int32            // 4字节
 	isEnd    bool             // 1字节
 }
 
-// NewTrieMatcher 创建具体类型匹配器（热路径直接使用，避免接口分发）
+// NewTrieMatcher 创建具体类型匹配器（DotSyntax；热路径直接使用，避免接口分发）
 func NewTrieMatcher() *TrieMatcher {
-	return &TrieMatcher{
-		root: newNode(),
+	return NewTrieMatcherWith(DotSyntax)
+}
+
+// NewTrieMatcherWith 创建使用语法 syntax 的匹配器（如 MQTTSyntax）
+func NewTrieMatcherWith(syntax PatternSyntax) *TrieMatcher {
+	return NewTrieMatcherSize(syntax, 0)
+}
+
+// NewTrieMatcherSize 创建使用语法 syntax、匹配结果缓存容量为 cacheSize 的匹配器
+// cacheSize 为 0 时使用 DefaultMatchCacheSize，为负时不缓存（每次 Match 遍历 Trie）。
+func NewTrieMatcherSize(syntax PatternSyntax, cacheSize int) *TrieMatcher {
+	if cacheSize == 0 {
+		cacheSize = DefaultMatchCacheSize
+	}
+	limit := 0
+	if cacheSize > 0 {
+		limit = (cacheSize + matchCacheShards - 1) / matchCacheShards
+	}
+	t := &TrieMatcher{
+		syntax:     syntax.norm(),
+		shardLimit: limit,
+		hits:       util.NewPerCPUCounter(),
 		pool: sync.Pool{
 			New: func() interface{} { s := make([]string, 0, 16); return &s },
 		},
 	}
+	t.root.Store(&node{})
+	return t
 }
 
-func newNode() *node {
-	return &node{
-		children: make(map[string]*node, 4),
+// child 返回 seg 对应的子节点（不存在时返回 nil）
+func (n *node) child(seg *segment) *node {
+	switch seg.kind {
+	case segLit:
+		return n.children[seg.text]
+	case segOne:
+		return n.one
+	case segMulti:
+		return n.multi
+	case segGlob:
+		for _, g := range n.globs {
+			if g.seg.text == seg.text {
+				return g
+			}
+		}
 	}
+	return nil
 }
 
-// containsStar 零分配检查是否包含'*'字符
-// 注: strings.Contains 内部使用 IndexByte 但需要 rune 解码前置检查，
-// 直接逐字节扫描在短字符串上更快
-func containsStar(s string) bool {
-	for i := 0; i < len(s); i++ {
-		if wildcardChars[s[i]] {
-			return true
+// withChild 返回 seg 对应子节点替换为 c 后的 n 的副本（c 为 nil 时删除该子节点）
+// 只复制被修改的容器（children / globs），其余子节点与原节点共享。
+func (n *node) withChild(seg *segment, c *node) *node {
+	cp := *n
+	switch seg.kind {
+	case segLit:
+		m := maps.Clone(n.children)
+		if m == nil {
+			m = make(map[string]*node, 1)
+		}
+		if c != nil {
+			m[seg.text] = c
+		} else {
+			delete(m, seg.text)
+		}
+		cp.children = m
+	case segOne:
+		cp.one = c
+	case segMulti:
+		cp.multi = c
+	case segGlob:
+		globs := make([]*node, 0, len(n.globs)+1)
+		found := false
+		for _, g := range n.globs {
+			if g.seg.text == seg.text {
+				found = true
+				if c == nil {
+					continue
+				}
+				g = c
+			}
+			globs = append(globs, g)
 		}
+		if !found && c != nil {
+			globs = append(globs, c)
+		}
+		cp.globs = globs
 	}
-	return false
+	return &cp
+}
+
+// insert 返回增加一次 pattern 引用后的新子树（复制路径）
+func (n *node) insert(segs []segment, pattern string) *node {
+	if len(segs) == 0 {
+		cp := *n
+		cp.isEnd = true
+		cp.pattern = pattern
+		cp.refCount++
+		return &cp
+	}
+	seg := &segs[0]
+	c := n.child(seg)
+	if c == nil {
+		c = &node{}
+		if seg.kind == segGlob {
+			c.seg = seg
+		}
+	}
+	return n.withChild(seg, c.insert(segs[1:], pattern))
+}
+
+// remove 返回减少一次 pattern 引用后的新子树（复制路径，变空的节点返回 nil）
+// 调用方保证 pattern 存在。
+func (n *node) remove(segs []segment) *node {
+	var cp *node
+	if len(segs) == 0 {
+		c := *n
+		if c.refCount--; c.refCount == 0 {
+			c.isEnd = false
+			c.pattern = ""
+		}
+		cp = &c
+	} else {
+		cp = n.withChild(&segs[0], n.child(&segs[0]).remove(segs[1:]))
+	}
+	if cp.empty() {
+		return nil
+	}
+	return cp
 }
 
-// wildcardChars [256]bool 查表 — 零分支判断通配符字符
-var wildcardChars [256]bool
+// find 返回 segs 路径末端的节点（不存在时返回 nil）
+func (n *node) find(segs []segment) *node {
+	for i := range segs {
+		if n = n.child(&segs[i]); n == nil {
+			return nil
+		}
+	}
+	return n
+}
 
-func init() {
-	wildcardChars['*'] = true
+// empty 节点不再承载任何 pattern 或子节点
+func (n *node) empty() bool {
+	return !n.isEnd && n.refCount == 0 && len(n.children) == 0 &&
+		n.one == nil && n.multi == nil && len(n.globs) == 0
 }
 
 // cacheShard 返回eventType的cache分片索引（FNV-1a散列）
@@ -122,97 +275,68 @@ func (t *TrieMatcher) putSlice(sp *[]string) {
 	t.pool.Put(sp)
 }
 
-// Add 添加模式到 Trie（使缓存失效）— 零分配 split
-func (t *TrieMatcher) Add(pattern string) {
-	t.mu.Lock()
-	defer t.mu.Unlock()
+// Syntax 返回匹配器使用的 pattern 语法
+func (t *TrieMatcher) Syntax() PatternSyntax {
+	return t.syntax
+}
 
-	sp := t.splitNoAlloc(pattern, '.')
+// Compile 按匹配器的语法编译并校验 pattern
+func (t *TrieMatcher) Compile(pattern string) (*Pattern, error) {
+	return t.syntax.Compile(pattern)
+}
 
-	// 安全: 深度限制与 Remove 一致，防止无法删除的深层 pattern 导致内存泄漏
-	if len(*sp) > maxTrieDepth {
-		t.putSlice(sp)
-		return
+// Add 添加模式到 Trie（发布新快照并使缓存失效）
+// pattern 语法无效或段数超过 16 时不添加，返回包装 ErrBadPattern 的错误。
+func (t *TrieMatcher) Add(pattern string) error {
+	p, err := t.syntax.Compile(pattern)
+	if err != nil {
+		return err
 	}
 
-	n := t.root
-
-	for _, part := range *sp {
-		if _, ok := n.children[part]; !ok {
-			n.children[part] = newNode()
-		}
-		n = n.children[part]
-	}
-	n.isEnd = true
-	n.pattern = pattern
-	n.refCount++
+	t.mu.Lock()
+	defer t.mu.Unlock()
 
-	// 精确匹配优化
-	if !containsStar(pattern) {
+	// 精确匹配优化（先于发布新 Trie 登记通配符数，快速路径不会漏掉新的通配符 pattern）
+	if p.wild {
+		t.wild.Add(1)
+	} else {
 		t.exact.Store(pattern, true)
 	}
 
-	t.putSlice(sp)
+	t.root.Store(t.root.Load().insert(p.segs, pattern))
 
-	// 使缓存失效
+	// 使缓存失效（先发布 Trie 再递增版本：读到新版本的读者必然遍历新 Trie）
 	t.cacheVer.Add(1)
+	return nil
 }
 
-// Remove 移除模式（引用计数 + 自底向上清理空节点 + 使缓存失效）— 零分配 split
-// 优化: 使用固定数组 [maxTrieDepth+1]*node 替代 make，避免堆分配
+// Remove 移除模式（引用计数；减到 0 时清理空节点，发布新快照并使缓存失效）
 func (t *TrieMatcher) Remove(pattern string) {
+	p, err := t.syntax.Compile(pattern)
+	if err != nil {
+		return // 无效 pattern 不会被 Add
+	}
+
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
-	sp := t.splitNoAlloc(pattern, '.')
-	parts := *sp
-
-	// 固定数组收集路径节点（最大深度限制 maxTrieDepth，避免 make 分配）
-	var pathBuf [maxTrieDepth + 1]*node
-	pathLen := 0
-	pathBuf[0] = t.root
-	pathLen++
-	n := t.root
-	for _, part := range parts {
-		if pathLen > maxTrieDepth {
-			t.putSlice(sp)
-			return // 超过最大深度，拒绝操作
-		}
-		if next, ok := n.children[part]; ok {
-			pathBuf[pathLen] = next
-			pathLen++
-			n = next
-		} else {
-			t.putSlice(sp)
-			return
-		}
+	root := t.root.Load()
+	end := root.find(p.segs)
+	if end == nil || end.refCount == 0 {
+		return
 	}
-
-	if n.refCount > 0 {
-		n.refCount--
+	if root = root.remove(p.segs); root == nil {
+		root = &node{}
 	}
+	t.root.Store(root)
 
-	if n.refCount == 0 {
-		n.isEnd = false
-		n.pattern = ""
+	if p.wild {
+		t.wild.Add(-1)
 	}
-
-	// 自底向上清理无用空节点，防止Trie内存泄漏
-	for i := len(parts) - 1; i >= 0; i-- {
-		child := pathBuf[i+1]
-		if !child.isEnd && len(child.children) == 0 && child.refCount == 0 {
-			delete(pathBuf[i].children, parts[i])
-		} else {
-			break
-		}
-	}
-
-	if !containsStar(pattern) {
+	if end.refCount == 1 && !p.wild {
 		t.exact.Delete(pattern)
 	}
 
-	t.putSlice(sp)
-
 	// 使缓存失效
 	t.cacheVer.Add(1)
 }
@@ -221,55 +345,154 @@ func (t *TrieMatcher) Remove(pattern string) {
 // 返回 *[]string — 调用者必须用 Put 归还，归还后不可再访问
 func (t *TrieMatcher) Match(eventType string) *[]string {
 	// 快速路径1：精确匹配（绝大多数场景无通配符）
-	if _, ok := t.exact.Load(eventType); ok {
+	// 存在通配符 pattern 时结果可能不止字面 pattern 本身，需走缓存 / Trie 收集全部命中的 pattern
+	if _, ok := t.exact.Load(eventType); ok && t.wild.Load() == 0 {
 		sp := t.pool.Get().(*[]string)
 		*sp = (*sp)[:0]
 		*sp = append(*sp, eventType)
 		return sp
 	}
 
-	// 快速路径2：cache命中
+	// 快速路径2：cache命中（无锁）
 	ver := t.cacheVer.Load()
-	shard := cacheShard(eventType)
-	if v, ok := t.cache[shard].m.Load(eventType); ok {
-		entry := v.(*matchCacheEntry)
-		if entry.ver == ver {
-			// cache命中 — 复制到池切片返回（调用者会Put回来）
-			sp := t.pool.Get().(*[]string)
-			*sp = (*sp)[:0]
-			*sp = append(*sp, entry.patterns...)
-			return sp
+	shard := &t.cache[cacheShard(eventType)]
+	if t.shardLimit > 0 {
+		if v, ok := shard.m.Load(eventType); ok {
+			entry := v.(*matchCacheEntry)
+			if entry.ver == ver {
+				if !entry.ref.Load() {
+					entry.ref.Store(true)
+				}
+				t.hits.Add(1)
+				// cache命中 — 复制到池切片返回（调用者会Put回来）
+				sp := t.pool.Get().(*[]string)
+				*sp = (*sp)[:0]
+				*sp = append(*sp, entry.patterns...)
+				return sp
+			}
 		}
+		t.misses.Add(1)
 	}
 
-	// 慢路径：Trie遍历
-	t.mu.RLock()
+	// 慢路径：无锁遍历当前 Trie 快照（须在读取 cacheVer 之后加载）
 	sp := t.pool.Get().(*[]string)
 	*sp = (*sp)[:0]
-	partsSp := t.splitNoAlloc(eventType, '.')
-	t.matchRecursive(t.root, *partsSp, 0, sp)
+	partsSp := t.splitNoAlloc(eventType, t.syntax.Sep)
+	var memo multiMemo
+	t.matchRecursive(t.root.Load(), *partsSp, 0, sp, &memo, false)
 	t.putSlice(partsSp)
-	t.mu.RUnlock()
 
 	// 写入cache（复制结果避免池回收后数据损坏）
-	results := *sp
-	cached := make([]string, len(results))
-	copy(cached, results)
-	t.cache[shard].m.Store(eventType, &matchCacheEntry{
-		patterns: cached,
-		ver:      ver,
-	})
+	if t.shardLimit > 0 {
+		results := *sp
+		cached := make([]string, len(results))
+		copy(cached, results)
+		if shard.put(&matchCacheEntry{key: eventType, patterns: cached, ver: ver}, t.shardLimit) {
+			t.evictions.Add(1)
+		}
+	}
 
 	return sp
 }
 
-func (t *TrieMatcher) matchRecursive(n *node, parts []string, depth int, results *[]string) {
+// put 写入缓存条目；分片已满时按 CLOCK 淘汰一个条目并返回 true
+func (c *matchCacheShard) put(e *matchCacheEntry, limit int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// 已有条目（过期或并发写入）: 原位替换
+	if v, ok := c.m.Load(e.key); ok {
+		old := v.(*matchCacheEntry)
+		if old.ver > e.ver {
+			return false // 并发写入了更新的结果
+		}
+		e.slot = old.slot
+		c.ring[e.slot] = e
+		c.m.Store(e.key, e)
+		return false
+	}
+	if len(c.ring) < limit {
+		e.slot = len(c.ring)
+		c.ring = append(c.ring, e)
+		c.m.Store(e.key, e)
+		return false
+	}
+
+	// 过期条目直接淘汰；未过期且引用位已置的清除引用位后跳过（最多两圈）
+	for {
+		victim := c.ring[c.hand]
+		if victim.ver < e.ver || !victim.ref.Swap(false) {
+			break
+		}
+		c.hand = (c.hand + 1) % len(c.ring)
+	}
+	victim := c.ring[c.hand]
+	c.m.Delete(victim.key)
+	e.slot = c.hand
+	c.ring[c.hand] = e
+	c.hand = (c.hand + 1) % len(c.ring)
+	c.m.Store(e.key, e)
+	return true
+}
+
+// CacheStats 返回匹配结果缓存的命中、未命中与淘汰次数（精确快速路径不经过缓存，不计入）
+func (t *TrieMatcher) CacheStats() (hits, misses, evictions int64) {
+	return t.hits.Read(), t.misses.Load(), t.evictions.Load()
+}
+
+// CacheLen 返回当前缓存条目数（含已过期、尚未替换的条目）
+func (t *TrieMatcher) CacheLen() int {
+	n := 0
+	for i := range t.cache {
+		c := &t.cache[i]
+		c.mu.Lock()
+		n += len(c.ring)
+		c.mu.Unlock()
+	}
+	return n
+}
+
+// multiMemo 一次匹配中已展开的 (** 节点, 深度)
+// 路径上有多个 ** 时，同一状态可经不同的切分到达；每个状态只展开一次，
+// 遍历量不超过 节点数×(段数+1)，而不是随 ** 个数组合增长（如 **.**.**.z）。
+// 只有经过第二个 ** 时才记录，单个 ** 的常见 pattern 不分配。
+type multiMemo struct {
+	seen map[multiVisit]struct{}
+}
+
+type multiVisit struct {
+	n     *node
+	depth int
+}
+
+// first 记录 (n, depth)，返回此前是否未展开过
+func (m *multiMemo) first(n *node, depth int) bool {
+	v := multiVisit{n, depth}
+	if _, ok := m.seen[v]; ok {
+		return false
+	}
+	if m.seen == nil {
+		m.seen = make(map[multiVisit]struct{})
+	}
+	m.seen[v] = struct{}{}
+	return true
+}
+
+// matchRecursive 深度优先收集匹配的 pattern；under 表示到达 n 的路径已经过 **
+func (t *TrieMatcher) matchRecursive(n *node, parts []string, depth int, results *[]string, memo *multiMemo, under bool) {
+	// 多层通配: 依次吞下零段、一段……直到末尾
+	if n.multi != nil {
+		for d := depth; d <= len(parts); d++ {
+			if under && !memo.first(n.multi, d) {
+				continue
+			}
+			t.matchRecursive(n.multi, parts, d, results, memo, true)
+		}
+	}
+
 	if depth == len(parts) {
 		if n.isEnd {
-			*results = append(*results, n.pattern)
-		}
-		if child, ok := n.children["**"]; ok && child.isEnd {
-			*results = append(*results, child.pattern)
+			appendUnique(results, n.pattern)
 		}
 		return
 	}
@@ -278,18 +501,30 @@ func (t *TrieMatcher) matchRecursive(n *node, parts []string, depth int, results
 
 	// 精确匹配
 	if child, ok := n.children[part]; ok {
-		t.matchRecursive(child, parts, depth+1, results)
+		t.matchRecursive(child, parts, depth+1, results, memo, under)
 	}
 
-	// 单层通配符 "*"
-	if child, ok := n.children["*"]; ok {
-		t.matchRecursive(child, parts, depth+1, results)
+	// 单层通配符
+	if n.one != nil {
+		t.matchRecursive(n.one, parts, depth+1, results, memo, under)
 	}
 
-	// 多层通配符 "**"
-	if child, ok := n.children["**"]; ok && child.isEnd {
-		*results = append(*results, child.pattern)
+	// 段内通配 / 备选
+	for _, g := range n.globs {
+		if g.seg.match(part) {
+			t.matchRecursive(g, parts, depth+1, results, memo, under)
+		}
+	}
+}
+
+// appendUnique 追加 pattern（同一 pattern 经多层通配可能有多条匹配路径，只记录一次）
+func appendUnique(results *[]string, pattern string) {
+	for _, p := range *results {
+		if p == pattern {
+			return
+		}
 	}
+	*results = append(*results, pattern)
 }
 
 // Put 放回Match返回的 *[]string 到池（清除引用避免内存泄漏）
@@ -312,41 +547,47 @@ func (t *TrieMatcher) HasMatch(eventType string) bool {
 		return true
 	}
 
-	t.mu.RLock()
-	defer t.mu.RUnlock()
-
-	sp := t.splitNoAlloc(eventType, '.')
+	sp := t.splitNoAlloc(eventType, t.syntax.Sep)
 	defer t.putSlice(sp)
-	return t.hasMatchRecursive(t.root, *sp, 0)
+	var memo multiMemo
+	return t.hasMatchRecursive(t.root.Load(), *sp, 0, &memo, false)
 }
 
-func (t *TrieMatcher) hasMatchRecursive(n *node, parts []string, depth int) bool {
-	if depth == len(parts) {
-		if n.isEnd {
-			return true
+// hasMatchRecursive 同 matchRecursive，找到一个匹配即返回（已展开的状态均未匹配，直接跳过）
+func (t *TrieMatcher) hasMatchRecursive(n *node, parts []string, depth int, memo *multiMemo, under bool) bool {
+	if n.multi != nil {
+		for d := depth; d <= len(parts); d++ {
+			if under && !memo.first(n.multi, d) {
+				continue
+			}
+			if t.hasMatchRecursive(n.multi, parts, d, memo, true) {
+				return true
+			}
 		}
-		if child, ok := n.children["**"]; ok && child.isEnd {
-			return true
-		}
-		return false
+	}
+
+	if depth == len(parts) {
+		return n.isEnd
 	}
 
 	part := parts[depth]
 
 	if child, ok := n.children[part]; ok {
-		if t.hasMatchRecursive(child, parts, depth+1) {
+		if t.hasMatchRecursive(child, parts, depth+1, memo, under) {
 			return true
 		}
 	}
 
-	if child, ok := n.children["*"]; ok {
-		if t.hasMatchRecursive(child, parts, depth+1) {
+	if n.one != nil {
+		if t.hasMatchRecursive(n.one, parts, depth+1, memo, under) {
 			return true
 		}
 	}
 
-	if child, ok := n.children["**"]; ok && child.isEnd {
-		return true
+	for _, g := range n.globs {
+		if g.seg.match(part) && t.hasMatchRecursive(g, parts, depth+1, memo, under) {
+			return true
+		}
 	}
 
 	return false
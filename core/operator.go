@@ -0,0 +1,33 @@
+package core
+
+import "time"
+
+// Operator 订阅算子（防抖、节流、采样、按 key 合并、缓冲、过滤），实现见 operator 包
+// 通过 WithOperators 挂到订阅上：OnWith 时按顺序构建，事件依次流经各算子后到达 handler。
+// 定时器挂在所属 Bus 的时间轮上（不为每个订阅启动 goroutine），到期后的投递在订阅自己的投递 goroutine 中按顺序执行，
+// Off 时随订阅一起取消。
+type Operator interface {
+	// Build 构建算子实例；next(rt) 以 rt 为运行环境构建新的下游 handler（每次调用得到相互独立的下游实例）
+	// 通常传入本算子收到的 rt；按 key 分区的算子为每个分区传入各自的运行环境。
+	Build(next func(rt OperatorRuntime) Handler, rt OperatorRuntime) Handler
+}
+
+// OperatorRuntime 订阅为算子提供的运行环境
+type OperatorRuntime interface {
+	// After d 后在所属 Bus 的时间轮上调用 fn（fn 应尽快返回，投递经 Deliver）；订阅取消（Off）或 Bus 关闭后不再调用
+	After(d time.Duration, fn func()) CancelFunc
+	// Deliver 在分发路径之外调用下游 handler h（定时投递，排队后在时间轮 goroutine 之外按顺序执行），panic/error 按该订阅上报
+	Deliver(h Handler, evt *Event)
+}
+
+// WithOperators 为订阅挂载算子（按参数顺序，前者在外层）
+//
+// 用法:
+//
+//	bus.(core.OptionSubscriber).OnWith("cache.invalidate.*", invalidate,
+//	    core.WithOperators(operator.CoalesceBy(keyOf), operator.Debounce(200*time.Millisecond)))
+func WithOperators(ops ...Operator) SubOption {
+	return func(o *SubOptions) {
+		o.Operators = append(o.Operators, ops...)
+	}
+}
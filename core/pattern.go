@@ -0,0 +1,306 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrBadPattern pattern 语法无效（CompilePattern / TrieMatcher.Add 返回的错误包装它）
+var ErrBadPattern = errors.New("beat: bad pattern")
+
+// maxAlternatives 单个段内 {a,b} 展开后的最大备选数
+const maxAlternatives = 64
+
+// PatternSyntax pattern 语法：段分隔符与整段通配符记号
+//
+// 在任何语法下:
+//   - One 占满一段时匹配恰好一段，Multi 占满一段时匹配零或多段（可出现在任意位置，如 a.**.z）
+//   - 段内的 '*' 匹配该段内任意字符（不跨分隔符），如 user.*ed
+//   - 段内的 {a,b} 为备选，可与其他字符组合，如 order.{created,paid}、user{,s}.*
+//
+// 零值等价于 DotSyntax。
+type PatternSyntax struct {
+	Sep   byte   // 段分隔符
+	One   string // 单段通配符
+	Multi string // 多段通配符
+}
+
+var (
+	// DotSyntax 默认语法: user.created、user.*、user.**
+	DotSyntax = PatternSyntax{Sep: '.', One: "*", Multi: "**"}
+	// MQTTSyntax MQTT 风格: sensor/+/temp、sensor/#（# 同时匹配 sensor 本身）
+	MQTTSyntax = PatternSyntax{Sep: '/', One: "+", Multi: "#"}
+)
+
+// norm 补全零值字段（零值语法即 DotSyntax）
+func (s PatternSyntax) norm() PatternSyntax {
+	if s.Sep == 0 {
+		s.Sep = DotSyntax.Sep
+	}
+	if s.One == "" {
+		s.One = DotSyntax.One
+	}
+	if s.Multi == "" {
+		s.Multi = DotSyntax.Multi
+	}
+	return s
+}
+
+// wildcardChars [256]bool 查表 — 零分支判断段内通配/备选字符
+var wildcardChars [256]bool
+
+func init() {
+	wildcardChars['*'] = true
+	wildcardChars['{'] = true
+}
+
+// HasWildcard 判断 pattern 是否含通配符或备选（不校验语法）
+// 返回 false 的 pattern 只匹配与其相同的事件类型。
+func (s PatternSyntax) HasWildcard(pattern string) bool {
+	s = s.norm()
+	start := 0
+	for i := 0; i <= len(pattern); i++ {
+		if i < len(pattern) {
+			if wildcardChars[pattern[i]] {
+				return true
+			}
+			if pattern[i] != s.Sep {
+				continue
+			}
+		}
+		if seg := pattern[start:i]; seg == s.One || seg == s.Multi {
+			return true
+		}
+		start = i + 1
+	}
+	return false
+}
+
+// segKind 段类型
+type segKind uint8
+
+const (
+	segLit   segKind = iota // 字面段
+	segOne                  // 单段通配
+	segMulti                // 多段通配
+	segGlob                 // 段内通配 / 备选
+)
+
+// segment 编译后的段
+type segment struct {
+	kind segKind
+	text string   // 原文（Trie 子节点的键）
+	alts []string // segGlob: 展开备选后的各个 glob（只含 '*' 通配）
+}
+
+// match 判断事件类型的一段是否匹配 segGlob 段
+func (g *segment) match(part string) bool {
+	for _, alt := range g.alts {
+		if glob(alt, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// glob 匹配只含 '*'（任意字符序列）的模式，回溯最近一个 '*'，O(len(p)*len(s))
+func glob(p, s string) bool {
+	var pi, si int
+	star, mark := -1, 0
+	for si < len(s) {
+		switch {
+		case pi < len(p) && p[pi] == '*':
+			star, mark = pi, si
+			pi++
+		case pi < len(p) && p[pi] == s[si]:
+			pi++
+			si++
+		case star >= 0:
+			pi = star + 1
+			mark++
+			si = mark
+		default:
+			return false
+		}
+	}
+	for pi < len(p) && p[pi] == '*' {
+		pi++
+	}
+	return pi == len(p)
+}
+
+// Pattern 编译后的 pattern（只读，可并发使用）
+type Pattern struct {
+	src  string
+	sep  byte
+	segs []segment
+	wild bool
+}
+
+// CompilePattern 按 DotSyntax 编译并校验 pattern
+// 语法无效（空段、括号不配对或嵌套、通配符未占满一段、备选过多）或段数超过 16 时返回包装 ErrBadPattern 的错误。
+func CompilePattern(pattern string) (*Pattern, error) {
+	return DotSyntax.Compile(pattern)
+}
+
+// Compile 按语法 s 编译并校验 pattern（规则同 CompilePattern）
+func (s PatternSyntax) Compile(pattern string) (*Pattern, error) {
+	s = s.norm()
+	if pattern == "" {
+		return nil, badPattern(pattern, "empty pattern")
+	}
+	p := &Pattern{src: pattern, sep: s.Sep}
+	for _, text := range strings.Split(pattern, string(s.Sep)) {
+		if len(p.segs) == maxTrieDepth {
+			return nil, badPattern(pattern, fmt.Sprintf("more than %d segments", maxTrieDepth))
+		}
+		seg, err := s.segment(text)
+		if err != nil {
+			return nil, badPattern(pattern, err.Error())
+		}
+		if seg.kind != segLit {
+			p.wild = true
+		}
+		p.segs = append(p.segs, seg)
+	}
+	return p, nil
+}
+
+// segment 编译一段
+func (s PatternSyntax) segment(text string) (segment, error) {
+	switch text {
+	case "":
+		return segment{}, errors.New("empty segment")
+	case s.One:
+		return segment{kind: segOne, text: text}, nil
+	case s.Multi:
+		return segment{kind: segMulti, text: text}, nil
+	}
+	for _, tok := range [...]string{s.One, s.Multi} {
+		if !strings.Contains(tok, "*") && strings.Contains(text, tok) {
+			return segment{}, fmt.Errorf("wildcard %q must occupy an entire segment", tok)
+		}
+	}
+	if !strings.ContainsAny(text, "*{}") {
+		return segment{kind: segLit, text: text}, nil
+	}
+	alts, err := expand(text)
+	if err != nil {
+		return segment{}, err
+	}
+	return segment{kind: segGlob, text: text, alts: alts}, nil
+}
+
+// expand 展开段内的 {a,b} 备选（可有多组，不可嵌套）
+func expand(text string) ([]string, error) {
+	open := strings.IndexAny(text, "{}")
+	if open < 0 {
+		return []string{text}, nil
+	}
+	if text[open] == '}' {
+		return nil, errors.New("unbalanced }")
+	}
+	end := strings.IndexAny(text[open+1:], "{}")
+	if end < 0 || text[open+1+end] == '{' {
+		if end < 0 {
+			return nil, errors.New("unbalanced {")
+		}
+		return nil, errors.New("nested {")
+	}
+	end += open + 1
+	rest, err := expand(text[end+1:])
+	if err != nil {
+		return nil, err
+	}
+	choices := strings.Split(text[open+1:end], ",")
+	if len(choices)*len(rest) > maxAlternatives {
+		return nil, fmt.Errorf("more than %d alternatives", maxAlternatives)
+	}
+	out := make([]string, 0, len(choices)*len(rest))
+	for _, c := range choices {
+		for _, r := range rest {
+			out = append(out, text[:open]+c+r)
+		}
+	}
+	return out, nil
+}
+
+// badPattern 构造 pattern 语法错误
+func badPattern(pattern, msg string) error {
+	return fmt.Errorf("%w %q: %s", ErrBadPattern, pattern, msg)
+}
+
+// String 返回 pattern 原文
+func (p *Pattern) String() string {
+	return p.src
+}
+
+// HasWildcard 是否含通配符或备选（false 表示只匹配与原文相同的事件类型）
+func (p *Pattern) HasWildcard() bool {
+	return p.wild
+}
+
+// Match 判断事件类型 eventType 是否匹配
+func (p *Pattern) Match(eventType string) bool {
+	if !p.wild {
+		return eventType == p.src
+	}
+	return p.match(p.segs, eventType, false)
+}
+
+// match 逐段匹配（零分配）：s 为事件类型的剩余部分，end 表示其各段已耗尽
+// ** 之外的段都恰好匹配一段，匹配失败时只需回到最近一个 ** 让它多吞一段（同 glob 的回溯），
+// 最坏 O(段数×事件段数)，不随 ** 个数组合增长。
+func (p *Pattern) match(segs []segment, s string, end bool) bool {
+	i := 0
+	star := -1       // 最近一个 ** 的下标
+	var markS string // 该 ** 已吞下的段之后的位置
+	var markEnd bool
+	for !end {
+		if i < len(segs) && segs[i].kind == segMulti {
+			star, markS, markEnd = i, s, end
+			i++
+			continue
+		}
+		if i < len(segs) && p.one(&segs[i], s) {
+			i++
+			s, end = p.next(s)
+			continue
+		}
+		if star < 0 {
+			return false
+		}
+		// 回溯: 最近一个 ** 多吞一段
+		i = star + 1
+		markS, markEnd = p.next(markS)
+		s, end = markS, markEnd
+	}
+	for i < len(segs) && segs[i].kind == segMulti {
+		i++
+	}
+	return i == len(segs)
+}
+
+// one 判断 s 的第一段是否匹配单段的 seg（字面、单段通配或段内通配）
+func (p *Pattern) one(seg *segment, s string) bool {
+	part := s
+	if j := strings.IndexByte(s, p.sep); j >= 0 {
+		part = s[:j]
+	}
+	switch seg.kind {
+	case segLit:
+		return part == seg.text
+	case segGlob:
+		return seg.match(part)
+	}
+	return true
+}
+
+// next 去掉 s 的第一段，返回剩余部分（没有剩余段时 end=true）
+func (p *Pattern) next(s string) (rest string, end bool) {
+	if j := strings.IndexByte(s, p.sep); j >= 0 {
+		return s[j+1:], false
+	}
+	return "", true
+}
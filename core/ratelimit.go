@@ -0,0 +1,53 @@
+package core
+
+import "errors"
+
+// ErrRateLimited 发布被限流规则拒绝（RateReject 策略）
+var ErrRateLimited = errors.New("beat: rate limited")
+
+// RatePolicy 发布侧限流命中（令牌不足）时的处理方式
+type RatePolicy uint8
+
+const (
+	// RateReject 不发布，Emit 返回 ErrRateLimited（默认）
+	RateReject RatePolicy = iota
+	// RateDelay 阻塞等待令牌后发布（EmitCtx 的 ctx 先结束时返回 ctx.Err()）
+	RateDelay
+	// RateDrop 静默丢弃，Emit 返回 nil
+	RateDrop
+)
+
+// RateLimit 发布侧令牌桶限流规则（Bus 级，beat.WithEmitRateLimit）
+// 类型匹配 Pattern 的全部事件共享一个令牌桶；同一事件匹配多条规则时须逐条通过。
+type RateLimit struct {
+	Pattern string     // 事件类型 pattern（支持通配符）
+	Rate    float64    // 每秒补充的令牌数
+	Burst   int        // 桶容量（允许的突发数，<=0 时为 1）
+	Policy  RatePolicy // 令牌不足时的处理方式
+}
+
+// SubRateMode 订阅侧限流命中时的处理方式
+type SubRateMode uint8
+
+const (
+	// SubRateDrop 本次事件不交给 handler（默认）
+	SubRateDrop SubRateMode = iota
+	// SubRateCoalesce 合并: 只保留最新一个被限流的事件，令牌可用时投递给 handler（Off 后不再投递）
+	// 合并投递在独立的定时 goroutine 中执行，panic/error 照常上报。
+	SubRateCoalesce
+)
+
+// WithRateLimit 限制订阅 handler 的调用频率（令牌桶：每秒 rate 个令牌，容量 burst）
+// 限流只影响本订阅，同一事件的其他订阅照常收到；命中次数计入 Stats().HandlerRateLimited。
+//
+// 用法:
+//
+//	bus.(core.OptionSubscriber).OnWith("metric.**", render,
+//	    core.WithRateLimit(10, 1, core.SubRateCoalesce)) // 每秒最多刷新 10 次，始终渲染最新值
+func WithRateLimit(rate float64, burst int, mode SubRateMode) SubOption {
+	return func(o *SubOptions) {
+		o.Rate = rate
+		o.Burst = burst
+		o.RateMode = mode
+	}
+}
@@ -0,0 +1,185 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrNoResponders 请求的所有匹配 handler 均已执行完毕，但没有任何 handler 回复
+var ErrNoResponders = errors.New("beat: no responders")
+
+// ErrNotRequest 对非 Request 发布的事件调用 Reply
+var ErrNotRequest = errors.New("beat: event is not a request")
+
+// InboxPrefix 回复收件箱事件类型前缀（回复事件的 Type 为 InboxPrefix + 请求 ID）
+const InboxPrefix = "_inbox."
+
+// Replier 请求事件的回复通道（Request 设置，handler 经 Reply 回复）
+type Replier interface {
+	Reply(resp *Event) error
+}
+
+// Requester 支持请求/回复的 Bus（三种实现均支持）
+//   - Request: 返回第一个回复
+//   - RequestAll: 等待全部匹配 handler 执行完毕，返回所有回复
+//
+// 请求以 Event.ID 作为关联 ID（为空时自动生成），handler 通过 Reply(req, resp) 回复。
+// 发布的是 evt 的浅拷贝：调用方的 evt 不被修改（生成的 ID、回复通道只写入拷贝），可复用或并发请求。
+// 没有 handler 回复时返回 ErrNoResponders；ctx 结束（超时）时返回 ctx.Err()。
+//
+// 用法:
+//
+//	bus.On("auth.check", func(e *core.Event) error {
+//	    return core.Reply(e, &core.Event{Data: []byte("ok")})
+//	})
+//	resp, err := bus.(core.Requester).Request(ctx, &core.Event{Type: "auth.check"})
+type Requester interface {
+	// Request 发布请求并返回第一个回复
+	Request(ctx context.Context, evt *Event) (*Event, error)
+	// RequestAll 发布请求并收集全部回复（其余 handler 的 error 一并返回）
+	RequestAll(ctx context.Context, evt *Event) ([]*Event, error)
+}
+
+// Reply 回复请求事件（handler 内调用）
+// resp 的 ID 被设为请求 ID，Type 被设为回复收件箱；req 不是经 Request 发布时返回 ErrNotRequest。
+func Reply(req, resp *Event) error {
+	if req == nil || req.reply == nil {
+		return ErrNotRequest
+	}
+	return req.reply.Reply(resp)
+}
+
+// ReplyTo 返回事件的回复通道（非 Request 发布时为 nil）
+func (e *Event) ReplyTo() Replier {
+	return e.reply
+}
+
+// SetReplyTo 设置事件的回复通道（nil 表示清除）
+func (e *Event) SetReplyTo(r Replier) {
+	e.reply = r
+}
+
+// AwaitBus 支持完成句柄的 Bus（SendRequest 依赖 EmitAwait 判断 handler 是否全部执行完毕）
+type AwaitBus interface {
+	Bus
+	Awaiter
+}
+
+var requestSeq atomic.Uint64
+
+// SendRequest 在 bus 上执行一次请求/回复（由 Bus 实现的 Request/RequestAll 调用）
+//   - inline=true: handler 在 Emit 内同步执行，回复直接写入收集器
+//   - inline=false: 为本次请求注册临时订阅 InboxPrefix+ID，回复经 Bus 路由回来，结束后取消订阅
+//
+// all=false 时收到第一个回复即返回；all=true 时等待全部 handler 执行完毕且已发出的回复全部送达。
+// ctx 为 nil 表示不设超时（此时无回复且 handler 未执行完毕的请求会一直等待）。
+func SendRequest(ctx context.Context, bus AwaitBus, evt *Event, inline, all bool) ([]*Event, error) {
+	if evt == nil {
+		return nil, ErrNoResponders
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	req := *evt // 浅拷贝：Data / Metadata 与调用方共享，ID、回复通道、完成句柄只写入拷贝
+	if req.ID == "" {
+		req.ID = "req-" + strconv.FormatUint(requestSeq.Add(1), 36)
+	}
+
+	c := &call{inbox: InboxPrefix + req.ID, notify: make(chan struct{}, 1)}
+	if !inline {
+		c.bus = bus
+		id := bus.On(c.inbox, c.receive)
+		defer bus.Off(id)
+	}
+	req.SetReplyTo(c)
+
+	cpl := bus.EmitAwait(ctx, &req)
+	for {
+		replies, received := c.snapshot()
+		if !all && len(replies) > 0 {
+			return replies[:1], nil
+		}
+		select {
+		case <-cpl.Done():
+			// handler 已全部执行完毕，sent 不再增长：等待在途回复送达
+			if received < c.sent.Load() {
+				select {
+				case <-c.notify:
+				case <-ctx.Done():
+					return replies, ctx.Err()
+				}
+				continue
+			}
+			errs := cpl.Err()
+			if len(replies) == 0 {
+				return nil, errors.Join(ErrNoResponders, errs)
+			}
+			if !all {
+				return replies[:1], nil
+			}
+			return replies, errs
+		case <-c.notify:
+		case <-ctx.Done():
+			return replies, ctx.Err()
+		}
+	}
+}
+
+// call 单次请求的回复收集器（实现 Replier）
+type call struct {
+	inbox  string
+	bus    Bus // nil=直接写入（inline）
+	sent   atomic.Int64
+	notify chan struct{}
+
+	mu       sync.Mutex
+	replies  []*Event
+	received int64
+}
+
+// Reply 实现 Replier：inline 模式直接收集，否则经 Bus 路由到临时订阅
+func (c *call) Reply(resp *Event) error {
+	if resp == nil {
+		return nil
+	}
+	resp.ID = c.inbox[len(InboxPrefix):]
+	resp.Type = c.inbox
+	c.sent.Add(1)
+	if c.bus == nil {
+		c.deliver(resp)
+		return nil
+	}
+	if err := c.bus.Emit(resp); err != nil {
+		c.sent.Add(-1)
+		return err
+	}
+	return nil
+}
+
+// receive 临时订阅 handler
+func (c *call) receive(resp *Event) error {
+	c.deliver(resp)
+	return nil
+}
+
+// deliver 收集回复并唤醒等待方
+func (c *call) deliver(resp *Event) {
+	c.mu.Lock()
+	c.replies = append(c.replies, resp)
+	c.received++
+	c.mu.Unlock()
+	select {
+	case c.notify <- struct{}{}:
+	default:
+	}
+}
+
+// snapshot 返回当前已收到的回复
+func (c *call) snapshot() ([]*Event, int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.replies[:len(c.replies):len(c.replies)], c.received
+}
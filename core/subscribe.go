@@ -0,0 +1,124 @@
+package core
+
+import (
+	"errors"
+
+	"github.com/uniyakcom/beat/where"
+)
+
+// ErrStopPropagation handler 返回它（或包装它的 error）表示事件已处理完毕：
+// 同一事件后续的 handler 不再调用，且不作为错误上报（不计入 Stats().Errors，Emit 返回 nil）。
+// 仅 Sync Bus 支持，其余实现将其视为普通 error。
+var ErrStopPropagation = errors.New("beat: stop propagation")
+
+// SubOptions 订阅选项（OnWith 时解析，订阅生命周期内只读）
+type SubOptions struct {
+	ErrorHandler ErrorHandler // 该订阅专属错误回调（先于 Bus 级回调调用）
+	Group        string       // 竞争消费者订阅组（空=普通订阅，每个事件都收到）
+	GroupBalance GroupBalance // 组内成员选择策略（以组内最早加入的成员为准）
+	Priority     int          // 调用优先级（仅 Sync Bus：越大越先调用，相同优先级按注册顺序）
+
+	// 订阅侧限流（Rate<=0 表示不限流）
+	Rate     float64     // handler 每秒最多调用次数（令牌补充速率）
+	Burst    int         // 令牌桶容量（<=0 时为 1）
+	RateMode SubRateMode // 限流命中时丢弃或合并
+
+	Operators []Operator // 订阅算子（WithOperators，前者在外层）
+
+	Where *where.Expr // 负载谓词（WithWhere，nil=不过滤）
+}
+
+// GroupBalance 订阅组成员选择策略
+type GroupBalance uint8
+
+const (
+	// GroupRoundRobin 轮询选择成员（默认）
+	GroupRoundRobin GroupBalance = iota
+	// GroupByKey Event.Key 非空时按 key 哈希选择成员（同 key 固定到同一成员），Key 为空时轮询
+	// 成员加入/离开后哈希分布随之变化。
+	GroupByKey
+)
+
+// GroupSubscriber 支持竞争消费者订阅组的 Bus（三种实现均支持）
+// 同一 pattern 下同名 group 的成员共享事件：每个事件只交给其中一个成员；
+// 不同 group 之间、group 与普通订阅之间仍各自收到事件（扇出）。
+// 成员通过 Off 离开，组内分配在下一个 RCU 快照中重新平衡。
+// 不同 pattern 下的同名 group 相互独立。
+//
+// 用法:
+//
+//	gs := bus.(core.GroupSubscriber)
+//	for i := 0; i < 4; i++ {
+//	    gs.OnGroup("job.*", "workers", runJob) // 每个 job 只执行一次
+//	}
+type GroupSubscriber interface {
+	OnGroup(pattern, group string, handler Handler, opts ...SubOption) uint64
+}
+
+// SubOption 订阅选项修改函数
+type SubOption func(*SubOptions)
+
+// NewSubOptions 依次应用 opts，返回解析后的订阅选项
+func NewSubOptions(opts ...SubOption) SubOptions {
+	var o SubOptions
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&o)
+		}
+	}
+	return o
+}
+
+// WithPriority 设置订阅的调用优先级（默认 0，可为负数）
+// Sync Bus 按优先级从高到低调用同一事件的 handler，相同优先级保持注册顺序；
+// 通配符与精确订阅匹配同一事件时合并为一条调用链排序。
+func WithPriority(p int) SubOption {
+	return func(o *SubOptions) {
+		o.Priority = p
+	}
+}
+
+// WithGroup 将订阅加入竞争消费者订阅组（等价于 OnGroup）
+func WithGroup(name string) SubOption {
+	return func(o *SubOptions) {
+		o.Group = name
+	}
+}
+
+// WithGroupBalance 设置订阅组成员选择策略（默认 GroupRoundRobin）
+func WithGroupBalance(b GroupBalance) SubOption {
+	return func(o *SubOptions) {
+		o.GroupBalance = b
+	}
+}
+
+// WithErrorSink 为单个订阅注册错误回调
+// handler 返回非 nil error 时调用（panic 不经过此回调，见 PanicHandler）；
+// 订阅的 pattern 语法无效时以 evt 为 nil 调用一次。
+//
+// 用法:
+//
+//	bus.(core.OptionSubscriber).OnWith("order.*", handle,
+//	    core.WithErrorSink(func(err error, evt *core.Event, sub core.SubInfo) {
+//	        log.Printf("order handler %d failed on %s: %v", sub.ID, evt.Type, err)
+//	    }))
+func WithErrorSink(h ErrorHandler) SubOption {
+	return func(o *SubOptions) {
+		o.ErrorHandler = h
+	}
+}
+
+// WithWhere 为订阅挂载负载谓词：x 对 Event.Data（JSON）求值为 false 的事件不交给 handler
+// 谓词先于订阅侧限流与算子求值；被过滤的调用计入 Stats().Filtered，不计为错误。
+// 同一 pattern 上多个谓词订阅读取相同字段时，每个事件只查找一次。
+// 订阅组成员的谓词在选出成员后求值，被过滤的事件不会再交给组内其他成员。
+//
+// 用法:
+//
+//	bus.(core.OptionSubscriber).OnWith("order.*", handle,
+//	    core.WithWhere(where.MustCompile(`amount > 100 && region == "eu"`)))
+func WithWhere(x *where.Expr) SubOption {
+	return func(o *SubOptions) {
+		o.Where = x
+	}
+}
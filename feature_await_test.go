@@ -0,0 +1,203 @@
+package beat
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/uniyakcom/beat/core"
+	implsync "github.com/uniyakcom/beat/internal/impl/sync"
+)
+
+// awaitBuilders 覆盖三种实现及 Sync 异步模式
+func awaitBuilders() map[string]func() (Bus, error) {
+	return map[string]func() (Bus, error){
+		"sync":       func() (Bus, error) { return ForSync() },
+		"sync-async": func() (Bus, error) { return implsync.New(&implsync.Config{Async: true}) },
+		"async":      func() (Bus, error) { return ForAsync(withWorkers(2)) },
+		"flow":       func() (Bus, error) { return ForFlow() },
+	}
+}
+
+// TestEmitAwaitAllImpls 句柄在全部 handler 执行完后完成，并携带 handler error
+func TestEmitAwaitAllImpls(t *testing.T) {
+	for name, build := range awaitBuilders() {
+		t.Run(name, func(t *testing.T) {
+			bus, err := build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer bus.Close()
+
+			var ran atomic.Int64
+			bus.On("job", func(e *Event) error {
+				ran.Add(1)
+				return errDeclined
+			})
+			bus.On("job", func(e *Event) error {
+				ran.Add(1)
+				panic("boom")
+			})
+
+			aw, ok := bus.(Awaiter)
+			if !ok {
+				t.Fatalf("%s bus should implement Awaiter", name)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			c := aw.EmitAwait(ctx, &Event{Type: "job"})
+			err = c.Wait(ctx)
+			if !errors.Is(err, errDeclined) {
+				t.Fatalf("Wait err = %v, want errDeclined", err)
+			}
+			// Sync 同步模式语义同 Emit: 首个 error 即返回
+			if name != "sync" {
+				if got := len(c.Errors()); got != 2 {
+					t.Errorf("Errors() = %v, want handler error + panic", c.Errors())
+				}
+				if ran.Load() != 2 {
+					t.Errorf("handlers ran %d times before completion, want 2", ran.Load())
+				}
+			}
+
+			// 无匹配 handler 也会完成
+			if err := aw.EmitMatchAwait(ctx, &Event{Type: "nobody.listens"}).Wait(ctx); err != nil {
+				t.Errorf("EmitMatchAwait err = %v", err)
+			}
+		})
+	}
+}
+
+// TestBarrierWaitsForEmitted Barrier 返回时此前发布的事件均已处理
+func TestBarrierWaitsForEmitted(t *testing.T) {
+	for name, build := range awaitBuilders() {
+		t.Run(name, func(t *testing.T) {
+			bus, err := build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer bus.Close()
+
+			var n atomic.Int64
+			bus.On("tick", func(e *Event) error {
+				if n.Add(1)%500 == 0 {
+					time.Sleep(time.Millisecond) // 制造积压
+				}
+				return nil
+			})
+
+			const total = 5000
+			for i := 0; i < total; i++ {
+				_ = bus.Emit(&Event{Type: "tick"})
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := bus.(Awaiter).Barrier(ctx); err != nil {
+				t.Fatalf("Barrier err = %v", err)
+			}
+			if got := n.Load(); got != total {
+				t.Errorf("processed %d after Barrier, want %d", got, total)
+			}
+		})
+	}
+}
+
+// TestBarrierContextAndClose 消费者卡住时 Barrier 随 ctx 结束返回；关闭后返回 ErrClosed
+func TestBarrierContextAndClose(t *testing.T) {
+	bus, _ := ForAsync()
+	g := newGatedCounter()
+	bus.On("job", g.handler)
+	_ = bus.Emit(&Event{Type: "job"})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := bus.(Awaiter).Barrier(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Barrier err = %v, want DeadlineExceeded", err)
+	}
+
+	g.release()
+	bus.Close()
+	if err := bus.(Awaiter).Barrier(context.Background()); !errors.Is(err, ErrClosed) {
+		t.Errorf("Barrier after Close err = %v, want ErrClosed", err)
+	}
+	if err := bus.(Awaiter).EmitAwait(nil, &Event{Type: "job"}).Err(); !errors.Is(err, ErrClosed) {
+		t.Errorf("EmitAwait after Close err = %v, want ErrClosed", err)
+	}
+}
+
+// TestBarrierAfterCloseAllImpls 各实现关闭后 Barrier 均返回 ErrClosed
+func TestBarrierAfterCloseAllImpls(t *testing.T) {
+	for name, build := range awaitBuilders() {
+		t.Run(name, func(t *testing.T) {
+			bus, err := build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			bus.On("job", func(*Event) error { return nil })
+			_ = bus.Emit(&Event{Type: "job"})
+			bus.Close()
+			if err := bus.(Awaiter).Barrier(context.Background()); !errors.Is(err, ErrClosed) {
+				t.Errorf("Barrier after Close err = %v, want ErrClosed", err)
+			}
+		})
+	}
+}
+
+// TestEmitAwaitFlowStageFiltered 被 Stage 过滤的事件同样完成
+func TestEmitAwaitFlowStageFiltered(t *testing.T) {
+	dropAll := func(batch []*Event) ([]*Event, error) { return batch[:0], nil }
+	bus, _ := ForFlow(WithStages(dropAll))
+	defer bus.Close()
+	var called atomic.Bool
+	bus.On("job", func(e *Event) error {
+		called.Store(true)
+		return nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := bus.(Awaiter).EmitAwait(ctx, &Event{Type: "job"}).Wait(ctx); err != nil {
+		t.Fatalf("Wait err = %v", err)
+	}
+	if called.Load() {
+		t.Error("filtered event reached the handler")
+	}
+}
+
+// TestEmitAwaitDropped 被溢出策略丢弃的事件以 ErrQueueFull 完成
+func TestEmitAwaitDropped(t *testing.T) {
+	bus := newSmallAsync(core.OverflowDropNewest, 0)
+	defer bus.Close()
+	g := newGatedCounter()
+	defer g.release()
+	bus.On("job", g.handler)
+
+	var pending []*Completion
+	var dropped *Completion
+	for i := 0; i < 3*smallRingCapacity()+500 && dropped == nil; i++ {
+		c := bus.EmitAwait(nil, &Event{Type: "job"})
+		select {
+		case <-c.Done():
+			dropped = c
+		default:
+			pending = append(pending, c)
+		}
+	}
+	if dropped == nil {
+		t.Fatal("expected a dropped event with a stuck consumer")
+	}
+	if !errors.Is(dropped.Err(), ErrQueueFull) {
+		t.Errorf("dropped completion err = %v, want ErrQueueFull", dropped.Err())
+	}
+
+	g.release()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, c := range pending {
+		if err := c.Wait(ctx); err != nil {
+			t.Fatalf("queued completion err = %v", err)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package beat
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/uniyakcom/beat/core"
+)
+
+type traceKey struct{}
+
+// TestEmitCtxAllImpls context 随事件传给三种实现的 handler
+func TestEmitCtxAllImpls(t *testing.T) {
+	builders := map[string]func(...Opt) (Bus, error){
+		"sync":  ForSync,
+		"async": ForAsync,
+		"flow":  ForFlow,
+	}
+	for name, build := range builders {
+		t.Run(name, func(t *testing.T) {
+			bus, err := build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer bus.Close()
+
+			var trace atomic.Value
+			var hasDeadline atomic.Bool
+			bus.On("req.done", func(e *Event) error {
+				if v, ok := e.Context().Value(traceKey{}).(string); ok {
+					trace.Store(v)
+				}
+				_, ok := e.Context().Deadline()
+				hasDeadline.Store(ok)
+				return nil
+			})
+
+			ce, ok := bus.(core.ContextEmitter)
+			if !ok {
+				t.Fatalf("%s bus should implement core.ContextEmitter", name)
+			}
+			ctx, cancel := context.WithTimeout(context.WithValue(context.Background(), traceKey{}, "trace-1"), 5*time.Second)
+			defer cancel()
+			if err := ce.EmitCtx(ctx, &Event{Type: "req.done"}); err != nil {
+				t.Fatalf("EmitCtx err = %v", err)
+			}
+
+			if !waitFor(t, 2*time.Second, func() bool { return trace.Load() != nil }) {
+				t.Fatal("handler did not see the context value")
+			}
+			if trace.Load() != "trace-1" || !hasDeadline.Load() {
+				t.Errorf("trace = %v, deadline = %v", trace.Load(), hasDeadline.Load())
+			}
+
+			// 已取消的 ctx 不发布
+			canceled, cancelNow := context.WithCancel(context.Background())
+			cancelNow()
+			for _, emit := range []func(context.Context, *Event) error{ce.EmitCtx, ce.EmitMatchCtx} {
+				if err := emit(canceled, &Event{Type: "req.done"}); !errors.Is(err, context.Canceled) {
+					t.Errorf("emit with canceled ctx err = %v, want context.Canceled", err)
+				}
+			}
+		})
+	}
+}
+
+// TestEmitCtxStopsRemainingHandlers Sync 同步分发中 ctx 结束后不再调用剩余 handler
+func TestEmitCtxStopsRemainingHandlers(t *testing.T) {
+	bus, _ := ForSync()
+	defer bus.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var second atomic.Bool
+	bus.On("order.*", func(e *Event) error {
+		cancel() // 第一个 handler 耗尽预算
+		return nil
+	})
+	bus.On("order.*", func(e *Event) error {
+		second.Store(true)
+		return nil
+	})
+
+	err := bus.(core.ContextEmitter).EmitMatchCtx(ctx, &Event{Type: "order.paid"})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("EmitMatchCtx err = %v, want context.Canceled", err)
+	}
+	if second.Load() {
+		t.Error("handler after ctx cancellation should not run")
+	}
+}
+
+// TestEmitCtxAbortsBlockedEnqueue ring 满阻塞等待时 ctx 到期即返回，而非永久阻塞
+func TestEmitCtxAbortsBlockedEnqueue(t *testing.T) {
+	bus := newSmallAsync(core.OverflowBlock, 0)
+	defer bus.Close()
+	g := newGatedCounter()
+	defer g.release()
+	bus.On("job", g.handler)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	var err error
+	for i := 0; i < 2*smallRingCapacity()+64 && err == nil; i++ {
+		err = bus.EmitCtx(ctx, &Event{Type: "job"})
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("EmitCtx err = %v, want context.DeadlineExceeded", err)
+	}
+}
@@ -0,0 +1,199 @@
+package beat
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/uniyakcom/beat/core"
+	implasync "github.com/uniyakcom/beat/internal/impl/async"
+	implsync "github.com/uniyakcom/beat/internal/impl/sync"
+)
+
+// dedupBuilders 开启去重的各实现
+func dedupBuilders(window time.Duration, maxEntries int) map[string]func() (Bus, error) {
+	return map[string]func() (Bus, error){
+		"sync": func() (Bus, error) { return ForSync(WithDedup(window, maxEntries)) },
+		"sync-async": func() (Bus, error) {
+			return implsync.New(&implsync.Config{Async: true, DedupWindow: window, DedupMaxEntries: maxEntries})
+		},
+		"async": func() (Bus, error) { return ForAsync(withWorkers(2), WithDedup(window, maxEntries)) },
+		"flow":  func() (Bus, error) { return ForFlow(WithDedup(window, maxEntries)) },
+	}
+}
+
+// TestDedupByID 窗口内相同 ID 的事件只处理一次（单条、批量、通配符与 EmitAwait 路径）
+func TestDedupByID(t *testing.T) {
+	for name, build := range dedupBuilders(time.Minute, 0) {
+		t.Run(name, func(t *testing.T) {
+			bus, err := build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer bus.Close()
+
+			var calls atomic.Int64
+			bus.On("payment.settled", func(e *Event) error {
+				calls.Add(1)
+				return nil
+			})
+
+			_ = bus.Emit(&Event{Type: "payment.settled", ID: "p-1"})
+			_ = bus.Emit(&Event{Type: "payment.settled", ID: "p-1"})
+			_ = bus.EmitBatch([]*Event{
+				{Type: "payment.settled", ID: "p-2"},
+				{Type: "payment.settled", ID: "p-1"},
+				{Type: "payment.settled", ID: "p-2"},
+			})
+			_ = bus.EmitMatch(&Event{Type: "payment.settled", ID: "p-2"})
+			// 无 ID 的事件不去重
+			_ = bus.Emit(&Event{Type: "payment.settled"})
+			_ = bus.Emit(&Event{Type: "payment.settled"})
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := bus.(Awaiter).EmitAwait(ctx, &Event{Type: "payment.settled", ID: "p-1"}).Wait(ctx); err != nil {
+				t.Errorf("EmitAwait of duplicate: %v", err)
+			}
+			_ = bus.(Awaiter).Barrier(ctx)
+
+			if n := calls.Load(); n != 4 {
+				t.Errorf("handler calls = %d, want 4", n)
+			}
+			if d := bus.Stats().Duplicates; d != 5 {
+				t.Errorf("Stats().Duplicates = %d, want 5", d)
+			}
+		})
+	}
+}
+
+// TestDedupRequestReply 开启去重时 Request 正常收到回复（回复沿用请求 ID，不被当作重复事件丢弃）
+func TestDedupRequestReply(t *testing.T) {
+	for name, build := range dedupBuilders(time.Minute, 0) {
+		t.Run(name, func(t *testing.T) {
+			bus, err := build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer bus.Close()
+
+			bus.On("auth.check", func(e *Event) error {
+				return Reply(e, &Event{Data: []byte("ok")})
+			})
+			ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+			defer cancel()
+			for _, id := range []string{"", "r-1"} {
+				resp, err := bus.(Requester).Request(ctx, &Event{Type: "auth.check", ID: id})
+				if err != nil {
+					t.Fatalf("Request(ID %q) err = %v", id, err)
+				}
+				if string(resp.Data) != "ok" {
+					t.Errorf("reply data = %q", resp.Data)
+				}
+			}
+			// 相同 ID 的请求仍按去重丢弃
+			if _, err := bus.(Requester).Request(ctx, &Event{Type: "auth.check", ID: "r-1"}); !errors.Is(err, ErrNoResponders) {
+				t.Errorf("duplicate Request err = %v, want ErrNoResponders", err)
+			}
+			if d := bus.Stats().Duplicates; d != 1 {
+				t.Errorf("Stats().Duplicates = %d, want 1", d)
+			}
+		})
+	}
+}
+
+// TestDedupReleasedOnFailedEnqueue 入队失败（ctx 到期）的事件不留去重记录也不被保留，同一 ID 的重试照常投递
+func TestDedupReleasedOnFailedEnqueue(t *testing.T) {
+	builders := map[string]func() (Bus, error){
+		"sync-async": func() (Bus, error) {
+			return implsync.New(&implsync.Config{Async: true, DedupWindow: time.Minute, Retain: []string{"job.retry"}})
+		},
+		"async": func() (Bus, error) {
+			return implasync.New(&implasync.Config{Workers: 1, RingSize: 64, DedupWindow: time.Minute, Retain: []string{"job.retry"}}), nil
+		},
+	}
+	for name, build := range builders {
+		t.Run(name, func(t *testing.T) {
+			bus, err := build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer bus.Close()
+			g := newGatedCounter()
+			defer g.release()
+			bus.On("job", g.handler)
+			var retried atomic.Int64
+			bus.On("job.retry", func(e *Event) error {
+				retried.Add(1)
+				return nil
+			})
+
+			// 卡住消费者并填满 ring
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			for err == nil {
+				err = bus.(core.ContextEmitter).EmitCtx(ctx, &Event{Type: "job"})
+			}
+			ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+			if err := bus.(core.ContextEmitter).EmitCtx(ctx, &Event{Type: "job.retry", ID: "r-1"}); !errors.Is(err, context.DeadlineExceeded) {
+				t.Fatalf("EmitCtx on full ring err = %v, want context.DeadlineExceeded", err)
+			}
+			if n := len(bus.(Retainer).Retained("job.retry")); n != 0 {
+				t.Errorf("retained %d events after failed enqueue, want 0", n)
+			}
+
+			g.release()
+			if err := bus.(core.ContextEmitter).EmitCtx(context.Background(), &Event{Type: "job.retry", ID: "r-1"}); err != nil {
+				t.Fatalf("retry EmitCtx err = %v", err)
+			}
+			if !waitFor(t, 5*time.Second, func() bool { return retried.Load() == 1 }) {
+				t.Fatalf("retry delivered %d times, want 1", retried.Load())
+			}
+			if d := bus.Stats().Duplicates; d != 0 {
+				t.Errorf("Stats().Duplicates = %d, want 0", d)
+			}
+			if n := len(bus.(Retainer).Retained("job.retry")); n != 1 {
+				t.Errorf("retained %d events after retry, want 1", n)
+			}
+		})
+	}
+}
+
+// TestDedupWindowAndBound 过期后相同 ID 重新发布；记录数有上界，超出时淘汰最早的 ID
+func TestDedupWindowAndBound(t *testing.T) {
+	bus, _ := ForSync(WithDedup(30*time.Millisecond, 64))
+	defer bus.Close()
+	var calls int
+	bus.On("job", func(e *Event) error {
+		calls++
+		return nil
+	})
+
+	_ = bus.Emit(&Event{Type: "job", ID: "a"})
+	_ = bus.Emit(&Event{Type: "job", ID: "a"})
+	time.Sleep(40 * time.Millisecond)
+	_ = bus.Emit(&Event{Type: "job", ID: "a"})
+	if calls != 2 {
+		t.Errorf("calls = %d, want 2 (processed again after window)", calls)
+	}
+
+	bus, _ = ForSync(WithDedup(time.Minute, 64))
+	defer bus.Close()
+	calls = 0
+	bus.On("job", func(e *Event) error {
+		calls++
+		return nil
+	})
+	for i := 0; i < 1000; i++ {
+		_ = bus.Emit(&Event{Type: "job", ID: strconv.Itoa(i)})
+	}
+	_ = bus.Emit(&Event{Type: "job", ID: "0"})   // 已被淘汰
+	_ = bus.Emit(&Event{Type: "job", ID: "999"}) // 仍在记录中
+	if calls != 1001 {
+		t.Errorf("calls = %d, want 1001", calls)
+	}
+}
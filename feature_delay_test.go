@@ -0,0 +1,138 @@
+package beat
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestEmitAfterAndCancel 延迟事件到期发布、计入 Depth，取消后不再发布
+func TestEmitAfterAndCancel(t *testing.T) {
+	for name, build := range awaitBuilders() {
+		t.Run(name, func(t *testing.T) {
+			bus, err := build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer bus.Close()
+			dl := bus.(Delayer)
+
+			fired := make(chan string, 4)
+			bus.On("reminder.due", func(e *Event) error {
+				fired <- e.ID
+				return nil
+			})
+
+			start := time.Now()
+			dl.EmitAfter(30*time.Millisecond, &Event{Type: "reminder.due", ID: "a"})
+			cancel := dl.EmitAfter(40*time.Millisecond, &Event{Type: "reminder.due", ID: "b"})
+			dl.EmitAt(start.Add(time.Hour), &Event{Type: "reminder.due", ID: "later"})
+			if d := bus.Stats().Depth; d != 3 {
+				t.Errorf("Depth = %d, want 3 pending", d)
+			}
+			if !cancel() || cancel() {
+				t.Error("cancel should succeed exactly once before firing")
+			}
+
+			select {
+			case id := <-fired:
+				if id != "a" {
+					t.Errorf("fired %q, want a", id)
+				}
+				if el := time.Since(start); el < 30*time.Millisecond {
+					t.Errorf("fired after %v, want >= 30ms", el)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatalf("delayed event not emitted: %+v", bus.Stats())
+			}
+			select {
+			case id := <-fired:
+				t.Errorf("unexpected event %q", id)
+			case <-time.After(60 * time.Millisecond):
+			}
+			if d := bus.Stats().Depth; d != 1 {
+				t.Errorf("Depth = %d, want 1 pending", d)
+			}
+		})
+	}
+}
+
+// TestEmitAtManyTimers 大量未到期事件共用一个时间轮，全部按时发布
+func TestEmitAtManyTimers(t *testing.T) {
+	bus, _ := ForAsync(withWorkers(2))
+	defer bus.Close()
+	dl := bus.(Delayer)
+
+	const n = 100000
+	var got atomic.Int64
+	done := make(chan struct{})
+	bus.On("job.timeout", func(e *Event) error {
+		if got.Add(1) == n {
+			close(done)
+		}
+		return nil
+	})
+
+	base := time.Now().Add(2 * time.Second)
+	for i := 0; i < n; i++ {
+		dl.EmitAt(base.Add(time.Duration(i%300)*time.Millisecond), &Event{Type: "job.timeout"})
+	}
+	if d := bus.Stats().Depth; d != n && time.Now().Before(base) {
+		t.Errorf("Depth = %d, want %d", d, n)
+	}
+	select {
+	case <-done:
+	case <-time.After(20 * time.Second):
+		t.Fatalf("emitted %d of %d delayed events", got.Load(), n)
+	}
+	if d := bus.Stats().Depth; d != 0 {
+		t.Errorf("Depth = %d after firing, want 0", d)
+	}
+}
+
+// TestDelayDrainPolicy Drain 按 DelayPolicy 发布或返回未到期事件，Close 丢弃
+func TestDelayDrainPolicy(t *testing.T) {
+	// DelayFire（默认）: Drain 时按到期顺序立即发布
+	bus, _ := ForAsync(withWorkers(2))
+	var order []string
+	bus.On("task", func(e *Event) error {
+		order = append(order, e.ID)
+		return nil
+	})
+	bus.(Delayer).EmitAfter(2*time.Hour, &Event{Type: "task", ID: "second"})
+	bus.(Delayer).EmitAfter(time.Hour, &Event{Type: "task", ID: "first"})
+	if err := bus.Drain(5 * time.Second); err != nil {
+		t.Fatalf("Drain err = %v", err)
+	}
+	if len(order) != 2 || order[0] != "first" || order[1] != "second" {
+		t.Errorf("fired on drain = %v, want [first second]", order)
+	}
+
+	// DelayReport: 不发布，以 *DelayedPendingError 返回
+	bus, _ = ForFlow(WithDelayPolicy(DelayReport))
+	bus.On("task", func(e *Event) error {
+		t.Errorf("pending event %s emitted under DelayReport", e.ID)
+		return nil
+	})
+	bus.(Delayer).EmitAfter(2*time.Hour, &Event{Type: "task", ID: "b"})
+	bus.(Delayer).EmitAfter(time.Hour, &Event{Type: "task", ID: "a"})
+	var pe *DelayedPendingError
+	if err := bus.Drain(5 * time.Second); !errors.As(err, &pe) {
+		t.Fatalf("Drain err = %v, want *DelayedPendingError", err)
+	}
+	if len(pe.Events) != 2 || pe.Events[0].ID != "a" {
+		t.Errorf("pending = %v, want [a b]", pe.Events)
+	}
+
+	// Close 丢弃未到期事件；关闭后的 EmitAfter 不发布
+	bus, _ = ForSync()
+	cancel := bus.(Delayer).EmitAfter(time.Millisecond, &Event{Type: "task.x"})
+	bus.Close()
+	if cancel() {
+		t.Error("cancel after Close should report false")
+	}
+	if bus.(Delayer).EmitAfter(0, &Event{Type: "task.y"})() {
+		t.Error("EmitAfter on closed bus should not schedule")
+	}
+}
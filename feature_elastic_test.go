@@ -0,0 +1,190 @@
+package beat
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// scaleLog 记录 ScaleObserver 收到的调整
+type scaleLog struct {
+	mu     sync.Mutex
+	events []ScaleEvent
+}
+
+func (l *scaleLog) observe(ev ScaleEvent) {
+	l.mu.Lock()
+	l.events = append(l.events, ev)
+	l.mu.Unlock()
+}
+
+func (l *scaleLog) get() []ScaleEvent {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]ScaleEvent(nil), l.events...)
+}
+
+// TestElasticResize Resize 按 [Min, Max] 截断并通知 observer；调整前后事件均被处理
+func TestElasticResize(t *testing.T) {
+	var log scaleLog
+	bus, err := ForAsync(withWorkers(2), WithElasticWorkers(ScalePolicy{Min: 1, Max: 4, Interval: -1, Observer: log.observe}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bus.Close()
+	rs := bus.(Resizer)
+
+	var n atomic.Int64
+	bus.On("job", func(*Event) error {
+		n.Add(1)
+		return nil
+	})
+	emit := func() {
+		for i := 0; i < 100; i++ {
+			_ = bus.Emit(&Event{Type: "job"})
+		}
+	}
+
+	if w := rs.Workers(); w != 2 {
+		t.Fatalf("initial Workers() = %d, want 2", w)
+	}
+	for _, c := range []struct{ n, want int }{{4, 4}, {10, 4}, {0, 1}, {3, 3}} {
+		emit()
+		if got := rs.Resize(c.n); got != c.want || rs.Workers() != c.want {
+			t.Errorf("Resize(%d) = %d (Workers %d), want %d", c.n, got, rs.Workers(), c.want)
+		}
+	}
+	emit()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := bus.(Awaiter).Barrier(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := n.Load(); got != 500 {
+		t.Errorf("processed %d events, want 500", got)
+	}
+
+	want := []ScaleEvent{{From: 2, To: 4, Reason: "manual"}, {From: 4, To: 1, Reason: "manual"}, {From: 1, To: 3, Reason: "manual"}}
+	if got := log.get(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("observer got %v, want %v", got, want)
+	}
+
+	fixed, _ := ForAsync(withWorkers(2))
+	defer fixed.Close()
+	if got := fixed.(Resizer).Resize(4); got != 2 {
+		t.Errorf("fixed bus Resize(4) = %d, want 2", got)
+	}
+	degrade, _ := ForAsync(withWorkers(2), func(p *Profile) { p.Auto.Degradation = true })
+	defer degrade.Close()
+	if got := degrade.(Resizer).Resize(1); got != 1 {
+		t.Errorf("Auto.Degradation bus Resize(1) = %d, want 1", got)
+	}
+}
+
+// TestElasticResizeOrdered 发布期间反复伸缩，每个 key 仍按发布顺序处理且不丢失
+func TestElasticResizeOrdered(t *testing.T) {
+	bus, err := ForAsync(WithOrdered(), withWorkers(2), WithElasticWorkers(ScalePolicy{Min: 1, Max: 4, Interval: -1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bus.Close()
+
+	c := newOrderChecker()
+	bus.On("order.updated", c.handler)
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			bus.(Resizer).Resize(1 + i%4)
+			time.Sleep(100 * time.Microsecond)
+		}
+	}()
+
+	const keys, eventsPerKey = 32, 300
+	var wg sync.WaitGroup
+	for p := 0; p < 4; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for seq := 0; seq < eventsPerKey; seq++ {
+				for k := p; k < keys; k += 4 {
+					_ = bus.Emit(&Event{Type: "order.updated", Key: fmt.Sprintf("order-%d", k), Data: seqData(seq)})
+				}
+			}
+		}(p)
+	}
+	wg.Wait()
+	close(stop)
+	<-done
+
+	c.wait(t, keys*eventsPerKey)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.disorder != 0 {
+		t.Errorf("%d events processed out of per-key order", c.disorder)
+	}
+}
+
+// TestElasticAutoscale 积压分布在多个 ring 且 worker 持续忙碌时扩容，负载消失后缩回 Min
+func TestElasticAutoscale(t *testing.T) {
+	var log scaleLog
+	bus, err := ForAsync(WithOrdered(), withWorkers(1),
+		WithElasticWorkers(ScalePolicy{Min: 1, Max: 4, Interval: 10 * time.Millisecond, Observer: log.observe}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bus.Close()
+	rs := bus.(Resizer)
+
+	bus.On("job", func(*Event) error {
+		time.Sleep(50 * time.Microsecond) // 慢 handler: worker 忙碌而非泊车
+		return nil
+	})
+
+	// 多个 key 分散到多个 keyed ring，单个 worker 独占全部 ring
+	deadline := time.Now().Add(5 * time.Second)
+	for rs.Workers() < 2 && time.Now().Before(deadline) {
+		for i := 0; i < 64; i++ {
+			_ = bus.Emit(&Event{Type: "job", Key: fmt.Sprintf("k%d", i%16)})
+		}
+		time.Sleep(2 * time.Millisecond) // 发布速率约为单 worker 处理能力的两倍
+	}
+	if w := rs.Workers(); w < 2 {
+		t.Fatalf("Workers() = %d under load, want >= 2", w)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := bus.(Awaiter).Barrier(ctx); err != nil {
+		t.Fatal(err)
+	}
+	for rs.Workers() > 1 && time.Now().Before(deadline.Add(5*time.Second)) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if w := rs.Workers(); w != 1 {
+		t.Fatalf("Workers() = %d after load stopped, want 1", w)
+	}
+
+	var grew, shrank bool
+	for _, ev := range log.get() {
+		switch ev.Reason {
+		case "backlog":
+			grew = grew || (ev.To == ev.From+1 && ev.Backlog > 0 && ev.Idle < 0.1)
+		case "idle":
+			shrank = shrank || (ev.To == ev.From-1 && ev.Backlog == 0 && ev.Idle > 0.5)
+		}
+	}
+	if !grew || !shrank {
+		t.Errorf("observer events %+v: want a backlog grow and an idle shrink", log.get())
+	}
+}
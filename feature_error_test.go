@@ -2,7 +2,11 @@ package beat
 
 import (
 	"errors"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/uniyakcom/beat/core"
 )
 
 // TestErrorHandling 测试handler返回错误
@@ -109,3 +113,116 @@ func TestEmitMatchNoMatch(t *testing.T) {
 		t.Errorf("EmitMatch with no matches should not error, got %v", err)
 	}
 }
+
+var errDeclined = errors.New("declined")
+
+// TestErrorHandlerAllImpls 三种实现均把每一次 handler error 交给错误回调，无丢失
+func TestErrorHandlerAllImpls(t *testing.T) {
+	builders := map[string]func(...Opt) (Bus, error){
+		"sync":  ForSync,
+		"async": ForAsync,
+		"flow":  ForFlow,
+	}
+	const n = 200
+	for name, build := range builders {
+		t.Run(name, func(t *testing.T) {
+			var busCalls, sinkCalls, wrongSub atomic.Int64
+			var badID uint64
+			bus, err := build(WithErrorHandler(func(err error, evt *Event, sub SubInfo) {
+				if !errors.Is(err, errDeclined) || evt.Type != "order.paid" ||
+					sub.ID != atomic.LoadUint64(&badID) || sub.Pattern != "order.paid" {
+					wrongSub.Add(1)
+				}
+				busCalls.Add(1)
+			}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer bus.Close()
+
+			os, ok := bus.(core.OptionSubscriber)
+			if !ok {
+				t.Fatalf("%s bus should implement core.OptionSubscriber", name)
+			}
+			bus.On("order.paid", func(e *Event) error { return nil })
+			id := os.OnWith("order.paid", func(e *Event) error { return errDeclined },
+				WithErrorSink(func(err error, evt *Event, sub SubInfo) {
+					sinkCalls.Add(1)
+				}))
+			atomic.StoreUint64(&badID, id)
+
+			for i := 0; i < n; i++ {
+				err := bus.Emit(&Event{Type: "order.paid"})
+				if name == "sync" && !errors.Is(err, errDeclined) {
+					t.Fatalf("sync Emit err = %v, want errDeclined", err)
+				}
+			}
+
+			deadline := time.Now().Add(3 * time.Second)
+			for busCalls.Load() < n && time.Now().Before(deadline) {
+				time.Sleep(5 * time.Millisecond)
+			}
+			if got := busCalls.Load(); got != n {
+				t.Fatalf("error handler calls = %d, want %d", got, n)
+			}
+			if got := sinkCalls.Load(); got != n {
+				t.Errorf("subscription sink calls = %d, want %d", got, n)
+			}
+			if wrongSub.Load() != 0 {
+				t.Errorf("%d callbacks carried wrong err/event/subscription", wrongSub.Load())
+			}
+			st := bus.Stats()
+			if st.Errors != n || st.ErrorsByPattern["order.paid"] != n {
+				t.Errorf("Stats errors = %d, by pattern = %v, want %d", st.Errors, st.ErrorsByPattern, n)
+			}
+		})
+	}
+}
+
+// TestErrorsByPatternWildcard 通配符订阅按各自模式计数，运行时可替换回调
+func TestErrorsByPatternWildcard(t *testing.T) {
+	bus, _ := ForAsync()
+	defer bus.Close()
+
+	en, ok := bus.(core.ErrorNotifier)
+	if !ok {
+		t.Fatal("async bus should implement core.ErrorNotifier")
+	}
+	var calls atomic.Int64
+	en.SetErrorHandler(func(err error, evt *Event, sub SubInfo) { calls.Add(1) })
+
+	bus.On("user.*", func(e *Event) error { return errDeclined })
+	bus.On("user.logout", func(e *Event) error { return nil })
+	bus.On("order.*", func(e *Event) error { return errDeclined })
+
+	_ = bus.EmitMatch(&Event{Type: "user.login"})
+	_ = bus.EmitMatch(&Event{Type: "user.signup"})
+	_ = bus.EmitMatch(&Event{Type: "user.logout"})
+	_ = bus.EmitMatch(&Event{Type: "order.paid"})
+
+	// user.logout 同时命中精确订阅与 user.*
+	st := bus.Stats()
+	want := map[string]int64{"user.*": 3, "order.*": 1}
+	if len(st.ErrorsByPattern) != len(want) {
+		t.Fatalf("ErrorsByPattern = %v, want %v", st.ErrorsByPattern, want)
+	}
+	for k, v := range want {
+		if st.ErrorsByPattern[k] != v {
+			t.Errorf("ErrorsByPattern[%q] = %d, want %d", k, st.ErrorsByPattern[k], v)
+		}
+	}
+	if calls.Load() != 4 {
+		t.Errorf("error handler calls = %d, want 4", calls.Load())
+	}
+
+	// 取消回调后仍计数
+	en.SetErrorHandler(nil)
+	before := calls.Load()
+	_ = bus.EmitMatch(&Event{Type: "order.shipped"})
+	if calls.Load() != before {
+		t.Error("error handler called after SetErrorHandler(nil)")
+	}
+	if got := bus.Stats().ErrorsByPattern["order.*"]; got != 2 {
+		t.Errorf("ErrorsByPattern[order.*] = %d, want 2", got)
+	}
+}
@@ -0,0 +1,129 @@
+package beat
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/uniyakcom/beat/core"
+)
+
+var (
+	errRuleEmail = errors.New("email invalid")
+	errRuleAge   = errors.New("age out of range")
+)
+
+// TestErrorRunAllAggregates ErrorRunAll 调用全部 handler，汇总 error 与 panic
+func TestErrorRunAllAggregates(t *testing.T) {
+	bus, err := ForSync(WithErrorMode(ErrorRunAll))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bus.Close()
+
+	var r recorder
+	email := bus.On("user.validate", r.handler("email", errRuleEmail))
+	crash := bus.On("user.validate", func(e *Event) error { panic("rule engine crashed") })
+	age := bus.On("user.*", r.handler("age", errRuleAge))
+	bus.On("user.validate", r.handler("name", nil))
+
+	err = bus.EmitMatch(&Event{Type: "user.validate"})
+	var ee *EmitError
+	if !errors.As(err, &ee) {
+		t.Fatalf("EmitMatch err = %v (%T), want *EmitError", err, err)
+	}
+	if got, want := r.take(), []string{"email", "age", "name"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("calls = %v, want %v", got, want)
+	}
+	if len(ee.Failures) != 3 {
+		t.Fatalf("failures = %d, want 3: %v", len(ee.Failures), err)
+	}
+	want := []struct {
+		id      uint64
+		pattern string
+		panic   bool
+	}{{email, "user.validate", false}, {crash, "user.validate", true}, {age, "user.*", false}}
+	for i, w := range want {
+		f := ee.Failures[i]
+		if f.Sub.ID != w.id || f.Sub.Pattern != w.pattern || f.Panic != w.panic || f.Event.Type != "user.validate" {
+			t.Errorf("failure[%d] = %+v, want id %d pattern %s panic %v", i, f, w.id, w.pattern, w.panic)
+		}
+	}
+	if !errors.Is(err, errRuleEmail) || !errors.Is(err, errRuleAge) {
+		t.Errorf("errors.Is should match every rule error: %v", err)
+	}
+	var he *HandlerError
+	if !errors.As(err, &he) || he.Sub.ID != email {
+		t.Errorf("errors.As *HandlerError = %+v, want first failure", he)
+	}
+	if !strings.Contains(err.Error(), "rule engine crashed") {
+		t.Errorf("Error() = %q, want panic value", err.Error())
+	}
+	if st := bus.Stats(); st.Errors != 2 || st.Panics != 1 {
+		t.Errorf("Stats errors = %d panics = %d, want 2 and 1", st.Errors, st.Panics)
+	}
+
+	// 无失败返回 nil
+	if err := bus.Emit(&Event{Type: "nobody"}); err != nil {
+		t.Errorf("Emit without failures err = %v", err)
+	}
+}
+
+// TestErrorRunAllBatch EmitBatch 汇总整批事件的失败，Event 区分所属事件
+func TestErrorRunAllBatch(t *testing.T) {
+	bus, _ := ForSync(WithErrorMode(ErrorRunAll))
+	defer bus.Close()
+
+	bus.On("check", func(e *Event) error {
+		if string(e.Data) == "bad" {
+			return errDeclined
+		}
+		return nil
+	})
+	bus.On("check", func(e *Event) error { return errRuleAge })
+
+	events := []*Event{{Type: "check", Data: []byte("bad")}, {Type: "check", Data: []byte("ok")}}
+	for name, emit := range map[string]func([]*Event) error{
+		"EmitBatch":      bus.EmitBatch,
+		"EmitMatchBatch": bus.EmitMatchBatch,
+	} {
+		var ee *EmitError
+		if err := emit(events); !errors.As(err, &ee) {
+			t.Fatalf("%s err = %v, want *EmitError", name, err)
+		}
+		if len(ee.Failures) != 3 || ee.Failures[0].Event != events[0] || ee.Failures[2].Event != events[1] {
+			t.Errorf("%s failures = %v", name, ee)
+		}
+	}
+}
+
+// TestErrorFailFastDefault 默认 ErrorFailFast：返回第一个 handler error，不再调用后续 handler
+func TestErrorFailFastDefault(t *testing.T) {
+	bus, _ := ForSync()
+	defer bus.Close()
+
+	var r recorder
+	bus.On("user.validate", r.handler("email", errRuleEmail))
+	bus.On("user.validate", r.handler("age", errRuleAge))
+
+	err := bus.Emit(&Event{Type: "user.validate"})
+	if err != errRuleEmail {
+		t.Errorf("Emit err = %v, want first handler error", err)
+	}
+	if got := r.take(); len(got) != 1 {
+		t.Errorf("calls = %v, want only the first handler", got)
+	}
+
+	// ErrorRunAll 下 ErrStopPropagation 仍结束分发
+	bus2, _ := ForSync(WithErrorMode(core.ErrorRunAll))
+	defer bus2.Close()
+	bus2.On("x", r.handler("stop", ErrStopPropagation))
+	bus2.On("x", r.handler("never", errRuleAge))
+	if err := bus2.Emit(&Event{Type: "x"}); err != nil {
+		t.Errorf("Emit err = %v, want nil after stop", err)
+	}
+	if got, want := r.take(), []string{"stop"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("calls = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,178 @@
+package beat
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// emitAll 以通配符匹配发布 n 个事件并等待全部处理完毕
+func emitAll(t *testing.T, bus Bus, typ string, n int, key func(i int) string) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		evt := &Event{Type: typ}
+		if key != nil {
+			evt.Key = key(i)
+		}
+		_ = bus.EmitMatch(evt) // handler error 由各测试经计数或 ErrorSink 检查
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := bus.(Awaiter).Barrier(ctx); err != nil {
+		t.Fatalf("Barrier err = %v", err)
+	}
+}
+
+// TestGroupCompetingConsumers 同组成员分摊事件，不同组与普通订阅各自收到全部事件
+func TestGroupCompetingConsumers(t *testing.T) {
+	const n = 200
+	for name, build := range awaitBuilders() {
+		t.Run(name, func(t *testing.T) {
+			bus, err := build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer bus.Close()
+
+			gs := bus.(GroupSubscriber)
+			workers := make([]atomic.Int64, 4)
+			for i := range workers {
+				c := &workers[i]
+				gs.OnGroup("job.*", "workers", func(e *Event) error {
+					c.Add(1)
+					return nil
+				})
+			}
+			var audit, plain atomic.Int64
+			gs.OnGroup("job.*", "audit", func(e *Event) error {
+				audit.Add(1)
+				return nil
+			})
+			bus.On("job.*", func(e *Event) error {
+				plain.Add(1)
+				return nil
+			})
+
+			emitAll(t, bus, "job.run", n, nil)
+
+			var total int64
+			for i := range workers {
+				got := workers[i].Load()
+				if got == 0 {
+					t.Errorf("worker %d received nothing", i)
+				}
+				total += got
+			}
+			if total != n {
+				t.Errorf("workers total = %d, want %d (each event exactly once)", total, n)
+			}
+			if audit.Load() != n || plain.Load() != n {
+				t.Errorf("audit = %d, plain = %d, want %d each", audit.Load(), plain.Load(), n)
+			}
+		})
+	}
+}
+
+// TestGroupByKeyAndRebalance GroupByKey 同 key 固定到同一成员；成员 Off 后其余成员接管
+func TestGroupByKeyAndRebalance(t *testing.T) {
+	for name, build := range awaitBuilders() {
+		t.Run(name, func(t *testing.T) {
+			bus, err := build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer bus.Close()
+
+			var mu sync.Mutex
+			owner := make(map[string]map[int]bool) // key → 处理过它的成员
+			counts := make([]int, 3)
+			ids := make([]uint64, 3)
+			for i := range ids {
+				i := i
+				ids[i] = bus.(GroupSubscriber).OnGroup("order", "shards", func(e *Event) error {
+					mu.Lock()
+					if owner[e.Key] == nil {
+						owner[e.Key] = make(map[int]bool)
+					}
+					owner[e.Key][i] = true
+					counts[i]++
+					mu.Unlock()
+					return nil
+				}, WithGroupBalance(GroupByKey))
+			}
+
+			key := func(i int) string { return fmt.Sprintf("k%d", i%16) }
+			emitAll(t, bus, "order", 160, key)
+			mu.Lock()
+			for k, m := range owner {
+				if len(m) != 1 {
+					t.Errorf("key %s handled by %d members, want 1", k, len(m))
+				}
+			}
+			owner = make(map[string]map[int]bool)
+			counts = make([]int, 3)
+			mu.Unlock()
+
+			bus.Off(ids[0])
+			emitAll(t, bus, "order", 160, key)
+			mu.Lock()
+			defer mu.Unlock()
+			if counts[0] != 0 {
+				t.Errorf("removed member received %d events", counts[0])
+			}
+			if counts[1]+counts[2] != 160 {
+				t.Errorf("remaining members received %d, want 160", counts[1]+counts[2])
+			}
+		})
+	}
+}
+
+// TestGroupErrorAttribution 组成员的错误回调携带成员自身的订阅 ID 与组名
+func TestGroupErrorAttribution(t *testing.T) {
+	for name, build := range awaitBuilders() {
+		t.Run(name, func(t *testing.T) {
+			bus, err := build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer bus.Close()
+
+			var mu sync.Mutex
+			var got []SubInfo
+			sink := WithErrorSink(func(err error, evt *Event, sub SubInfo) {
+				mu.Lock()
+				got = append(got, sub)
+				mu.Unlock()
+			})
+			fail := func(e *Event) error { return errDeclined }
+			a := bus.(GroupSubscriber).OnGroup("task", "pool", fail, sink)
+			b := bus.(GroupSubscriber).OnGroup("task", "pool", fail, sink)
+
+			// 精确匹配发布路径
+			for i := 0; i < 2; i++ {
+				_ = bus.Emit(&Event{Type: "task"})
+			}
+			if err := bus.(Awaiter).Barrier(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			if len(got) != 2 {
+				t.Fatalf("error sink calls = %d, want 2", len(got))
+			}
+			seen := map[uint64]bool{}
+			for _, s := range got {
+				if s.Group != "pool" || s.Pattern != "task" {
+					t.Errorf("SubInfo = %+v, want group pool / pattern task", s)
+				}
+				seen[s.ID] = true
+			}
+			if !seen[a] || !seen[b] {
+				t.Errorf("error sink IDs = %v, want both members %d and %d", seen, a, b)
+			}
+		})
+	}
+}
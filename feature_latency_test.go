@@ -0,0 +1,115 @@
+package beat
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	implsync "github.com/uniyakcom/beat/internal/impl/sync"
+	"github.com/uniyakcom/beat/util"
+)
+
+// TestHistogramPercentileMerge 分位数落在真实值所在桶（相对误差 ≤ 1/16），Merge / Reset 按预期累计与清零
+func TestHistogramPercentileMerge(t *testing.T) {
+	var a util.Histogram
+	b := util.NewPerCPUHistogram()
+	for i := 1; i <= 1000; i++ {
+		a.Record(time.Duration(i) * time.Microsecond)
+		b.Record(time.Duration(i) * time.Millisecond)
+	}
+	a.Record(-time.Second) // 负值按 0 计
+
+	sa := a.Snapshot()
+	if sa.Count != 1001 || sa.Max != time.Millisecond {
+		t.Fatalf("Count %d Max %v, want 1001 and 1ms", sa.Count, sa.Max)
+	}
+	for _, c := range []struct {
+		p    float64
+		want time.Duration
+	}{{50, 500 * time.Microsecond}, {99, 990 * time.Microsecond}, {100, time.Millisecond}} {
+		got := sa.Percentile(c.p)
+		if got < c.want || got > c.want+c.want/16 {
+			t.Errorf("Percentile(%v) = %v, want within [%v, %v]", c.p, got, c.want, c.want+c.want/16)
+		}
+	}
+
+	sb := b.Snapshot()
+	sb.Merge(&sa)
+	if sb.Count != 2001 || sb.Max != time.Second {
+		t.Errorf("merged Count %d Max %v, want 2001 and 1s", sb.Count, sb.Max)
+	}
+	if p := sb.Percentile(50); p < time.Millisecond || p > time.Millisecond+time.Millisecond/16 {
+		t.Errorf("merged p50 = %v, want about 1ms", p)
+	}
+	if want := sa.Sum + 500500*time.Millisecond; sb.Sum != want {
+		t.Errorf("merged Sum %v, want %v", sb.Sum, want)
+	}
+
+	a.Reset()
+	b.Reset()
+	if sa, sb := a.Snapshot(), b.Snapshot(); sa.Count != 0 || sb.Count != 0 || sb.Max != 0 || sb.Percentile(99) != 0 {
+		t.Errorf("after Reset: %d / %d records", sa.Count, sb.Count)
+	}
+}
+
+// TestHandlerLatencyAllImpls 开启后各实现按订阅 pattern 记录 handler 耗时；未开启时不记录
+func TestHandlerLatencyAllImpls(t *testing.T) {
+	builders := map[string]func() (Bus, error){
+		"sync":       func() (Bus, error) { return ForSync(WithHandlerLatency()) },
+		"sync-async": func() (Bus, error) { return implsync.New(&implsync.Config{Async: true, HandlerLatency: true}) },
+		"async":      func() (Bus, error) { return ForAsync(withWorkers(2), WithHandlerLatency()) },
+		"flow":       func() (Bus, error) { return ForFlow(WithHandlerLatency()) },
+	}
+	const n, slow = 10, 2 * time.Millisecond
+	for name, build := range builders {
+		t.Run(name, func(t *testing.T) {
+			bus, err := build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer bus.Close()
+			ls := bus.(LatencyStatter)
+
+			bus.On("job.slow", func(*Event) error {
+				time.Sleep(slow)
+				return nil
+			})
+			bus.On("job.fast", func(*Event) error { return nil })
+			bus.On("job.fast", func(*Event) error { return nil }) // 同一 pattern 的订阅共用直方图
+			for i := 0; i < n; i++ {
+				_ = bus.Emit(&Event{Type: "job.slow"})
+				_ = bus.Emit(&Event{Type: "job.fast"})
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := bus.(Awaiter).Barrier(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			got := ls.HandlerLatency()
+			s, f := got["job.slow"], got["job.fast"]
+			if s.Count != n || f.Count != 2*n {
+				t.Fatalf("Count slow %d fast %d, want %d and %d", s.Count, f.Count, n, 2*n)
+			}
+			if p := s.Percentile(50); p < slow {
+				t.Errorf("slow p50 = %v, want >= %v", p, slow)
+			}
+			if p := f.Percentile(50); p >= slow {
+				t.Errorf("fast p50 = %v, want < %v", p, slow)
+			}
+
+			ls.ResetHandlerLatency()
+			if c := ls.HandlerLatency()["job.slow"].Count; c != 0 {
+				t.Errorf("after ResetHandlerLatency: %d records", c)
+			}
+		})
+	}
+
+	bus, _ := ForAsync(withWorkers(1))
+	defer bus.Close()
+	bus.On("job", func(*Event) error { return nil })
+	_ = bus.Emit(&Event{Type: "job"})
+	if got := bus.(LatencyStatter).HandlerLatency(); got != nil {
+		t.Errorf("HandlerLatency() without option = %v, want nil", got)
+	}
+}
@@ -0,0 +1,116 @@
+package beat
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/uniyakcom/beat/core"
+)
+
+// TestMatchCacheBounded 不同事件类型数远超容量时缓存条目数不增长，热点类型不被淘汰
+func TestMatchCacheBounded(t *testing.T) {
+	m := core.NewTrieMatcherSize(core.DotSyntax, 64)
+	if err := m.Add("tenant.*.order"); err != nil {
+		t.Fatal(err)
+	}
+	match := func(typ string) int {
+		sp := m.Match(typ)
+		defer m.Put(sp)
+		return len(*sp)
+	}
+
+	for i := 0; i < 5000; i++ {
+		if n := match(fmt.Sprintf("tenant.%d.order", i)); n != 1 {
+			t.Fatalf("Match(tenant.%d.order) = %d patterns, want 1", i, n)
+		}
+		if n := match("tenant.hot.order"); n != 1 {
+			t.Fatalf("Match(tenant.hot.order) = %d patterns, want 1", n)
+		}
+	}
+	if n := m.CacheLen(); n > 64 {
+		t.Errorf("CacheLen = %d, want <= 64", n)
+	}
+	hits, misses, evictions := m.CacheStats()
+	// 热点类型只在首次未命中（CLOCK 引用位保护它不被淘汰）
+	if misses != 5001 || hits != 4999 {
+		t.Errorf("hits/misses = %d/%d, want 4999/5001", hits, misses)
+	}
+	if evictions < 5000-64 {
+		t.Errorf("evictions = %d, want >= %d", evictions, 5000-64)
+	}
+
+	if n := testing.AllocsPerRun(100, func() { match("tenant.hot.order") }); n != 0 {
+		t.Errorf("cache hit allocs = %v, want 0", n)
+	}
+}
+
+// TestMatchCacheInvalidate Add/Remove 后过期条目不再命中并被原位替换
+func TestMatchCacheInvalidate(t *testing.T) {
+	m := core.NewTrieMatcherSize(core.DotSyntax, 16)
+	_ = m.Add("user.*")
+	count := func(typ string) int {
+		sp := m.Match(typ)
+		defer m.Put(sp)
+		return len(*sp)
+	}
+	for i := 0; i < 16; i++ {
+		count(fmt.Sprintf("user.%d", i))
+	}
+	_ = m.Add("user.**")
+	if n := count("user.1"); n != 2 {
+		t.Errorf("after Add Match(user.1) = %d patterns, want 2", n)
+	}
+	m.Remove("user.*")
+	if n := count("user.1"); n != 1 {
+		t.Errorf("after Remove Match(user.1) = %d patterns, want 1", n)
+	}
+	if n := m.CacheLen(); n > 16 {
+		t.Errorf("CacheLen = %d, want <= 16", n)
+	}
+
+	off := core.NewTrieMatcherSize(core.DotSyntax, -1)
+	_ = off.Add("user.*")
+	for i := 0; i < 3; i++ {
+		sp := off.Match("user.a")
+		off.Put(sp)
+	}
+	if h, mi, e := off.CacheStats(); off.CacheLen() != 0 || h+mi+e != 0 {
+		t.Errorf("disabled cache: len=%d stats=%d/%d/%d, want all 0", off.CacheLen(), h, mi, e)
+	}
+}
+
+// TestMatchCacheStats 缓存计数经 Bus.Stats 暴露（WithMatchCacheSize 生效）
+func TestMatchCacheStats(t *testing.T) {
+	for name, build := range map[string]func() (Bus, error){
+		"sync":  func() (Bus, error) { return ForSync(WithMatchCacheSize(32)) },
+		"async": func() (Bus, error) { return ForAsync(withWorkers(2), WithMatchCacheSize(32)) },
+		"flow":  func() (Bus, error) { return ForFlow(WithMatchCacheSize(32)) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			bus, err := build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer bus.Close()
+			bus.On("metric.**", func(*Event) error { return nil })
+
+			for i := 0; i < 200; i++ {
+				_ = bus.EmitMatch(&Event{Type: fmt.Sprintf("metric.host%d.cpu", i)})
+				_ = bus.EmitMatch(&Event{Type: "metric.total"})
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = bus.(Awaiter).Barrier(ctx) // Flow 在 worker 中匹配
+
+			s := bus.Stats()
+			if s.MatchCacheMisses < 201 || s.MatchCacheHits < 199 {
+				t.Errorf("hits/misses = %d/%d, want >= 199/201", s.MatchCacheHits, s.MatchCacheMisses)
+			}
+			if s.MatchCacheEvictions < 200-32 {
+				t.Errorf("evictions = %d, want >= %d", s.MatchCacheEvictions, 200-32)
+			}
+		})
+	}
+}
@@ -0,0 +1,235 @@
+package beat
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/uniyakcom/beat/core"
+	"github.com/uniyakcom/beat/operator"
+)
+
+// collector 并发安全地记录投递的事件 ID
+type collector struct {
+	mu  sync.Mutex
+	ids []string
+}
+
+func (c *collector) handler(e *Event) error {
+	c.mu.Lock()
+	c.ids = append(c.ids, e.ID)
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *collector) get() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.ids...)
+}
+
+// waitIDs 等待 c 收到 n 个事件（超时返回已收到的）
+func (c *collector) waitIDs(n int) []string {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if ids := c.get(); len(ids) >= n {
+			return ids
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return c.get()
+}
+
+func emitIDs(bus Bus, typ string, ids ...string) {
+	for _, id := range ids {
+		_ = bus.Emit(&Event{Type: typ, ID: id})
+	}
+}
+
+// TestOperatorTiming Debounce 投递静默后的最新事件；Throttle 首尾投递；Sample 周期投递最新事件
+func TestOperatorTiming(t *testing.T) {
+	bus, _ := ForSync()
+	defer bus.Close()
+	os := bus.(core.OptionSubscriber)
+
+	var deb, thr, smp collector
+	os.OnWith("deb", deb.handler, WithOperators(operator.Debounce(30*time.Millisecond)))
+	os.OnWith("thr", thr.handler, WithOperators(operator.Throttle(40*time.Millisecond)))
+	os.OnWith("smp", smp.handler, WithOperators(operator.Sample(30*time.Millisecond)))
+
+	emitIDs(bus, "deb", "1", "2", "3", "4", "5")
+	emitIDs(bus, "thr", "1", "2", "3")
+	if ids := thr.get(); len(ids) != 1 || ids[0] != "1" {
+		t.Errorf("throttle leading = %v, want [1]", ids)
+	}
+	emitIDs(bus, "smp", "1", "2")
+	if ids := smp.get(); len(ids) != 0 {
+		t.Errorf("sample delivered %v before the period", ids)
+	}
+
+	if ids := deb.waitIDs(1); len(ids) != 1 || ids[0] != "5" {
+		t.Errorf("debounce = %v, want [5]", ids)
+	}
+	if ids := thr.waitIDs(2); len(ids) != 2 || ids[1] != "3" {
+		t.Errorf("throttle = %v, want [1 3]", ids)
+	}
+	if ids := smp.waitIDs(1); len(ids) != 1 || ids[0] != "2" {
+		t.Errorf("sample = %v, want [2]", ids)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if n := len(deb.get()) + len(thr.get()) + len(smp.get()); n != 4 {
+		t.Errorf("extra deliveries: deb=%v thr=%v smp=%v", deb.get(), thr.get(), smp.get())
+	}
+}
+
+// TestOperatorSlowHandlerIsolation 定时投递不在时间轮 goroutine 中执行 handler：
+// 阻塞的 handler 不推迟同一 Bus 其他订阅与其他 Bus 的定时投递
+func TestOperatorSlowHandlerIsolation(t *testing.T) {
+	a, _ := ForSync()
+	defer a.Close()
+	b, _ := ForSync()
+	defer b.Close()
+
+	block, entered := make(chan struct{}), make(chan struct{}, 1)
+	defer close(block)
+	a.(core.OptionSubscriber).OnWith("slow", func(*Event) error {
+		entered <- struct{}{}
+		<-block
+		return nil
+	}, WithOperators(operator.Debounce(5*time.Millisecond)))
+	var sameBus, otherBus collector
+	a.(core.OptionSubscriber).OnWith("fast", sameBus.handler, WithOperators(operator.Debounce(5*time.Millisecond)))
+	b.(core.OptionSubscriber).OnWith("fast", otherBus.handler, WithOperators(operator.Debounce(5*time.Millisecond)))
+
+	emitIDs(a, "slow", "1")
+	select {
+	case <-entered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("slow handler not called")
+	}
+	emitIDs(a, "fast", "2")
+	emitIDs(b, "fast", "3")
+	if ids := sameBus.waitIDs(1); len(ids) != 1 {
+		t.Errorf("same bus delivery blocked by slow handler: %v", ids)
+	}
+	if ids := otherBus.waitIDs(1); len(ids) != 1 {
+		t.Errorf("other bus delivery blocked by slow handler: %v", ids)
+	}
+
+	// 算子定时器不计入延迟事件积压
+	b.(core.OptionSubscriber).OnWith("idle", otherBus.handler, WithOperators(operator.Debounce(time.Hour)))
+	emitIDs(b, "idle", "4")
+	if d := b.Stats().Depth; d != 0 {
+		t.Errorf("Stats().Depth = %d with a pending operator timer, want 0", d)
+	}
+}
+
+// TestOperatorCoalesceByAndFilter CoalesceBy 之后的算子按 key 独立生效；Filter 丢弃不满足条件的事件
+func TestOperatorCoalesceByAndFilter(t *testing.T) {
+	for name, build := range awaitBuilders() {
+		t.Run(name, func(t *testing.T) {
+			bus, err := build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer bus.Close()
+
+			var c collector
+			bus.(core.OptionSubscriber).OnWith("cache.invalidate", c.handler, WithOperators(
+				operator.Filter(func(e *Event) bool { return !strings.HasPrefix(e.ID, "skip") }),
+				operator.CoalesceBy(func(e *Event) string { return e.ID[:1] }),
+				operator.Debounce(30*time.Millisecond),
+			))
+			emitIDs(bus, "cache.invalidate", "a1", "b1", "a2", "skip-a", "b2", "a3", "skip-b")
+
+			c.waitIDs(2)
+			time.Sleep(60 * time.Millisecond)
+			ids := c.get()
+			sort.Strings(ids)
+			if len(ids) != 2 || ids[0] != "a3" || ids[1] != "b2" {
+				t.Errorf("delivered %v, want [a3 b2]", ids)
+			}
+		})
+	}
+}
+
+// TestOperatorBuffer 攒满 n 个同步投递整批，不足 n 个时超时投递
+func TestOperatorBuffer(t *testing.T) {
+	bus, _ := ForSync()
+	defer bus.Close()
+
+	batches := make(chan []string, 4)
+	bus.(core.OptionSubscriber).OnWith("audit", func(e *Event) error {
+		var ids []string
+		for _, evt := range operator.Batch(e) {
+			ids = append(ids, evt.ID)
+		}
+		batches <- ids
+		return nil
+	}, WithOperators(operator.Buffer(3, 30*time.Millisecond)))
+
+	emitIDs(bus, "audit", "1", "2", "3", "4", "5")
+	select {
+	case b := <-batches:
+		if strings.Join(b, ",") != "1,2,3" {
+			t.Errorf("first batch = %v, want [1 2 3]", b)
+		}
+	default:
+		t.Fatal("full batch not delivered synchronously")
+	}
+	select {
+	case b := <-batches:
+		if strings.Join(b, ",") != "4,5" {
+			t.Errorf("second batch = %v, want [4 5]", b)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("partial batch not delivered on timeout")
+	}
+}
+
+// TestOperatorOffAndPanic Off 取消未到期的定时投递；定时投递中的 panic 按订阅上报
+func TestOperatorOffAndPanic(t *testing.T) {
+	for name, build := range awaitBuilders() {
+		t.Run(name, func(t *testing.T) {
+			bus, err := build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer bus.Close()
+			os := bus.(core.OptionSubscriber)
+
+			var c collector
+			id := os.OnWith("job", c.handler, WithOperators(operator.Debounce(20*time.Millisecond)))
+			_ = bus.Emit(&Event{Type: "job", ID: "cancelled"})
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = bus.(Awaiter).Barrier(ctx) // 异步实现: 等待事件到达算子
+			bus.Off(id)
+
+			panicked := make(chan SubInfo, 1)
+			bus.(core.PanicNotifier).SetPanicInfoHandler(func(r any, evt *Event, sub SubInfo) {
+				panicked <- sub
+			})
+			pid := os.OnWith("boom", func(e *Event) error {
+				panic("deferred handler failure")
+			}, WithOperators(operator.Sample(10*time.Millisecond)))
+			_ = bus.Emit(&Event{Type: "boom"})
+
+			select {
+			case sub := <-panicked:
+				if sub.ID != pid {
+					t.Errorf("panic reported for sub %d, want %d", sub.ID, pid)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("panic in deferred delivery not reported")
+			}
+			time.Sleep(40 * time.Millisecond)
+			if ids := c.get(); len(ids) != 0 {
+				t.Errorf("delivered %v after Off", ids)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package beat
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// orderChecker 记录每个 key 最近处理的序号，检测乱序
+type orderChecker struct {
+	mu       sync.Mutex
+	last     map[string]int
+	disorder int
+	total    atomic.Int64
+}
+
+func newOrderChecker() *orderChecker {
+	return &orderChecker{last: make(map[string]int)}
+}
+
+func (c *orderChecker) handler(e *Event) error {
+	seq := int(e.Data[0])<<16 | int(e.Data[1])<<8 | int(e.Data[2])
+	c.mu.Lock()
+	prev, ok := c.last[e.Key]
+	if (ok && seq != prev+1) || (!ok && seq != 0) {
+		c.disorder++
+	}
+	c.last[e.Key] = seq
+	c.mu.Unlock()
+	c.total.Add(1)
+	return nil
+}
+
+func seqData(seq int) []byte {
+	return []byte{byte(seq >> 16), byte(seq >> 8), byte(seq)}
+}
+
+// withWorkers 固定 worker 数（Cores/2），保证低核机器上 key 也分散到多个 worker
+func withWorkers(n int) Opt {
+	return func(p *Profile) { p.Cores = n * 2 }
+}
+
+func (c *orderChecker) wait(t *testing.T, want int64) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for c.total.Load() < want && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := c.total.Load(); got != want {
+		t.Fatalf("processed %d events, want %d", got, want)
+	}
+}
+
+// TestOrderedPerKeyManyProducers 多生产者并发发布，每个 key 的处理顺序与发布顺序一致
+func TestOrderedPerKeyManyProducers(t *testing.T) {
+	bus, err := ForAsync(WithOrdered(), withWorkers(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bus.Close()
+
+	c := newOrderChecker()
+	bus.On("order.updated", c.handler)
+
+	const (
+		producers    = 16
+		keysPerProd  = 8
+		eventsPerKey = 500
+	)
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			keys := make([]string, keysPerProd)
+			for k := range keys {
+				keys[k] = fmt.Sprintf("order-%d-%d", p, k)
+			}
+			for seq := 0; seq < eventsPerKey; seq++ {
+				for _, key := range keys {
+					_ = bus.Emit(&Event{Type: "order.updated", Key: key, Data: seqData(seq)})
+				}
+				if seq%50 == 0 {
+					runtime.Gosched() // 促使生产者在 P 之间迁移
+				}
+			}
+		}(p)
+	}
+	wg.Wait()
+
+	c.wait(t, producers*keysPerProd*eventsPerKey)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.disorder != 0 {
+		t.Errorf("%d events processed out of per-key order", c.disorder)
+	}
+	if len(c.last) != producers*keysPerProd {
+		t.Errorf("saw %d keys, want %d", len(c.last), producers*keysPerProd)
+	}
+}
+
+// TestOrderedSingleKeyMigratingProducer 单生产者跨 P 迁移时同一 key 仍保序，
+// 且无 Key 事件照常投递
+func TestOrderedSingleKeyMigratingProducer(t *testing.T) {
+	bus, err := ForAsync(WithOrdered(), withWorkers(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bus.Close()
+
+	c := newOrderChecker()
+	bus.On("account.tx", c.handler)
+	var unkeyed atomic.Int64
+	bus.On("account.ping", func(e *Event) error {
+		unkeyed.Add(1)
+		return nil
+	})
+
+	const n = 20000
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for seq := 0; seq < n; seq++ {
+			_ = bus.Emit(&Event{Type: "account.tx", Key: "acct-1", Data: seqData(seq)})
+			_ = bus.Emit(&Event{Type: "account.ping"})
+			if seq%16 == 0 {
+				runtime.Gosched()
+			}
+		}
+	}()
+	<-done
+
+	c.wait(t, n)
+	c.mu.Lock()
+	if c.disorder != 0 {
+		t.Errorf("%d events processed out of order", c.disorder)
+	}
+	c.mu.Unlock()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for unkeyed.Load() < n && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := unkeyed.Load(); got != n {
+		t.Errorf("unkeyed events processed = %d, want %d", got, n)
+	}
+}
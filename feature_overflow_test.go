@@ -0,0 +1,213 @@
+package beat
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/uniyakcom/beat/core"
+	implasync "github.com/uniyakcom/beat/internal/impl/async"
+	implsync "github.com/uniyakcom/beat/internal/impl/sync"
+)
+
+// gatedCounter 阻塞所有 handler 直到 release，模拟卡住的消费者
+type gatedCounter struct {
+	gate chan struct{}
+	once sync.Once
+	n    atomic.Int64
+}
+
+func newGatedCounter() *gatedCounter {
+	return &gatedCounter{gate: make(chan struct{})}
+}
+
+func (g *gatedCounter) handler(e *Event) error {
+	<-g.gate
+	g.n.Add(1)
+	return nil
+}
+
+func (g *gatedCounter) release() {
+	g.once.Do(func() { close(g.gate) })
+}
+
+// waitFor 轮询直到 cond 成立或超时
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) bool {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	return true
+}
+
+// smallRingCapacity 单 worker、64 槽 ring 时所有 Per-P ring 的总容量上界
+func smallRingCapacity() int {
+	rings := 1
+	for rings < runtime.GOMAXPROCS(0) {
+		rings *= 2
+	}
+	return 64 * rings
+}
+
+func newSmallAsync(policy core.OverflowPolicy, timeout time.Duration) *implasync.Bus {
+	return implasync.New(&implasync.Config{
+		Workers:         1,
+		RingSize:        64,
+		Overflow:        policy,
+		OverflowTimeout: timeout,
+	})
+}
+
+// TestOverflowBlockTimeout 消费者卡住时 Emit 在超时后返回 ErrQueueFull，而非永久阻塞
+func TestOverflowBlockTimeout(t *testing.T) {
+	bus, err := ForAsync(WithOverflow(OverflowBlockTimeout, 5*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bus.Close()
+	g := newGatedCounter()
+	defer g.release()
+	bus.On("job", g.handler)
+
+	var full error
+	for i := 0; i < 1<<22 && full == nil; i++ {
+		full = bus.Emit(&Event{Type: "job"})
+	}
+	if !errors.Is(full, ErrQueueFull) {
+		t.Fatalf("Emit err = %v, want ErrQueueFull", full)
+	}
+	if got := bus.Stats().Dropped; got != 1 {
+		t.Errorf("Stats().Dropped = %d, want 1", got)
+	}
+}
+
+// TestOverflowDropPolicies 丢弃策略下 已处理+已丢弃 = 已发布，且不阻塞发布者
+func TestOverflowDropPolicies(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		policy core.OverflowPolicy
+	}{
+		{"newest", core.OverflowDropNewest},
+		{"oldest", core.OverflowDropOldest},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			bus := newSmallAsync(tc.policy, 0)
+			defer bus.Close()
+			g := newGatedCounter()
+			defer g.release()
+
+			var lastSeen atomic.Bool
+			bus.On("job", g.handler)
+			bus.On("job", func(e *Event) error {
+				if string(e.Data) == "last" {
+					lastSeen.Store(true)
+				}
+				return nil
+			})
+
+			total := 3*smallRingCapacity() + 500
+			for i := 0; i < total-1; i++ {
+				if err := bus.Emit(&Event{Type: "job"}); err != nil {
+					t.Fatalf("Emit err = %v", err)
+				}
+			}
+			_ = bus.Emit(&Event{Type: "job", Data: []byte("last")})
+
+			dropped := bus.Stats().Dropped
+			if dropped == 0 {
+				t.Fatal("expected drops with a stuck consumer")
+			}
+			g.release()
+			ok := waitFor(t, 5*time.Second, func() bool {
+				return g.n.Load()+bus.Stats().Dropped == int64(total)
+			})
+			if !ok {
+				t.Fatalf("processed %d + dropped %d != emitted %d", g.n.Load(), bus.Stats().Dropped, total)
+			}
+			// DropOldest 保留最新事件
+			if tc.policy == core.OverflowDropOldest && !lastSeen.Load() {
+				t.Error("newest event was dropped under OverflowDropOldest")
+			}
+		})
+	}
+}
+
+// TestOverflowSpillKeepsOrder Spill 不丢弃、不阻塞，且按键有序模式下溢出后仍保持 FIFO
+func TestOverflowSpillKeepsOrder(t *testing.T) {
+	bus := implasync.New(&implasync.Config{
+		Workers:  2,
+		RingSize: 64,
+		Ordered:  true,
+		Overflow: core.OverflowSpill,
+	})
+	defer bus.Close()
+
+	gate := make(chan struct{})
+	c := newOrderChecker()
+	bus.On("tx", func(e *Event) error {
+		<-gate
+		return c.handler(e)
+	})
+
+	const producers, perKey = 4, 2000
+	var wg sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			key := fmt.Sprintf("acct-%d", p)
+			for seq := 0; seq < perKey; seq++ {
+				if err := bus.Emit(&Event{Type: "tx", Key: key, Data: seqData(seq)}); err != nil {
+					t.Errorf("Emit err = %v", err)
+					return
+				}
+			}
+		}(p)
+	}
+	wg.Wait() // 消费者全部卡住时发布者仍能完成
+	close(gate)
+
+	c.wait(t, producers*perKey)
+	if d := bus.Stats().Dropped; d != 0 {
+		t.Errorf("Stats().Dropped = %d, want 0", d)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.disorder != 0 {
+		t.Errorf("%d events processed out of per-key order", c.disorder)
+	}
+}
+
+// TestOverflowSyncAsyncMode Sync 异步模式共享调度器，同样支持溢出策略
+func TestOverflowSyncAsyncMode(t *testing.T) {
+	b, err := implsync.New(&implsync.Config{Async: true, Overflow: core.OverflowDropNewest})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer b.Close()
+	g := newGatedCounter()
+	defer g.release()
+	b.On("job", g.handler)
+
+	total := 3 * 8192 * (smallRingCapacity() / 64)
+	for i := 0; i < total; i++ {
+		if err := b.Emit(&Event{Type: "job"}); err != nil {
+			t.Fatalf("Emit err = %v", err)
+		}
+	}
+	if b.Stats().Dropped == 0 {
+		t.Fatal("expected drops with a stuck consumer")
+	}
+	g.release()
+	if !waitFor(t, 5*time.Second, func() bool { return g.n.Load()+b.Stats().Dropped == int64(total) }) {
+		t.Fatalf("processed %d + dropped %d != emitted %d", g.n.Load(), b.Stats().Dropped, total)
+	}
+}
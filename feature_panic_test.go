@@ -0,0 +1,190 @@
+package beat
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/uniyakcom/beat/core"
+)
+
+// panicReport PanicInfoHandler 收到的参数
+type panicReport struct {
+	recovered interface{}
+	evtType   string
+	sub       SubInfo
+	calls     int
+}
+
+// panicRecord 并发安全地记录 panicReport
+type panicRecord struct {
+	mu sync.Mutex
+	panicReport
+}
+
+func (r *panicRecord) handler() PanicInfoHandler {
+	return func(rec interface{}, evt *Event, sub SubInfo) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.recovered = rec
+		if evt != nil {
+			r.evtType = evt.Type
+		}
+		r.sub = sub
+		r.calls++
+	}
+}
+
+func (r *panicRecord) snapshot() panicReport {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.panicReport
+}
+
+// TestPanicHandlerAllImpls 三种实现均把 recover 值、事件与订阅信息交给 PanicInfoHandler
+func TestPanicHandlerAllImpls(t *testing.T) {
+	builders := map[string]func(...Opt) (Bus, error){
+		"sync":  ForSync,
+		"async": ForAsync,
+		"flow":  ForFlow,
+	}
+	for name, build := range builders {
+		t.Run(name, func(t *testing.T) {
+			rec := &panicRecord{}
+			bus, err := build(WithPanicInfoHandler(rec.handler()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer bus.Close()
+
+			var after int32
+			bus.On("order.paid", func(e *Event) error { return nil })
+			badID := bus.On("order.paid", func(e *Event) error { panic("boom") })
+			bus.On("order.paid", func(e *Event) error {
+				atomic.AddInt32(&after, 1)
+				return nil
+			})
+
+			err = bus.Emit(&Event{Type: "order.paid"})
+			if name == "sync" && err == nil {
+				t.Error("sync Emit should return panic as error")
+			}
+
+			deadline := time.Now().Add(2 * time.Second)
+			for rec.snapshot().calls == 0 && time.Now().Before(deadline) {
+				time.Sleep(5 * time.Millisecond)
+			}
+
+			got := rec.snapshot()
+			if got.calls != 1 {
+				t.Fatalf("panic handler calls = %d, want 1", got.calls)
+			}
+			if got.recovered != "boom" {
+				t.Errorf("recovered = %v, want boom", got.recovered)
+			}
+			if got.evtType != "order.paid" {
+				t.Errorf("event type = %q, want order.paid", got.evtType)
+			}
+			if got.sub.ID != badID || got.sub.Pattern != "order.paid" {
+				t.Errorf("sub = %+v, want {ID:%d Pattern:order.paid}", got.sub, badID)
+			}
+			if bus.Stats().Panics != 1 {
+				t.Errorf("Stats().Panics = %d, want 1", bus.Stats().Panics)
+			}
+
+			// Async/Flow: panic 仅跳过出错的 handler，后续 handler 照常执行
+			if name != "sync" {
+				for atomic.LoadInt32(&after) == 0 && time.Now().Before(deadline) {
+					time.Sleep(5 * time.Millisecond)
+				}
+				if atomic.LoadInt32(&after) != 1 {
+					t.Error("handler after the panicking one was not called")
+				}
+			}
+		})
+	}
+}
+
+// TestPanicHandlerLegacy 不带订阅信息的 PanicHandler 仍可经选项与 SetPanicHandler 注册
+func TestPanicHandlerLegacy(t *testing.T) {
+	builders := map[string]func(...Opt) (Bus, error){
+		"sync":  ForSync,
+		"async": ForAsync,
+		"flow":  ForFlow,
+	}
+	for name, build := range builders {
+		t.Run(name, func(t *testing.T) {
+			got := make(chan string, 2)
+			var h PanicHandler = func(r interface{}, evt *Event) { got <- evt.Type }
+			bus, err := build(WithPanicHandler(h))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer bus.Close()
+			bus.On("job", func(*Event) error { panic("boom") })
+
+			expect := func(want string) {
+				t.Helper()
+				select {
+				case typ := <-got:
+					if typ != want {
+						t.Errorf("panic handler got %q, want %q", typ, want)
+					}
+				case <-time.After(2 * time.Second):
+					t.Fatalf("panic handler not called, want %q", want)
+				}
+			}
+			_ = bus.Emit(&Event{Type: "job"})
+			expect("job")
+			bus.(core.PanicNotifier).SetPanicHandler(func(r interface{}, evt *Event) { got <- "set:" + evt.Type })
+			_ = bus.Emit(&Event{Type: "job"})
+			expect("set:job")
+		})
+	}
+}
+
+// TestPanicNotifierWildcard 运行时注册 + 通配符订阅的 pattern 上报
+func TestPanicNotifierWildcard(t *testing.T) {
+	bus, _ := ForSync()
+	defer bus.Close()
+
+	pn, ok := bus.(core.PanicNotifier)
+	if !ok {
+		t.Fatal("sync bus should implement core.PanicNotifier")
+	}
+	rec := &panicRecord{}
+	pn.SetPanicInfoHandler(rec.handler())
+
+	id := bus.On("user.*", func(e *Event) error { panic(errors.New("bad user")) })
+
+	if err := bus.EmitMatch(&Event{Type: "user.created"}); err == nil {
+		t.Error("EmitMatch should return panic as error")
+	}
+	got := rec.snapshot()
+	if got.sub.ID != id || got.sub.Pattern != "user.*" || got.evtType != "user.created" {
+		t.Errorf("unexpected panic report: %+v", got)
+	}
+}
+
+// TestPanicHandlerFlowStage Flow Stage panic 上报（evt 为 nil，sub 为零值）
+func TestPanicHandlerFlowStage(t *testing.T) {
+	rec := &panicRecord{}
+	bus, _ := ForFlow(
+		WithPanicInfoHandler(rec.handler()),
+		WithStages(func(batch []*Event) ([]*Event, error) { panic("stage") }),
+	)
+	defer bus.Close()
+
+	bus.Emit(&Event{Type: "etl"})
+
+	deadline := time.Now().Add(2 * time.Second)
+	for rec.snapshot().calls == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	got := rec.snapshot()
+	if got.calls != 1 || got.recovered != "stage" || got.sub != (SubInfo{}) {
+		t.Errorf("unexpected stage panic report: %+v", got)
+	}
+}
@@ -0,0 +1,346 @@
+package beat
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/uniyakcom/beat/core"
+	implsync "github.com/uniyakcom/beat/internal/impl/sync"
+)
+
+// TestCompilePattern 语法校验：无效 pattern 返回包装 ErrBadPattern 的错误
+func TestCompilePattern(t *testing.T) {
+	for _, p := range []string{
+		"user.created", "user.*", "user.**", "a.**.z", "user.*ed", "*.created",
+		"order.{created,paid}", "user{,s}.*", "{a,b}{c,d}.x",
+	} {
+		if _, err := CompilePattern(p); err != nil {
+			t.Errorf("CompilePattern(%q) = %v, want nil", p, err)
+		}
+	}
+	for _, p := range []string{
+		"", ".", "user.", "a..b", "a.{b", "a.b}", "a.{b,{c}}",
+		strings.Repeat("a.", 16) + "a",
+		"{a,b,c,d,e,f,g,h}{a,b,c,d,e,f,g,h}{a,b}",
+	} {
+		if _, err := CompilePattern(p); !errors.Is(err, ErrBadPattern) {
+			t.Errorf("CompilePattern(%q) = %v, want ErrBadPattern", p, err)
+		}
+	}
+	for _, p := range []string{"sensor/+/temp", "sensor/#", "#", "home/*.light"} {
+		if _, err := MQTTSyntax.Compile(p); err != nil {
+			t.Errorf("MQTTSyntax.Compile(%q) = %v, want nil", p, err)
+		}
+	}
+	for _, p := range []string{"sensor/a+", "sensor/+ed", "sensor/#x", "sensor//x"} {
+		if _, err := MQTTSyntax.Compile(p); !errors.Is(err, ErrBadPattern) {
+			t.Errorf("MQTTSyntax.Compile(%q) = %v, want ErrBadPattern", p, err)
+		}
+	}
+}
+
+// TestPatternMatch 段内通配、备选、中间 **：Pattern.Match 与 TrieMatcher 结果一致
+func TestPatternMatch(t *testing.T) {
+	cases := []struct {
+		syntax  PatternSyntax
+		pattern string
+		match   []string
+		miss    []string
+	}{
+		{DotSyntax, "user.*ed", []string{"user.created", "user.ed", "user.deleted"}, []string{"user.create", "user.x.ed", "users.created"}},
+		{DotSyntax, "order.{created,paid}", []string{"order.created", "order.paid"}, []string{"order.shipped", "order.created.x"}},
+		{DotSyntax, "user{,s}.*", []string{"user.a", "users.b"}, []string{"userx.a", "user"}},
+		{DotSyntax, "a.**.z", []string{"a.z", "a.b.z", "a.b.c.z"}, []string{"a", "a.b", "a.z.b", "b.z"}},
+		{DotSyntax, "**.error", []string{"error", "db.error", "db.conn.error"}, []string{"db.errors"}},
+		{DotSyntax, "*.{a*,b}.**", []string{"x.ab", "x.b.c.d", "x.a"}, []string{"x.c", "x"}},
+		{DotSyntax, "a.**.b.**.c", []string{"a.b.c", "a.x.b.y.z.c", "a.b.b.c.c"}, []string{"a.c.b", "a.b.c.d", "a.x.c"}},
+		{DotSyntax, "**.**.x.**", []string{"x", "a.x", "a.b.x.c"}, []string{"a.b", "xa.b"}},
+		{MQTTSyntax, "sensor/+/temp", []string{"sensor/1/temp"}, []string{"sensor/temp", "sensor/1/2/temp", "sensor.1.temp"}},
+		{MQTTSyntax, "sensor/#", []string{"sensor", "sensor/1", "sensor/1/temp"}, []string{"sensors/1"}},
+		{MQTTSyntax, "home/*.light", []string{"home/kitchen.light"}, []string{"home/kitchen/light"}},
+	}
+	for _, c := range cases {
+		p, err := c.syntax.Compile(c.pattern)
+		if err != nil {
+			t.Fatalf("Compile(%q): %v", c.pattern, err)
+		}
+		m := core.NewTrieMatcherWith(c.syntax)
+		if err := m.Add(c.pattern); err != nil {
+			t.Fatalf("Add(%q): %v", c.pattern, err)
+		}
+		for _, want := range []bool{true, false} {
+			list := c.match
+			if !want {
+				list = c.miss
+			}
+			for _, typ := range list {
+				if got := p.Match(typ); got != want {
+					t.Errorf("%q.Match(%q) = %v, want %v", c.pattern, typ, got, want)
+				}
+				sp := m.Match(typ)
+				if got := len(*sp) == 1; got != want {
+					t.Errorf("TrieMatcher %q Match(%q) = %v, want %v", c.pattern, typ, *sp, want)
+				}
+				m.Put(sp)
+				if got := m.HasMatch(typ); got != want {
+					t.Errorf("TrieMatcher %q HasMatch(%q) = %v, want %v", c.pattern, typ, got, want)
+				}
+			}
+		}
+	}
+}
+
+// TestPatternMultiWildcardBound 多个 ** 不再组合回溯：最坏情况也应在线性时间内完成
+func TestPatternMultiWildcardBound(t *testing.T) {
+	pattern := strings.Repeat("**.", 11) + "z"
+	miss := strings.Repeat("a.", 15) + "a"
+	hit := strings.Repeat("a.", 15) + "z"
+
+	p, err := CompilePattern(pattern)
+	if err != nil {
+		t.Fatalf("CompilePattern(%q): %v", pattern, err)
+	}
+	m := core.NewTrieMatcher()
+	if err := m.Add(pattern); err != nil {
+		t.Fatalf("Add(%q): %v", pattern, err)
+	}
+
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		if p.Match(miss) || !p.Match(hit) {
+			t.Fatalf("Pattern.Match: miss=%v hit=%v", p.Match(miss), p.Match(hit))
+		}
+		if m.HasMatch(miss) || !m.HasMatch(hit) {
+			t.Fatalf("TrieMatcher.HasMatch: miss=%v hit=%v", m.HasMatch(miss), m.HasMatch(hit))
+		}
+		sp := m.Match(miss)
+		n := len(*sp)
+		m.Put(sp)
+		sp = m.Match(hit)
+		n2 := len(*sp)
+		m.Put(sp)
+		if n != 0 || n2 != 1 {
+			t.Fatalf("TrieMatcher.Match: miss=%d hit=%d results", n, n2)
+		}
+	}
+	// 组合回溯时单次不匹配即需 C(26,11) 量级的分支（秒级）；有界实现 10 轮仅需微秒
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("10 rounds of %q took %v", pattern, d)
+	}
+}
+
+// TestPatternMatcherAddRemove 无效 pattern 由 Add 返回错误；Remove 后不再匹配
+func TestPatternMatcherAddRemove(t *testing.T) {
+	m := core.NewTrieMatcher()
+	if err := m.Add(strings.Repeat("a.", 20) + "a"); !errors.Is(err, ErrBadPattern) {
+		t.Errorf("Add(over-deep) = %v, want ErrBadPattern", err)
+	}
+	if err := m.Add("a.{b"); !errors.Is(err, ErrBadPattern) {
+		t.Errorf("Add(a.{b) = %v, want ErrBadPattern", err)
+	}
+	for _, p := range []string{"a.**.z", "a.*.z", "a.{b,c}.z", "a.b.z"} {
+		if err := m.Add(p); err != nil {
+			t.Fatalf("Add(%q): %v", p, err)
+		}
+	}
+	match := func(typ string) []string {
+		sp := m.Match(typ)
+		defer m.Put(sp)
+		got := append([]string(nil), *sp...)
+		sort.Strings(got)
+		return got
+	}
+	if got := match("a.b.z"); strings.Join(got, " ") != "a.**.z a.*.z a.b.z a.{b,c}.z" {
+		t.Errorf("Match(a.b.z) = %v", got)
+	}
+	m.Remove("a.{b,c}.z")
+	m.Remove("a.**.z")
+	if got := match("a.b.z"); strings.Join(got, " ") != "a.*.z a.b.z" {
+		t.Errorf("after Remove Match(a.b.z) = %v", got)
+	}
+	m.Remove("a.*.z")
+	m.Remove("a.b.z")
+	if m.HasMatch("a.b.z") || m.HasMatch("a.c.z") {
+		t.Error("HasMatch after removing all patterns = true")
+	}
+}
+
+// TestPatternSyntaxBus 各 Bus 按 WithPatternSyntax 解析订阅；无效 pattern 不订阅并返回 0
+func TestPatternSyntaxBus(t *testing.T) {
+	for name, build := range map[string]func() (Bus, error){
+		"sync":       func() (Bus, error) { return ForSync(WithPatternSyntax(MQTTSyntax)) },
+		"sync-async": func() (Bus, error) { return implsync.New(&implsync.Config{Async: true, PatternSyntax: MQTTSyntax}) },
+		"async":      func() (Bus, error) { return ForAsync(withWorkers(2), WithPatternSyntax(MQTTSyntax)) },
+		"flow":       func() (Bus, error) { return ForFlow(WithPatternSyntax(MQTTSyntax)) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			bus, err := build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer bus.Close()
+
+			var temp, all, exact collector
+			bus.On("sensor/+/temp", temp.handler)
+			bus.On("sensor/#", all.handler)
+			bus.On("sensor/1/temp", exact.handler)
+			if id := bus.On("sensor/a+", all.handler); id != 0 {
+				t.Errorf("On(invalid) = %d, want 0", id)
+			}
+
+			for id, typ := range map[string]string{
+				"a": "sensor/1/temp",
+				"b": "sensor/2/humidity",
+				"c": "sensor",
+				"d": "sensor.1.temp",
+			} {
+				_ = bus.EmitMatch(&Event{Type: typ, ID: id})
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = bus.(Awaiter).Barrier(ctx)
+
+			for name, c := range map[string]struct {
+				got  []string
+				want string
+			}{
+				"sensor/+/temp": {temp.get(), "a"},
+				"sensor/#":      {all.get(), "abc"},
+				"sensor/1/temp": {exact.get(), "a"},
+			} {
+				sort.Strings(c.got)
+				if got := strings.Join(c.got, ""); got != c.want {
+					t.Errorf("%s got %q, want %q", name, got, c.want)
+				}
+			}
+		})
+	}
+
+	if _, err := ForAsync(WithRetain("a.{b")); !errors.Is(err, ErrBadPattern) {
+		t.Errorf("ForAsync(WithRetain(invalid)) err = %v, want ErrBadPattern", err)
+	}
+	if _, err := ForSync(WithPatternSyntax(MQTTSyntax), WithEmitRateLimit("a/#b", 1, 1, RateDrop)); !errors.Is(err, ErrBadPattern) {
+		t.Errorf("ForSync(WithEmitRateLimit(invalid)) err = %v, want ErrBadPattern", err)
+	}
+}
+
+// TestTryOnBadPattern 无效 pattern: TryOn 返回包装 ErrBadPattern 的错误，On / OnWith 返回 0 并经 ErrorHandler 上报
+func TestTryOnBadPattern(t *testing.T) {
+	for name, build := range awaitBuilders() {
+		t.Run(name, func(t *testing.T) {
+			bus, err := build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer bus.Close()
+
+			var mu sync.Mutex
+			var reported []string
+			record := func(kind string) ErrorHandler {
+				return func(err error, evt *Event, sub SubInfo) {
+					if !errors.Is(err, ErrBadPattern) || evt != nil || sub.ID != 0 {
+						t.Errorf("%s reported (%v, %v, %+v)", kind, err, evt, sub)
+					}
+					mu.Lock()
+					reported = append(reported, kind+":"+sub.Pattern)
+					mu.Unlock()
+				}
+			}
+			bus.(core.ErrorNotifier).SetErrorHandler(record("bus"))
+			ts := bus.(TrySubscriber)
+
+			var c collector
+			if id, err := ts.TryOn("a.{b", c.handler, WithErrorSink(record("sink"))); id != 0 || !errors.Is(err, ErrBadPattern) {
+				t.Errorf("TryOn(invalid) = (%d, %v), want (0, ErrBadPattern)", id, err)
+			}
+			if id := bus.On("a..b", c.handler); id != 0 {
+				t.Errorf("On(invalid) = %d, want 0", id)
+			}
+			id, err := ts.TryOn("a.*", c.handler)
+			if id == 0 || err != nil {
+				t.Fatalf("TryOn(valid) = (%d, %v)", id, err)
+			}
+
+			_ = bus.EmitMatch(&Event{Type: "a.b", ID: "x"})
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = bus.(Awaiter).Barrier(ctx)
+			if got := strings.Join(c.get(), ""); got != "x" {
+				t.Errorf("valid subscription got %q, want %q", got, "x")
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if got, want := strings.Join(reported, " "), "sink:a.{b bus:a.{b bus:a..b"; got != want {
+				t.Errorf("reported %q, want %q", got, want)
+			}
+		})
+	}
+
+	if _, err := TryOn("a.{b", func(*Event) error { return nil }); !errors.Is(err, ErrBadPattern) {
+		t.Errorf("beat.TryOn(invalid) err = %v, want ErrBadPattern", err)
+	}
+}
+
+// TestMatcherConcurrentChurn 并发 Add / Remove 时读者无锁遍历的快照始终完整：常驻 pattern 每次都能匹配到
+func TestMatcherConcurrentChurn(t *testing.T) {
+	m := core.NewTrieMatcherSize(core.DotSyntax, -1)
+	for _, p := range []string{"a.*.c", "a.**", "a.b.c", "a.{b,x}.c"} {
+		_ = m.Add(p)
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	for w := 0; w < 2; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			churn := []string{"a.b.*", fmt.Sprintf("a.*.c%d", w), "a.b.c", "**.c", "a.*ed.c"}
+			for i := 0; ; i++ {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				p := churn[i%len(churn)]
+				_ = m.Add(p)
+				m.Remove(p)
+			}
+		}(w)
+	}
+
+	for i := 0; i < 20000; i++ {
+		sp := m.Match("a.b.c")
+		got := map[string]bool{}
+		for _, p := range *sp {
+			got[p] = true
+		}
+		m.Put(sp)
+		for _, p := range []string{"a.*.c", "a.**", "a.b.c", "a.{b,x}.c"} {
+			if !got[p] {
+				close(stop)
+				wg.Wait()
+				t.Fatalf("iteration %d: Match(a.b.c) missing stable pattern %q", i, p)
+			}
+		}
+		if !m.HasMatch("a.x.c") {
+			close(stop)
+			wg.Wait()
+			t.Fatalf("iteration %d: HasMatch(a.x.c) = false", i)
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	sp := m.Match("a.b.c")
+	defer m.Put(sp)
+	if len(*sp) != 4 {
+		t.Errorf("after churn Match(a.b.c) = %v, want the 4 stable patterns", *sp)
+	}
+}
@@ -0,0 +1,206 @@
+package beat
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/uniyakcom/beat/core"
+	implsync "github.com/uniyakcom/beat/internal/impl/sync"
+)
+
+// recorder 记录 handler 调用顺序
+type recorder struct {
+	mu    sync.Mutex
+	calls []string
+}
+
+func (r *recorder) handler(name string, ret error) Handler {
+	return func(e *Event) error {
+		r.mu.Lock()
+		r.calls = append(r.calls, name)
+		r.mu.Unlock()
+		return ret
+	}
+}
+
+func (r *recorder) take() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := r.calls
+	r.calls = nil
+	return out
+}
+
+// TestPriorityOrdering 优先级高者先调用，相同优先级按注册顺序；Off 后顺序保持
+func TestPriorityOrdering(t *testing.T) {
+	bus, _ := ForSync()
+	defer bus.Close()
+	ow := bus.(core.OptionSubscriber)
+
+	var r recorder
+	ow.OnWith("user.created", r.handler("audit", nil), WithPriority(-10))
+	first := ow.OnWith("user.created", r.handler("a", nil))
+	ow.OnWith("user.created", r.handler("auth", nil), WithPriority(100))
+	ow.OnWith("user.created", r.handler("b", nil))
+
+	_ = bus.Emit(&Event{Type: "user.created"})
+	if got, want := r.take(), []string{"auth", "a", "b", "audit"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Emit order = %v, want %v", got, want)
+	}
+
+	bus.Off(first)
+	_ = bus.UnsafeEmit(&Event{Type: "user.created"})
+	if got, want := r.take(), []string{"auth", "b", "audit"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("order after Off = %v, want %v", got, want)
+	}
+}
+
+// TestPriorityMergedWildcardChain 通配符与精确订阅合并为一条调用链排序（含 Sync 异步模式）
+func TestPriorityMergedWildcardChain(t *testing.T) {
+	builders := map[string]func() (Bus, error){
+		"sync":       func() (Bus, error) { return ForSync() },
+		"sync-async": func() (Bus, error) { return implsync.New(&implsync.Config{Async: true}) },
+	}
+	for name, build := range builders {
+		t.Run(name, func(t *testing.T) {
+			bus, err := build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer bus.Close()
+			ow := bus.(core.OptionSubscriber)
+
+			var r recorder
+			ow.OnWith("user.created", r.handler("exact", nil))
+			ow.OnWith("user.**", r.handler("auth", nil), WithPriority(10))
+			ow.OnWith("user.*", r.handler("log", nil), WithPriority(-1))
+			ow.OnWith("*.created", r.handler("metrics", nil))
+
+			_ = bus.EmitMatch(&Event{Type: "user.created"})
+			if got, want := r.take(), []string{"auth", "exact", "metrics", "log"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("EmitMatch order = %v, want %v", got, want)
+			}
+
+			// 精确发布只调用精确订阅
+			_ = bus.Emit(&Event{Type: "user.created"})
+			_ = bus.(Awaiter).Barrier(context.Background())
+			if got, want := r.take(), []string{"exact"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("Emit order = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+// TestStopPropagation ErrStopPropagation 结束后续分发且不作为错误上报
+func TestStopPropagation(t *testing.T) {
+	bus, _ := ForSync()
+	defer bus.Close()
+	ow := bus.(core.OptionSubscriber)
+
+	var r recorder
+	ow.OnWith("order.*", r.handler("validate", fmt.Errorf("duplicate order: %w", ErrStopPropagation)), WithPriority(1))
+	ow.OnWith("order.paid", r.handler("ship", nil))
+	ow.OnWith("order.paid", r.handler("notify", nil))
+
+	if err := bus.EmitMatch(&Event{Type: "order.paid"}); err != nil {
+		t.Errorf("EmitMatch err = %v, want nil", err)
+	}
+	if got, want := r.take(), []string{"validate"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("calls = %v, want %v", got, want)
+	}
+	if err := bus.UnsafeEmitMatch(&Event{Type: "order.paid"}); err != nil {
+		t.Errorf("UnsafeEmitMatch err = %v, want nil", err)
+	}
+	r.take()
+
+	// 精确路径: 中途停止
+	ow.OnWith("order.paid", r.handler("stop", ErrStopPropagation), WithPriority(1))
+	if err := bus.Emit(&Event{Type: "order.paid"}); err != nil {
+		t.Errorf("Emit err = %v, want nil", err)
+	}
+	if err := bus.EmitBatch([]*Event{{Type: "order.paid"}, {Type: "order.paid"}}); err != nil {
+		t.Errorf("EmitBatch err = %v, want nil", err)
+	}
+	if got, want := r.take(), []string{"stop", "stop", "stop"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("calls = %v, want %v", got, want)
+	}
+	if st := bus.Stats(); st.Errors != 0 {
+		t.Errorf("Stats().Errors = %d, want 0", st.Errors)
+	}
+}
+
+// TestMatcherExactWildcardOverlap 回归: 字面 pattern 与通配符 pattern 同时命中时 Match 返回两者
+// （此前精确快速路径只返回字面 pattern，EmitMatch 漏掉通配符订阅）；
+// 重复注册的字面 pattern 只在最后一次 Remove 后退出匹配
+func TestMatcherExactWildcardOverlap(t *testing.T) {
+	m := core.NewTrieMatcher()
+	match := func(typ string) string {
+		sp := m.Match(typ)
+		defer m.Put(sp)
+		got := append([]string(nil), *sp...)
+		sort.Strings(got)
+		return strings.Join(got, " ")
+	}
+	m.Add("user.created")
+	if got := match("user.created"); got != "user.created" {
+		t.Errorf("exact only: Match = %q", got)
+	}
+	m.Add("user.*")
+	m.Add("user.**")
+	if got := match("user.created"); got != "user.* user.** user.created" {
+		t.Errorf("with wildcards: Match = %q, want all three", got)
+	}
+	m.Remove("user.*")
+	m.Remove("user.**")
+	if got := match("user.created"); got != "user.created" {
+		t.Errorf("after removing wildcards: Match = %q", got)
+	}
+
+	m.Add("user.created")
+	m.Remove("user.created")
+	if got := match("user.created"); got != "user.created" {
+		t.Errorf("one of two registrations removed: Match = %q, want user.created", got)
+	}
+	m.Remove("user.created")
+	if got := match("user.created"); got != "" {
+		t.Errorf("all registrations removed: Match = %q, want none", got)
+	}
+
+	// 各实现的 EmitMatch 同时投递给字面订阅与通配符订阅
+	for name, build := range awaitBuilders() {
+		t.Run(name, func(t *testing.T) {
+			bus, err := build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer bus.Close()
+			var mu sync.Mutex
+			var got []string
+			for _, p := range []string{"user.created", "user.*"} {
+				p := p
+				bus.On(p, func(*Event) error {
+					mu.Lock()
+					got = append(got, p)
+					mu.Unlock()
+					return nil
+				})
+			}
+			_ = bus.EmitMatch(&Event{Type: "user.created"})
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = bus.(Awaiter).Barrier(ctx)
+			mu.Lock()
+			defer mu.Unlock()
+			sort.Strings(got)
+			if strings.Join(got, " ") != "user.* user.created" {
+				t.Errorf("EmitMatch delivered to %v, want both subscriptions", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package beat
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// TestQueueStatsBacklog 消费者卡住时各实现报告积压与最早事件等待时长；放行后积压清零并记录入队→出队延迟
+func TestQueueStatsBacklog(t *testing.T) {
+	const total, stall = 1500, 20 * time.Millisecond
+	for name, build := range awaitBuilders() {
+		t.Run(name, func(t *testing.T) {
+			bus, err := build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer bus.Close()
+			qs := bus.(QueueStatter)
+			if name == "sync" {
+				if got := qs.QueueStats(); len(got) != 0 {
+					t.Fatalf("sync mode QueueStats() = %d queues, want none", len(got))
+				}
+				return
+			}
+
+			g := newGatedCounter()
+			defer g.release()
+			bus.On("job", g.handler)
+			for i := 0; i < total; i++ {
+				_ = bus.Emit(&Event{Type: "job"})
+			}
+			time.Sleep(stall)
+
+			var depth int64
+			var oldest time.Duration
+			for _, q := range qs.QueueStats() {
+				if q.Capacity <= 0 || q.Depth < 0 {
+					t.Errorf("%s[%d]: Capacity %d, Depth %d", q.Kind, q.Index, q.Capacity, q.Depth)
+				}
+				depth += q.Depth
+				oldest = max(oldest, q.OldestAge)
+			}
+			// 至多一批被取出（卡在 handler 中；Flow 一批 512），其余仍在队列里
+			if depth < total/2 || depth > total {
+				t.Errorf("total queue depth = %d, want in [%d, %d]", depth, total/2, total)
+			}
+			if d := bus.Stats().Depth; d != depth {
+				t.Errorf("Stats().Depth = %d, want %d (sum of QueueStats)", d, depth)
+			}
+			if oldest < stall {
+				t.Errorf("oldest queued age = %v, want >= %v", oldest, stall)
+			}
+
+			g.release()
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := bus.(Awaiter).Barrier(ctx); err != nil {
+				t.Fatal(err)
+			}
+			var samples uint64
+			var slowest time.Duration
+			for _, q := range qs.QueueStats() {
+				if q.Depth != 0 || q.OldestAge != 0 {
+					t.Errorf("%s[%d] after drain: Depth %d, OldestAge %v", q.Kind, q.Index, q.Depth, q.OldestAge)
+				}
+				samples += q.Samples
+				slowest = max(slowest, q.LatencyMax)
+				if q.Samples > 0 && (q.LatencyP50 > q.LatencyP99 || q.LatencyP99 > q.LatencyMax) {
+					t.Errorf("%s[%d]: p50 %v, p99 %v, max %v not ordered", q.Kind, q.Index, q.LatencyP50, q.LatencyP99, q.LatencyMax)
+				}
+			}
+			if samples < total/32 {
+				t.Errorf("latency samples = %d, want >= %d", samples, total/32)
+			}
+			if slowest < stall {
+				t.Errorf("max sampled latency = %v, want >= %v", slowest, stall)
+			}
+		})
+	}
+}
@@ -0,0 +1,300 @@
+package beat
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/uniyakcom/beat/core"
+	implsync "github.com/uniyakcom/beat/internal/impl/sync"
+)
+
+// rateBuilders 配置了发布侧限流规则的各实现
+func rateBuilders(limits ...core.RateLimit) map[string]func() (Bus, error) {
+	var opts []Opt
+	for _, l := range limits {
+		opts = append(opts, WithEmitRateLimit(l.Pattern, l.Rate, l.Burst, l.Policy))
+	}
+	return map[string]func() (Bus, error){
+		"sync": func() (Bus, error) { return ForSync(opts...) },
+		"sync-async": func() (Bus, error) {
+			return implsync.New(&implsync.Config{Async: true, RateLimits: limits})
+		},
+		"async": func() (Bus, error) { return ForAsync(append([]Opt{withWorkers(2)}, opts...)...) },
+		"flow":  func() (Bus, error) { return ForFlow(opts...) },
+	}
+}
+
+// TestEmitRateLimit 匹配 pattern 的事件共享令牌桶，超出后按策略拒绝或丢弃，其他类型不受影响
+func TestEmitRateLimit(t *testing.T) {
+	builders := rateBuilders(
+		core.RateLimit{Pattern: "metric.**", Rate: 0.01, Burst: 3, Policy: RateReject},
+		core.RateLimit{Pattern: "log.*", Rate: 0.01, Burst: 2, Policy: RateDrop},
+	)
+	for name, build := range builders {
+		t.Run(name, func(t *testing.T) {
+			bus, err := build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer bus.Close()
+
+			var metrics, logs, orders atomic.Int64
+			bus.On("metric.cpu", func(e *Event) error { metrics.Add(1); return nil })
+			bus.On("metric.mem", func(e *Event) error { metrics.Add(1); return nil })
+			bus.On("log.info", func(e *Event) error { logs.Add(1); return nil })
+			bus.On("order.created", func(e *Event) error { orders.Add(1); return nil })
+
+			var rejected int
+			for i := 0; i < 4; i++ {
+				for _, typ := range []string{"metric.cpu", "metric.mem"} {
+					if err := bus.Emit(&Event{Type: typ}); errors.Is(err, ErrRateLimited) {
+						rejected++
+					} else if err != nil {
+						t.Fatalf("Emit: %v", err)
+					}
+				}
+				if err := bus.Emit(&Event{Type: "log.info"}); err != nil {
+					t.Errorf("RateDrop Emit err = %v, want nil", err)
+				}
+				_ = bus.Emit(&Event{Type: "order.created"})
+			}
+			// 批量: 被拒绝的事件移除，其余照常发布
+			err = bus.EmitBatch([]*Event{{Type: "order.created"}, {Type: "metric.cpu"}, {Type: "order.created"}})
+			if !errors.Is(err, ErrRateLimited) {
+				t.Errorf("EmitBatch err = %v, want ErrRateLimited", err)
+			}
+			// RateDrop 丢弃的 EmitAwait 事件直接完成
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := bus.(Awaiter).EmitAwait(ctx, &Event{Type: "log.info"}).Wait(ctx); err != nil {
+				t.Errorf("EmitAwait of dropped event: %v", err)
+			}
+			_ = bus.(Awaiter).Barrier(ctx)
+
+			if rejected != 5 || metrics.Load() != 3 {
+				t.Errorf("rejected = %d, metric calls = %d; want 5, 3", rejected, metrics.Load())
+			}
+			if logs.Load() != 2 || orders.Load() != 6 {
+				t.Errorf("log calls = %d, order calls = %d; want 2, 6", logs.Load(), orders.Load())
+			}
+			if n := bus.Stats().RateLimited; n != 9 {
+				t.Errorf("Stats().RateLimited = %d, want 9", n)
+			}
+		})
+	}
+}
+
+// TestEmitRateLimitDedupRetry 被限流拒绝的事件不占用去重记录，同一 ID 重投时正常发布（单条与批量）
+func TestEmitRateLimitDedupRetry(t *testing.T) {
+	const rate = 20 // 每 50ms 一个令牌
+	limit := core.RateLimit{Pattern: "order.*", Rate: rate, Burst: 1, Policy: RateReject}
+	opts := []Opt{WithEmitRateLimit(limit.Pattern, limit.Rate, limit.Burst, limit.Policy), WithDedup(time.Minute, 0)}
+	builders := map[string]func() (Bus, error){
+		"sync": func() (Bus, error) { return ForSync(opts...) },
+		"sync-async": func() (Bus, error) {
+			return implsync.New(&implsync.Config{Async: true, RateLimits: []core.RateLimit{limit}, DedupWindow: time.Minute})
+		},
+		"async": func() (Bus, error) { return ForAsync(append([]Opt{withWorkers(2)}, opts...)...) },
+		"flow":  func() (Bus, error) { return ForFlow(opts...) },
+	}
+	for name, build := range builders {
+		t.Run(name, func(t *testing.T) {
+			bus, err := build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer bus.Close()
+
+			var calls atomic.Int64
+			bus.On("order.created", func(e *Event) error { calls.Add(1); return nil })
+
+			emitRetry := func(emit func(*Event) error, id string) {
+				t.Helper()
+				time.Sleep(2 * time.Second / rate)
+				if err := bus.Emit(&Event{Type: "order.created"}); err != nil { // 取走唯一的令牌
+					t.Fatalf("%s: Emit err = %v", id, err)
+				}
+				if err := emit(&Event{Type: "order.created", ID: id}); !errors.Is(err, ErrRateLimited) {
+					t.Fatalf("%s: first attempt err = %v, want ErrRateLimited", id, err)
+				}
+				time.Sleep(2 * time.Second / rate)
+				if err := emit(&Event{Type: "order.created", ID: id}); err != nil {
+					t.Fatalf("%s: retry err = %v", id, err)
+				}
+			}
+			emitRetry(bus.Emit, "o-1")
+			emitRetry(func(e *Event) error { return bus.EmitBatch([]*Event{e}) }, "o-2")
+			emitRetry(bus.EmitMatch, "o-3")
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = bus.(Awaiter).Barrier(ctx)
+			// 每轮: 耗尽令牌的事件 + 重投成功的事件
+			if n := calls.Load(); n != 6 {
+				t.Errorf("handler calls = %d, want 6", n)
+			}
+			if d := bus.Stats().Duplicates; d != 0 {
+				t.Errorf("Stats().Duplicates = %d, want 0", d)
+			}
+		})
+	}
+}
+
+// TestEmitRateLimitMultiRule 事件命中多条规则时，被后面的规则拒绝不消耗前面规则的令牌
+func TestEmitRateLimitMultiRule(t *testing.T) {
+	bus, _ := ForSync(
+		WithEmitRateLimit("order.created", 0.01, 2, RateReject), // 长期配额
+		WithEmitRateLimit("order.created", 20, 1, RateReject),   // 短时突发（50ms 一个令牌）
+	)
+	defer bus.Close()
+	var calls int
+	bus.On("order.created", func(*Event) error { calls++; return nil })
+
+	for i := 0; i < 3; i++ {
+		_ = bus.Emit(&Event{Type: "order.created"})
+	}
+	// 第 2、3 次被第二条规则拒绝，第一条规则的令牌仍剩 1 个
+	time.Sleep(100 * time.Millisecond)
+	if err := bus.Emit(&Event{Type: "order.created"}); err != nil {
+		t.Errorf("Emit after burst refill err = %v, want nil", err)
+	}
+	if calls != 2 {
+		t.Errorf("handler calls = %d, want 2", calls)
+	}
+}
+
+// TestEmitRateDelay RateDelay 等待令牌后发布；EmitCtx 的 ctx 先结束时放弃并返回 ctx.Err()
+func TestEmitRateDelay(t *testing.T) {
+	bus, _ := ForSync(WithEmitRateLimit("mail.send", 50, 1, RateDelay))
+	defer bus.Close()
+	var sent int
+	bus.On("mail.send", func(e *Event) error {
+		sent++
+		return nil
+	})
+
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := bus.Emit(&Event{Type: "mail.send"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if el := time.Since(start); el < 55*time.Millisecond {
+		t.Errorf("4 emits at 50/s took %v, want >= 60ms", el)
+	}
+	if sent != 4 {
+		t.Errorf("sent = %d, want 4", sent)
+	}
+
+	bus, _ = ForSync(WithEmitRateLimit("mail.send", 1, 1, RateDelay))
+	defer bus.Close()
+	_ = bus.Emit(&Event{Type: "mail.send"})
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := bus.(core.ContextEmitter).EmitCtx(ctx, &Event{Type: "mail.send"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("EmitCtx err = %v, want DeadlineExceeded", err)
+	}
+}
+
+// TestHandlerRateLimitDrop 订阅侧限流只影响本订阅，超出的调用被丢弃并计数
+func TestHandlerRateLimitDrop(t *testing.T) {
+	for name, build := range awaitBuilders() {
+		t.Run(name, func(t *testing.T) {
+			bus, err := build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer bus.Close()
+
+			var limited, full atomic.Int64
+			bus.(core.OptionSubscriber).OnWith("tick", func(e *Event) error {
+				limited.Add(1)
+				return nil
+			}, WithRateLimit(0.01, 2, SubRateDrop))
+			bus.On("tick", func(e *Event) error {
+				full.Add(1)
+				return nil
+			})
+
+			for i := 0; i < 10; i++ {
+				_ = bus.Emit(&Event{Type: "tick"})
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = bus.(Awaiter).Barrier(ctx)
+
+			if limited.Load() != 2 || full.Load() != 10 {
+				t.Errorf("limited = %d, full = %d; want 2, 10", limited.Load(), full.Load())
+			}
+			if n := bus.Stats().HandlerRateLimited; n != 8 {
+				t.Errorf("Stats().HandlerRateLimited = %d, want 8", n)
+			}
+		})
+	}
+}
+
+// TestHandlerRateLimitCoalesce 合并模式: 限流期间只保留最新事件，令牌到达后投递
+func TestHandlerRateLimitCoalesce(t *testing.T) {
+	for name, build := range awaitBuilders() {
+		t.Run(name, func(t *testing.T) {
+			bus, err := build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer bus.Close()
+
+			got := make(chan string, 16)
+			bus.(core.OptionSubscriber).OnWith("price.updated", func(e *Event) error {
+				got <- e.ID
+				return nil
+			}, WithRateLimit(20, 1, SubRateCoalesce))
+
+			ids := []string{"1", "2", "3", "4", "5"}
+			for _, id := range ids {
+				_ = bus.Emit(&Event{Type: "price.updated", ID: id})
+			}
+
+			for _, want := range []string{"1", "5"} {
+				select {
+				case id := <-got:
+					if id != want {
+						t.Errorf("delivered %q, want %q", id, want)
+					}
+				case <-time.After(5 * time.Second):
+					t.Fatalf("coalesced event %q not delivered", want)
+				}
+			}
+			select {
+			case id := <-got:
+				t.Errorf("unexpected delivery %q", id)
+			case <-time.After(100 * time.Millisecond):
+			}
+			if n := bus.Stats().HandlerRateLimited; n != 4 {
+				t.Errorf("Stats().HandlerRateLimited = %d, want 4", n)
+			}
+
+			// Off 后被保留的事件不再投递
+			id := bus.(core.OptionSubscriber).OnWith("price.closed", func(e *Event) error {
+				got <- e.ID
+				return nil
+			}, WithRateLimit(20, 1, SubRateCoalesce))
+			_ = bus.Emit(&Event{Type: "price.closed", ID: "6"})
+			_ = bus.Emit(&Event{Type: "price.closed", ID: "7"})
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = bus.(Awaiter).Barrier(ctx)
+			bus.Off(id)
+			if id := <-got; id != "6" {
+				t.Errorf("delivered %q, want 6", id)
+			}
+			select {
+			case id := <-got:
+				t.Errorf("coalesced event %q delivered after Off", id)
+			case <-time.After(100 * time.Millisecond):
+			}
+		})
+	}
+}
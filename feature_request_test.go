@@ -0,0 +1,119 @@
+package beat
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+)
+
+// TestRequestReplyAllImpls 三种实现（含 Sync 异步模式）返回第一个回复，ID 作为关联
+func TestRequestReplyAllImpls(t *testing.T) {
+	for name, build := range awaitBuilders() {
+		t.Run(name, func(t *testing.T) {
+			bus, err := build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer bus.Close()
+
+			bus.On("auth.check", func(e *Event) error {
+				return Reply(e, &Event{Data: append([]byte("ok:"), e.Data...)})
+			})
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			req := &Event{Type: "auth.check", Data: []byte("alice")}
+			resp, err := bus.(Requester).Request(ctx, req)
+			if err != nil {
+				t.Fatalf("Request err = %v", err)
+			}
+			if string(resp.Data) != "ok:alice" {
+				t.Errorf("reply data = %q", resp.Data)
+			}
+			if resp.ID == "" || req.ID != "" || req.ReplyTo() != nil || req.Completion() != nil {
+				t.Errorf("reply ID = %q; caller's event modified: ID %q, ReplyTo %v", resp.ID, req.ID, req.ReplyTo())
+			}
+
+			// 显式 ID 作为关联 ID；同一事件可复用
+			req.ID = "r-1"
+			for i := 0; i < 2; i++ {
+				resp, err := bus.(Requester).Request(ctx, req)
+				if err != nil {
+					t.Fatalf("Request with ID err = %v", err)
+				}
+				if resp.ID != "r-1" {
+					t.Errorf("reply ID = %q, want r-1", resp.ID)
+				}
+			}
+
+			// 无人订阅
+			if _, err := bus.(Requester).Request(ctx, &Event{Type: "nobody.home"}); !errors.Is(err, ErrNoResponders) {
+				t.Errorf("Request without subscribers err = %v, want ErrNoResponders", err)
+			}
+		})
+	}
+}
+
+// TestRequestAllGathersReplies RequestAll 收集每个 handler 的回复，并返回未回复 handler 的 error
+func TestRequestAllGathersReplies(t *testing.T) {
+	for name, build := range awaitBuilders() {
+		t.Run(name, func(t *testing.T) {
+			bus, err := build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer bus.Close()
+
+			for _, region := range []string{"eu", "us", "ap"} {
+				region := region
+				bus.On("quote", func(e *Event) error {
+					return Reply(e, &Event{Data: []byte(region)})
+				})
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			replies, err := bus.(Requester).RequestAll(ctx, &Event{Type: "quote"})
+			if err != nil {
+				t.Fatalf("RequestAll err = %v", err)
+			}
+			got := make([]string, len(replies))
+			for i, r := range replies {
+				got[i] = string(r.Data)
+			}
+			sort.Strings(got)
+			if len(got) != 3 || got[0] != "ap" || got[1] != "eu" || got[2] != "us" {
+				t.Errorf("replies = %v, want [ap eu us]", got)
+			}
+		})
+	}
+}
+
+// TestRequestTimeoutAndSilentHandler handler 不回复时: 执行完毕返回 ErrNoResponders，未执行完毕随 ctx 超时
+func TestRequestTimeoutAndSilentHandler(t *testing.T) {
+	bus, _ := ForAsync()
+	defer bus.Close()
+
+	bus.On("silent", func(e *Event) error { return errDeclined })
+	g := newGatedCounter()
+	defer g.release()
+	bus.On("stuck", g.handler)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_, err := bus.(Requester).Request(ctx, &Event{Type: "silent"})
+	if !errors.Is(err, ErrNoResponders) || !errors.Is(err, errDeclined) {
+		t.Errorf("silent handler err = %v, want ErrNoResponders and handler error", err)
+	}
+
+	short, cancelShort := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancelShort()
+	if _, err := bus.(Requester).Request(short, &Event{Type: "stuck"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("stuck handler err = %v, want DeadlineExceeded", err)
+	}
+
+	if err := Reply(&Event{Type: "plain"}, &Event{}); !errors.Is(err, ErrNotRequest) {
+		t.Errorf("Reply to plain event err = %v, want ErrNotRequest", err)
+	}
+}
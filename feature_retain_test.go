@@ -0,0 +1,114 @@
+package beat
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	implsync "github.com/uniyakcom/beat/internal/impl/sync"
+)
+
+// TestRetainLateSubscriber 晚注册的精确/通配符订阅立即收到保留事件，读取与清除
+func TestRetainLateSubscriber(t *testing.T) {
+	for name, build := range awaitBuilders() {
+		t.Run(name, func(t *testing.T) {
+			bus, err := build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer bus.Close()
+			rt := bus.(Retainer)
+
+			data := []byte("v1")
+			if err := rt.EmitRetain(&Event{Type: "config.updated", Data: data}); err != nil {
+				t.Fatal(err)
+			}
+			_ = rt.EmitRetain(&Event{Type: "config.updated", Data: []byte("v2")})
+			_ = rt.EmitRetain(&Event{Type: "leader.elected", Data: []byte("node-3")})
+			data[0] = 'X' // 保留的是副本
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = bus.(Awaiter).Barrier(ctx) // 实时事件处理完毕后再订阅，只观察保留事件的投递
+
+			var mu sync.Mutex
+			got := map[string]string{}
+			record := func(e *Event) error {
+				mu.Lock()
+				got[e.Type] = string(e.Data)
+				mu.Unlock()
+				return nil
+			}
+			bus.On("config.updated", record)
+			mu.Lock()
+			if got["config.updated"] != "v2" {
+				t.Errorf("exact subscriber got %q, want latest v2", got["config.updated"])
+			}
+			got = map[string]string{}
+			mu.Unlock()
+
+			bus.On("**", record) // 在 On 返回前收到
+			mu.Lock()
+			if len(got) != 2 || got["leader.elected"] != "node-3" {
+				t.Errorf("wildcard subscriber got %v, want both retained events", got)
+			}
+			mu.Unlock()
+
+			// 订阅组成员不接收保留事件
+			bus.(GroupSubscriber).OnGroup("config.*", "workers", func(e *Event) error {
+				t.Errorf("group member received retained %s", e.Type)
+				return nil
+			})
+
+			if rs := rt.Retained("config.*"); len(rs) != 1 || string(rs[0].Data) != "v2" {
+				t.Errorf("Retained(config.*) = %v", rs)
+			}
+			st := bus.Stats()
+			if st.Retained != 2 || st.RetainedBytes != int64(len("v2")+len("node-3")) {
+				t.Errorf("Stats retained = %d / %d bytes", st.Retained, st.RetainedBytes)
+			}
+			if n := rt.ClearRetained("**"); n != 2 {
+				t.Errorf("ClearRetained = %d, want 2", n)
+			}
+			if st := bus.Stats(); st.Retained != 0 || st.RetainedBytes != 0 {
+				t.Errorf("Stats after clear = %d / %d", st.Retained, st.RetainedBytes)
+			}
+		})
+	}
+}
+
+// TestRetainPatternOption WithRetain 按 pattern 自动保留普通 Emit 的事件
+func TestRetainPatternOption(t *testing.T) {
+	builders := map[string]func() (Bus, error){
+		"sync":       func() (Bus, error) { return ForSync(WithRetain("config.*")) },
+		"sync-async": func() (Bus, error) { return implsync.New(&implsync.Config{Async: true, Retain: []string{"config.*"}}) },
+		"async":      func() (Bus, error) { return ForAsync(withWorkers(2), WithRetain("config.*")) },
+		"flow":       func() (Bus, error) { return ForFlow(WithRetain("config.*")) },
+	}
+	for name, build := range builders {
+		t.Run(name, func(t *testing.T) {
+			bus, err := build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer bus.Close()
+
+			_ = bus.Emit(&Event{Type: "config.updated", Data: []byte("a")})
+			_ = bus.EmitBatch([]*Event{{Type: "config.flags", Data: []byte("b")}, {Type: "order.created"}})
+			_ = bus.EmitMatch(&Event{Type: "config.updated", Data: []byte("c")})
+			_ = bus.(Awaiter).Barrier(context.Background())
+
+			var got []string
+			bus.On("config.*", func(e *Event) error {
+				got = append(got, e.Type+"="+string(e.Data))
+				return nil
+			})
+			if len(got) != 2 || got[0] != "config.flags=b" || got[1] != "config.updated=c" {
+				t.Errorf("retained replay = %v", got)
+			}
+			if rs := bus.(Retainer).Retained("order.created"); len(rs) != 0 {
+				t.Errorf("non-matching type retained: %v", rs)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package beat
+
+import (
+	"bytes"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestFlowWithStages 测试 ForFlow(WithStages) 自定义 Pipeline
+func TestFlowWithStages(t *testing.T) {
+	upper := func(batch []*Event) ([]*Event, error) {
+		for _, e := range batch {
+			e.Data = bytes.ToUpper(e.Data)
+		}
+		return batch, nil
+	}
+	dropEmpty := func(batch []*Event) ([]*Event, error) {
+		out := batch[:0]
+		for _, e := range batch {
+			if len(e.Data) > 0 {
+				out = append(out, e)
+			}
+		}
+		return out, nil
+	}
+
+	bus, err := ForFlow(WithStages(dropEmpty, upper))
+	if err != nil {
+		t.Fatalf("ForFlow failed: %v", err)
+	}
+	defer bus.Close()
+
+	var received, lower int32
+	bus.On("etl.row", func(e *Event) error {
+		atomic.AddInt32(&received, 1)
+		if !bytes.Equal(e.Data, bytes.ToUpper(e.Data)) {
+			atomic.AddInt32(&lower, 1)
+		}
+		return nil
+	})
+
+	for i := 0; i < 10; i++ {
+		data := []byte("row")
+		if i%2 == 1 {
+			data = nil
+		}
+		bus.Emit(&Event{Type: "etl.row", Data: data})
+	}
+	time.Sleep(300 * time.Millisecond)
+
+	if got := atomic.LoadInt32(&received); got != 5 {
+		t.Errorf("expected 5 events after dropEmpty, got %d", got)
+	}
+	if got := atomic.LoadInt32(&lower); got != 0 {
+		t.Errorf("expected all events transformed, %d were not", got)
+	}
+}
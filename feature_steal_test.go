@@ -0,0 +1,99 @@
+package beat
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestWorkStealingSlowHandler 属主 worker 被慢 handler 占住时，其 ring 的积压由空闲 worker 窃取处理
+func TestWorkStealingSlowHandler(t *testing.T) {
+	bus, err := ForAsync(withWorkers(2), WithWorkStealing())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bus.Close()
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	bus.On("job.slow", func(*Event) error {
+		close(started)
+		<-release
+		return nil
+	})
+	var fast atomic.Int64
+	bus.On("job.fast", func(*Event) error {
+		fast.Add(1)
+		return nil
+	})
+
+	_ = bus.Emit(&Event{Type: "job.slow"})
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("slow handler did not start")
+	}
+	for i := 0; i < 100; i++ {
+		_ = bus.Emit(&Event{Type: "job.fast"})
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for fast.Load() < 100 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if n := fast.Load(); n != 100 {
+		t.Errorf("processed %d fast events while owner was blocked, want 100", n)
+	}
+	close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := bus.(Awaiter).Barrier(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := bus.Stats().Processed; n != 101 {
+		t.Errorf("Stats().Processed = %d, want 101", n)
+	}
+}
+
+// TestWorkStealingOrdered 与 WithOrdered 同用时 keyed 事件不参与窃取，每个 key 仍按发布顺序处理
+func TestWorkStealingOrdered(t *testing.T) {
+	bus, err := ForAsync(WithOrdered(), WithWorkStealing(), withWorkers(4))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bus.Close()
+
+	c := newOrderChecker()
+	bus.On("order.updated", c.handler)
+	var plain atomic.Int64
+	bus.On("order.logged", func(*Event) error {
+		plain.Add(1)
+		return nil
+	})
+
+	const keys, eventsPerKey = 16, 500
+	for seq := 0; seq < eventsPerKey; seq++ {
+		for k := 0; k < keys; k++ {
+			_ = bus.Emit(&Event{Type: "order.updated", Key: fmt.Sprintf("order-%d", k), Data: seqData(seq)})
+			_ = bus.Emit(&Event{Type: "order.logged"})
+		}
+	}
+
+	c.wait(t, keys*eventsPerKey)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := bus.(Awaiter).Barrier(ctx); err != nil {
+		t.Fatal(err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.disorder != 0 {
+		t.Errorf("%d events processed out of per-key order", c.disorder)
+	}
+	if n := plain.Load(); n != keys*eventsPerKey {
+		t.Errorf("processed %d unkeyed events, want %d", n, keys*eventsPerKey)
+	}
+}
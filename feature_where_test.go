@@ -0,0 +1,152 @@
+package beat
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/uniyakcom/beat/core"
+	"github.com/uniyakcom/beat/where"
+)
+
+// TestWhereFilter 谓词为 false 的事件不交给 handler，计入 Stats().Filtered；其他订阅不受影响
+func TestWhereFilter(t *testing.T) {
+	for name, build := range awaitBuilders() {
+		t.Run(name, func(t *testing.T) {
+			bus, err := build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer bus.Close()
+
+			var big, all collector
+			bus.(core.OptionSubscriber).OnWith("order.created", big.handler,
+				WithWhere(where.MustCompile(`amount > 100 && region == "eu"`)))
+			bus.On("order.created", all.handler)
+
+			for id, data := range map[string]string{
+				"a": `{"amount":150,"region":"eu"}`,
+				"b": `{"amount":50,"region":"eu"}`,
+				"c": `{"amount":500,"region":"us"}`,
+				"d": `{"amount":101.5,"region":"eu","items":[1,2]}`,
+				"e": `not json`,
+				"f": ``,
+			} {
+				_ = bus.Emit(&Event{Type: "order.created", ID: id, Data: []byte(data)})
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			_ = bus.(Awaiter).Barrier(ctx)
+
+			ids := big.get()
+			sort.Strings(ids)
+			if len(ids) != 2 || ids[0] != "a" || ids[1] != "d" {
+				t.Errorf("filtered subscription got %v, want [a d]", ids)
+			}
+			if n := len(all.get()); n != 6 {
+				t.Errorf("plain subscription got %d events, want 6", n)
+			}
+			if n := bus.Stats().Filtered; n != 4 {
+				t.Errorf("Stats().Filtered = %d, want 4", n)
+			}
+		})
+	}
+}
+
+// TestWhereShared 同一 pattern 的多个谓词订阅共享查找；复用 Event 对象原地改写 Data 后重新求值
+func TestWhereShared(t *testing.T) {
+	bus, _ := ForSync()
+	defer bus.Close()
+	os := bus.(core.OptionSubscriber)
+
+	var eu, vip, either, grp collector
+	os.OnWith("user.login", eu.handler, WithWhere(where.MustCompile(`region == "eu"`)))
+	os.OnWith("user.login", vip.handler, WithWhere(where.MustCompile(`user.vip`)))
+	os.OnWith("user.login", either.handler, WithWhere(where.MustCompile(`region == "eu" || user.vip`)))
+	for i := 0; i < 2; i++ {
+		os.OnWith("user.login", grp.handler, WithGroup("audit"), WithWhere(where.MustCompile(`!user.vip`)))
+	}
+
+	evt := &Event{Type: "user.login", Data: make([]byte, 0, 64)}
+	for i, data := range []string{
+		`{"region":"eu","user":{"vip":false}}`,
+		`{"region":"us","user":{"vip":true }}`, // 与上一条等长，原地改写
+		`{"region":"cn","user":{"vip":false}}`,
+	} {
+		evt.ID = string(rune('1' + i))
+		evt.Data = append(evt.Data[:0], data...)
+		_ = bus.Emit(evt)
+	}
+
+	for name, c := range map[string]struct {
+		got  []string
+		want string
+	}{
+		"eu":     {eu.get(), "1"},
+		"vip":    {vip.get(), "2"},
+		"either": {either.get(), "12"},
+		"group":  {grp.get(), "13"},
+	} {
+		got := ""
+		for _, id := range c.got {
+			got += id
+		}
+		if got != c.want {
+			t.Errorf("%s got %q, want %q", name, got, c.want)
+		}
+	}
+	if n := bus.Stats().Filtered; n != 6 {
+		t.Errorf("Stats().Filtered = %d, want 6", n)
+	}
+}
+
+// TestWhereCompile 语法与比较语义
+func TestWhereCompile(t *testing.T) {
+	data := []byte(`{"amount":150,"region":"eu","tags":["a","b"],"user":{"vip":true},"note":null}`)
+	for expr, want := range map[string]bool{
+		`amount >= 150 && amount < 151`:                          true,
+		`(amount > 200 || region == 'eu') && !user.vip == false`: true,
+		`tags.1 == "b"`:         true,
+		`region > "de"`:         true,
+		`amount == "150"`:       false,
+		`amount != "150"`:       true,
+		`missing == null`:       true,
+		`note == null && !note`: true,
+		`region`:                true,
+		`!missing`:              true,
+		`-1 < amount`:           true,
+		`user.vip == true`:      true,
+	} {
+		if got := where.MustCompile(expr).Match(data); got != want {
+			t.Errorf("%s = %v, want %v", expr, got, want)
+		}
+	}
+
+	for expr, offset := range map[string]int{
+		``:            0,
+		`amount >`:    8,
+		`(amount > 1`: 11,
+		`a & b`:       2,
+		`a == "x`:     5,
+		`a == 1e`:     5,
+		`a..b == 1`:   0,
+		`a == 1 b`:    7,
+	} {
+		_, err := where.Compile(expr)
+		var werr *where.Error
+		if !errors.As(err, &werr) {
+			t.Errorf("Compile(%q) err = %v, want *where.Error", expr, err)
+			continue
+		}
+		if werr.Offset != offset {
+			t.Errorf("Compile(%q) offset = %d, want %d (%v)", expr, werr.Offset, offset, err)
+		}
+	}
+
+	x := where.MustCompile(`amount > 100 && region == "eu"`)
+	if n := testing.AllocsPerRun(100, func() { x.Match(data) }); n != 0 {
+		t.Errorf("Match allocs = %v, want 0", n)
+	}
+}
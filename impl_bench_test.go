@@ -1,9 +1,15 @@
 package beat
 
 import (
+	"context"
+	"fmt"
 	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 
+	"github.com/uniyakcom/beat/core"
 	implasync "github.com/uniyakcom/beat/internal/impl/async"
 	"github.com/uniyakcom/beat/internal/impl/flow"
 	implsync "github.com/uniyakcom/beat/internal/impl/sync"
@@ -323,3 +329,373 @@ func BenchmarkImplAsyncHighConcurrency(b *testing.B) {
 	throughput := float64(b.N) / b.Elapsed().Seconds()
 	b.ReportMetric(throughput/1e6, "M/s")
 }
+
+// BenchmarkAsyncSkewed 倾斜生产者：单个 goroutine 发布（事件集中在一个 P 的 ring），handler 有少量计算
+// Static 为默认静态亲和（只有属主 worker 消费该 ring），Stealing 为 WithWorkStealing（空闲 worker 分担）。
+// 计时包含 Barrier 等待全部处理完成；多核机器上两者差距随 worker 数扩大。
+func BenchmarkAsyncSkewed(b *testing.B) {
+	for _, steal := range []bool{false, true} {
+		name := "Static"
+		if steal {
+			name = "Stealing"
+		}
+		b.Run(name, func(b *testing.B) {
+			bus := implasync.New(&implasync.Config{
+				Workers:      4,
+				RingSize:     1 << 13,
+				WorkStealing: steal,
+			})
+			defer bus.Close()
+
+			var sink atomic.Uint64
+			bus.On("bench", func(e *Event) error {
+				x := uint64(len(e.Data))
+				for i := 0; i < 200; i++ {
+					x = x*6364136223846793005 + 1442695040888963407
+				}
+				sink.Add(x & 1)
+				return nil
+			})
+			evt := &Event{Type: "bench", Data: []byte("data")}
+
+			b.ResetTimer()
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = bus.Emit(evt)
+			}
+			_ = bus.Barrier(context.Background())
+			throughput := float64(b.N) / b.Elapsed().Seconds()
+			b.ReportMetric(throughput/1e6, "M/s")
+		})
+	}
+}
+
+// ═══════════════════════════════════════════════════════════════════
+// TrieMatcher 专项基准（通配符密集 + 订阅变动）
+// ═══════════════════════════════════════════════════════════════════
+
+// churnMatcher 变动基准比较的匹配器（*core.TrieMatcher 与 rwTrieMatcher）
+type churnMatcher interface {
+	Add(pattern string) error
+	Remove(pattern string)
+	Match(eventType string) *[]string
+	Put(sp *[]string)
+}
+
+// churnImpls 变动基准的两种实现: 当前无锁快照 Trie 与改造前的 RWMutex Trie（对比基线）
+var churnImpls = []struct {
+	name string
+	new  func() churnMatcher
+}{
+	{"Snapshot", func() churnMatcher { return core.NewTrieMatcherSize(core.DotSyntax, -1) }},
+	{"RWMutex", func() churnMatcher { return newRWTrieMatcher(core.DotSyntax) }},
+}
+
+// fillChurnMatcher 预置 4096 个通配符 pattern（结果缓存已关闭，每次 Match 都遍历 Trie）
+func fillChurnMatcher(m churnMatcher) churnMatcher {
+	for i := 0; i < 4096; i++ {
+		_ = m.Add(fmt.Sprintf("svc%d.*.e%d", i%64, i))
+		if i%64 == 0 {
+			_ = m.Add(fmt.Sprintf("svc%d.**", i/64))
+		}
+	}
+	return m
+}
+
+// BenchmarkMatcherChurn 并行 Match，每 churn 次操作穿插一次 Add + Remove（模拟 On/Off 变动）
+// RWMutex 子基准为改造前的实现（读锁 + 原地修改），与 Snapshot 对比。
+func BenchmarkMatcherChurn(b *testing.B) {
+	for _, impl := range churnImpls {
+		for _, churn := range []int{0, 1000, 100, 10} {
+			name := "ReadOnly"
+			if churn > 0 {
+				name = fmt.Sprintf("1in%d", churn)
+			}
+			newMatcher := impl.new
+			b.Run(impl.name+"/"+name, func(b *testing.B) {
+				m := fillChurnMatcher(newMatcher())
+				var gid atomic.Int64
+
+				b.ResetTimer()
+				b.ReportAllocs()
+				b.RunParallel(func(pb *testing.PB) {
+					g := gid.Add(1)
+					tmp := fmt.Sprintf("svc%d.*.tmp%d", g%64, g)
+					i := 0
+					for pb.Next() {
+						i++
+						if churn > 0 && i%churn == 0 {
+							_ = m.Add(tmp)
+							m.Remove(tmp)
+							continue
+						}
+						sp := m.Match(churnTypes[i&(len(churnTypes)-1)])
+						m.Put(sp)
+					}
+				})
+				throughput := float64(b.N) / b.Elapsed().Seconds()
+				b.ReportMetric(throughput/1e6, "M/s")
+			})
+		}
+	}
+}
+
+// BenchmarkMatcherChurnWriter 后台 goroutine 持续 Add / Remove 时的并行 Match 吞吐（Snapshot 与 RWMutex 对比）
+func BenchmarkMatcherChurnWriter(b *testing.B) {
+	for _, impl := range churnImpls {
+		newMatcher := impl.new
+		b.Run(impl.name, func(b *testing.B) {
+			m := fillChurnMatcher(newMatcher())
+			stop := make(chan struct{})
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				for i := 0; ; i++ {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+					p := fmt.Sprintf("svc%d.*.w%d", i%64, i%256)
+					_ = m.Add(p)
+					m.Remove(p)
+					runtime.Gosched()
+				}
+			}()
+
+			b.ResetTimer()
+			b.ReportAllocs()
+			b.RunParallel(func(pb *testing.PB) {
+				i := 0
+				for pb.Next() {
+					i++
+					sp := m.Match(churnTypes[i&(len(churnTypes)-1)])
+					m.Put(sp)
+				}
+			})
+			b.StopTimer()
+			close(stop)
+			<-done
+			throughput := float64(b.N) / b.Elapsed().Seconds()
+			b.ReportMetric(throughput/1e6, "M/s")
+		})
+	}
+}
+
+// churnTypes 基准使用的事件类型（命中 svc*.*.e* 与 svc*.** 两类 pattern）
+var churnTypes = func() []string {
+	types := make([]string, 256)
+	for i := range types {
+		types[i] = fmt.Sprintf("svc%d.x.e%d", i%64, i*16)
+	}
+	return types
+}()
+
+// rwTrieMatcher 改为无锁快照之前的 TrieMatcher（仅供变动基准对比）
+// 读者持 RWMutex 读锁遍历，Add / Remove 持写锁原地修改节点。
+// 只保留基准用到的部分：结果缓存与精确快速路径已省略（基准中缓存关闭、pattern 均含通配符）；
+// 段内通配 / 备选段经 PatternSyntax.Compile 编译成单段 Pattern 匹配。
+type rwTrieMatcher struct {
+	root   *rwNode
+	syntax core.PatternSyntax
+
+	mu   sync.RWMutex
+	pool sync.Pool
+}
+
+type rwNode struct {
+	children map[string]*rwNode // 字面段
+	one      *rwNode            // 单段通配
+	multi    *rwNode            // 多段通配
+	globs    []*rwNode          // 段内通配 / 备选（按原文区分）
+	seg      string             // globs 中的节点: 段原文
+	glob     *core.Pattern      // globs 中的节点: 编译后的单段 pattern
+	pattern  string
+	refCount int32
+	isEnd    bool
+}
+
+func newRWTrieMatcher(syntax core.PatternSyntax) *rwTrieMatcher {
+	return &rwTrieMatcher{
+		root:   &rwNode{children: make(map[string]*rwNode, 4)},
+		syntax: syntax,
+		pool: sync.Pool{
+			New: func() interface{} { s := make([]string, 0, 16); return &s },
+		},
+	}
+}
+
+// child 返回段 seg 对应的子节点；create 为 true 时不存在则创建
+func (t *rwTrieMatcher) child(n *rwNode, seg string, create bool) *rwNode {
+	var c *rwNode
+	switch {
+	case seg == t.syntax.One:
+		if n.one == nil && create {
+			n.one = &rwNode{children: make(map[string]*rwNode, 4)}
+		}
+		c = n.one
+	case seg == t.syntax.Multi:
+		if n.multi == nil && create {
+			n.multi = &rwNode{children: make(map[string]*rwNode, 4)}
+		}
+		c = n.multi
+	case t.syntax.HasWildcard(seg):
+		for _, g := range n.globs {
+			if g.seg == seg {
+				return g
+			}
+		}
+		if create {
+			p, _ := t.syntax.Compile(seg)
+			c = &rwNode{children: make(map[string]*rwNode, 4), seg: seg, glob: p}
+			n.globs = append(n.globs, c)
+		}
+	default:
+		c = n.children[seg]
+		if c == nil && create {
+			c = &rwNode{children: make(map[string]*rwNode, 4)}
+			n.children[seg] = c
+		}
+	}
+	return c
+}
+
+// unlink 删除段 seg 对应的子节点
+func (t *rwTrieMatcher) unlink(n *rwNode, seg string) {
+	switch {
+	case seg == t.syntax.One:
+		n.one = nil
+	case seg == t.syntax.Multi:
+		n.multi = nil
+	case t.syntax.HasWildcard(seg):
+		for i, g := range n.globs {
+			if g.seg == seg {
+				n.globs = append(n.globs[:i], n.globs[i+1:]...)
+				return
+			}
+		}
+	default:
+		delete(n.children, seg)
+	}
+}
+
+func (n *rwNode) empty() bool {
+	return !n.isEnd && n.refCount == 0 && len(n.children) == 0 &&
+		n.one == nil && n.multi == nil && len(n.globs) == 0
+}
+
+func (t *rwTrieMatcher) Add(pattern string) error {
+	if _, err := t.syntax.Compile(pattern); err != nil {
+		return err
+	}
+	segs := strings.Split(pattern, string(t.syntax.Sep))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	n := t.root
+	for _, seg := range segs {
+		n = t.child(n, seg, true)
+	}
+	n.isEnd = true
+	n.pattern = pattern
+	n.refCount++
+	return nil
+}
+
+func (t *rwTrieMatcher) Remove(pattern string) {
+	if _, err := t.syntax.Compile(pattern); err != nil {
+		return
+	}
+	segs := strings.Split(pattern, string(t.syntax.Sep))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	path := make([]*rwNode, 1, len(segs)+1)
+	path[0] = t.root
+	n := t.root
+	for _, seg := range segs {
+		if n = t.child(n, seg, false); n == nil {
+			return
+		}
+		path = append(path, n)
+	}
+	if n.refCount > 0 {
+		n.refCount--
+	}
+	if n.refCount == 0 {
+		n.isEnd = false
+		n.pattern = ""
+	}
+	for i := len(segs) - 1; i >= 0; i-- {
+		if !path[i+1].empty() {
+			break
+		}
+		t.unlink(path[i], segs[i])
+	}
+}
+
+func (t *rwTrieMatcher) Match(eventType string) *[]string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	sp := t.pool.Get().(*[]string)
+	*sp = (*sp)[:0]
+	partsSp := t.pool.Get().(*[]string)
+	*partsSp = (*partsSp)[:0]
+	start := 0
+	for i := 0; i < len(eventType); i++ {
+		if eventType[i] == t.syntax.Sep {
+			*partsSp = append(*partsSp, eventType[start:i])
+			start = i + 1
+		}
+	}
+	*partsSp = append(*partsSp, eventType[start:])
+	t.matchRecursive(t.root, *partsSp, 0, sp)
+	t.Put(partsSp)
+	return sp
+}
+
+func (t *rwTrieMatcher) matchRecursive(n *rwNode, parts []string, depth int, results *[]string) {
+	if n.multi != nil {
+		for d := depth; d <= len(parts); d++ {
+			t.matchRecursive(n.multi, parts, d, results)
+		}
+	}
+	if depth == len(parts) {
+		if n.isEnd {
+			for _, p := range *results {
+				if p == n.pattern {
+					return
+				}
+			}
+			*results = append(*results, n.pattern)
+		}
+		return
+	}
+	part := parts[depth]
+	if child, ok := n.children[part]; ok {
+		t.matchRecursive(child, parts, depth+1, results)
+	}
+	if n.one != nil {
+		t.matchRecursive(n.one, parts, depth+1, results)
+	}
+	for _, g := range n.globs {
+		if g.glob.Match(part) {
+			t.matchRecursive(g, parts, depth+1, results)
+		}
+	}
+}
+
+func (t *rwTrieMatcher) Put(sp *[]string) {
+	if sp == nil {
+		return
+	}
+	all := (*sp)[:cap(*sp)]
+	for i := range all {
+		all[i] = ""
+	}
+	*sp = all[:0]
+	t.pool.Put(sp)
+}
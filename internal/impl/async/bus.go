@@ -3,7 +3,9 @@
 // 设计特点:
 //   - 零 CAS 热路径: SPSC ring 仅用 atomic Load/Store（x86 = 普通 MOV）
 //   - Per-P 分发: procPin 保证同一 P 上 goroutine 串行化 → 真 SPSC 单写者
-//   - Worker 亲和性: worker[i] 静态拥有 rings {i, i+w, ...}，无 work-stealing
+//   - Worker 亲和性: worker[i] 静态拥有 rings {i, i+w, ...}，默认只消费自己的 ring
+//   - 工作窃取（Config.WorkStealing，可选）: 每个 ring 的消费端由租约保护，空闲 worker 抢到租约后
+//     从积压最多的 Per-P ring 按批取走处理（同一 ring 内不再保序；keyed ring 与共享 ring 不参与窃取）
 //   - RCU 订阅管理: atomic.Pointer 读无锁，写时 CoW
 //   - 扁平化 handler: dispatch 直接遍历 []core.Handler（消除 *sub 间接访问）
 //   - 单类型快速路径: 仅 1 种事件类型时跳过 map lookup（节省 ~16ns）
@@ -16,6 +18,7 @@
 package async
 
 import (
+	"context"
 	"fmt"
 	"runtime"
 	"sync"
@@ -23,7 +26,15 @@ import (
 	"time"
 
 	"github.com/uniyakcom/beat/core"
+	"github.com/uniyakcom/beat/internal/support/dedup"
+	"github.com/uniyakcom/beat/internal/support/group"
+	"github.com/uniyakcom/beat/internal/support/latency"
+	"github.com/uniyakcom/beat/internal/support/opchain"
+	"github.com/uniyakcom/beat/internal/support/predicate"
+	"github.com/uniyakcom/beat/internal/support/ratelimit"
+	"github.com/uniyakcom/beat/internal/support/retain"
 	"github.com/uniyakcom/beat/internal/support/sched"
+	"github.com/uniyakcom/beat/internal/support/wheel"
 	"github.com/uniyakcom/beat/util"
 )
 
@@ -32,30 +43,69 @@ type sub struct {
 	id      uint64
 	pattern string
 	handler core.Handler
+	onError core.ErrorHandler // 订阅级错误回调（可为 nil）
+
+	// 订阅组（OnGroup）：成员记录组名与策略；组槽位额外持有 grp（id=0，仅出现在 slots 中）
+	group   string
+	balance core.GroupBalance
+	grp     *group.Group[*sub]
+
+	ops  *opchain.Chain     // 定时投递环境（算子、限流合并投递；Off 时 Stop）
+	pred *predicate.Binding // 负载谓词（nil=未挂载）
+}
+
+// info 返回上报给回调的订阅信息
+func (s *sub) info() core.SubInfo {
+	return core.SubInfo{ID: s.id, Pattern: s.pattern, Group: s.group}
+}
+
+// pick 返回本次调用实际执行的订阅与 handler（组槽位按均衡策略选出一个成员）
+func (s *sub) pick(evt *core.Event, h core.Handler) (*sub, core.Handler) {
+	if s.grp == nil {
+		return s, h
+	}
+	m := s.grp.Pick(evt.Key)
+	return m, m.handler
 }
 
 // subsSnapshot RCU 快照 — 双层结构
 //   - byID: On/Off 管理路径（含 sub.ID 用于删除）
+//   - slots: 分发槽位（订阅组成员折叠为一个组槽位，无组时与 byID 共享切片）
 //   - handlers: dispatch 热路径（预扁平化 []core.Handler，消除 *sub 间接访问）
 //   - singleKey/singleHandlers: 单事件类型快速路径（跳过 map hash+lookup ≈ 16ns）
+//
+// slots[k][i] 与 handlers[k][i] 一一对应，panic 上报时据此定位订阅。
 type subsSnapshot struct {
 	byID           map[string][]*sub
+	slots          map[string][]*sub
 	handlers       map[string][]core.Handler
 	singleKey      string
 	singleHandlers []core.Handler
+	singleSubs     []*sub
+}
+
+// lookup 返回 key 对应的 handler 列表及其订阅（单类型快速路径跳过 map lookup）
+func (s *subsSnapshot) lookup(key string) ([]core.Handler, []*sub) {
+	if s.singleKey == key {
+		return s.singleHandlers, s.singleSubs
+	}
+	return s.handlers[key], s.slots[key]
 }
 
 // buildSnapshot 从 byID 构建完整快照（On/Off 时调用，非热路径）
 func buildSnapshot(byID map[string][]*sub) *subsSnapshot {
 	snap := &subsSnapshot{
 		byID:     byID,
+		slots:    make(map[string][]*sub, len(byID)),
 		handlers: make(map[string][]core.Handler, len(byID)),
 	}
 	for k, subs := range byID {
-		hs := make([]core.Handler, len(subs))
-		for i, s := range subs {
+		slots := group.Collapse(subs, groupInfo, groupSlot)
+		hs := make([]core.Handler, len(slots))
+		for i, s := range slots {
 			hs[i] = s.handler
 		}
+		snap.slots[k] = slots
 		snap.handlers[k] = hs
 	}
 	// 单事件类型快速路径：跳过 map lookup
@@ -63,11 +113,29 @@ func buildSnapshot(byID map[string][]*sub) *subsSnapshot {
 		for k, hs := range snap.handlers {
 			snap.singleKey = k
 			snap.singleHandlers = hs
+			snap.singleSubs = snap.slots[k]
 		}
 	}
 	return snap
 }
 
+// groupInfo 返回订阅所属的组与均衡策略（group.Collapse 回调）
+func groupInfo(s *sub) (string, core.GroupBalance) {
+	return s.group, s.balance
+}
+
+// groupSlot 为订阅组创建分发槽位：handler 按均衡策略转交给一个成员
+func groupSlot(g *group.Group[*sub]) *sub {
+	return &sub{
+		pattern: g.Members()[0].pattern,
+		group:   g.Name(),
+		grp:     g,
+		handler: func(evt *core.Event) error {
+			return g.Pick(evt.Key).handler(evt)
+		},
+	}
+}
+
 var globalSubID atomic.Uint64
 
 // ─── Bus ─────────────────────────────────────────────────────────────
@@ -87,15 +155,68 @@ type Bus struct {
 	// 生命周期
 	closed atomic.Bool
 
+	// 按键有序: Event.Key 非空时经 keyed ring 投递（同 key 同 worker，FIFO）
+	ordered bool
+
 	// 运行时统计（emitted 已移除：消除 consumer 热路径 7ns 开销）
 	processed *util.PerCPUCounter
 	panics    *util.PerCPUCounter
+
+	// panic/错误回调（冷路径，仅 panic/error 时读取）
+	onPanic atomic.Pointer[core.PanicInfoHandler]
+	onError atomic.Pointer[core.ErrorHandler]
+	errs    util.KeyedCounter // pattern → handler error 次数
+
+	// 保留事件（sticky）
+	ret *retain.Store
+
+	// 延迟发布（时间轮）
+	delay *wheel.Wheel
+
+	// 按 Event.ID 去重（nil=未开启）
+	dedup *dedup.Set
+
+	// 限流（发布侧 nil=未配置规则；订阅侧命中计数）
+	limits  *ratelimit.Limits
+	subHits ratelimit.Hits
+
+	// 负载谓词（WithWhere）
+	preds predicate.Registry
+
+	// handler 耗时直方图（nil=未开启）
+	lat *latency.Recorder
 }
 
 // Config SPSC 配置（简化：不再需要 NodeCount/NodeSize）
 type Config struct {
 	Workers  int    // worker 数量（0=NumCPU/2 = 物理核数）
 	RingSize uint64 // 每个 SPSC ring 大小（0=8192，必须 2 的幂）
+	Ordered  bool   // 按 Event.Key 保序投递（同 key FIFO，不同 key 仍并行）
+
+	WorkStealing bool // 空闲 worker 从积压最多的 ring 窃取批次（同一 ring 内不再保序）
+
+	Scale core.ScalePolicy // 弹性 worker（Max>0 时开启；Workers 为初始 worker 数，按 [Min, Max] 截断）
+
+	Overflow        core.OverflowPolicy // ring 满时的处理策略（默认 OverflowBlock）
+	OverflowTimeout time.Duration       // OverflowBlockTimeout 的等待上限（0=100ms）
+
+	PanicHandler core.PanicInfoHandler // handler panic 回调（nil=仅计数）
+	ErrorHandler core.ErrorHandler     // handler error 回调（nil=仅计数）
+
+	Retain []string // 自动保留的事件 pattern（支持通配符；匹配的事件发布时保留最新副本）
+
+	DelayPolicy core.DelayPolicy // Drain 时未到期延迟事件的处理方式（默认 DelayFire）
+
+	DedupWindow     time.Duration // >0 时按 Event.ID 去重，窗口内重复 ID 的事件在匹配前丢弃
+	DedupMaxEntries int           // 去重最多记录的 ID 数（0=65536）
+
+	RateLimits []core.RateLimit // 发布侧限流规则（按事件类型 pattern 的令牌桶）
+
+	PatternSyntax core.PatternSyntax // pattern 语法（订阅、保留与限流 pattern 共用；零值=DotSyntax）
+
+	MatchCacheSize int // 匹配结果缓存容量（条目数；0=core.DefaultMatchCacheSize，<0 不缓存）
+
+	HandlerLatency bool // 按订阅 pattern 记录 handler 耗时（core.LatencyStatter）
 }
 
 // DefaultConfig 默认配置
@@ -126,22 +247,59 @@ func New(cfg *Config) *Bus {
 		cfg.RingSize = 1 << 13
 	}
 
+	slots := cfg.Workers
+	if cfg.Scale.Max > 0 {
+		slots = cfg.Scale.Max // 弹性模式: 槽位数取上限
+	}
+
 	e := &Bus{
-		sch:       NewShardedScheduler(cfg.RingSize, cfg.Workers),
-		matcher:   core.NewTrieMatcher(),
+		sch:       NewShardedScheduler(cfg.RingSize, slots),
+		matcher:   core.NewTrieMatcherSize(cfg.PatternSyntax, cfg.MatchCacheSize),
 		processed: util.NewPerCPUCounter(),
 		panics:    util.NewPerCPUCounter(),
+		ordered:   cfg.Ordered,
+		ret:       retain.New(cfg.Retain, cfg.PatternSyntax),
+	}
+	if cfg.Ordered {
+		e.sch.EnableKeyed()
+	}
+	if cfg.WorkStealing {
+		e.sch.EnableStealing()
 	}
+	if cfg.Scale.Max > 0 {
+		e.sch.EnableElastic(cfg.Scale.Min, cfg.Scale.Interval, cfg.Scale.Observer)
+		e.sch.Resize(cfg.Workers)
+	}
+	e.sch.SetOverflow(cfg.Overflow, cfg.OverflowTimeout)
 
 	e.subs.Store(buildSnapshot(make(map[string][]*sub)))
+	e.delay = wheel.New(e.Emit, cfg.DelayPolicy)
+	if cfg.DedupWindow > 0 {
+		e.dedup = dedup.New(cfg.DedupWindow, cfg.DedupMaxEntries)
+	}
+	e.limits = ratelimit.New(cfg.RateLimits, cfg.PatternSyntax)
+	e.lat = latency.New(cfg.HandlerLatency)
+	e.SetPanicInfoHandler(cfg.PanicHandler)
+	e.SetErrorHandler(cfg.ErrorHandler)
 
+	// 兜底: dispatch 已逐 handler 捕获 panic，此处仅防御 worker 自身异常
 	e.sch.OnPanic = func(r any) {
 		e.panics.Add(1)
 	}
+	// 被溢出策略丢弃的 EmitAwait 事件以 ErrQueueFull 完成
+	e.sch.OnDrop = func(evt *core.Event) {
+		if c := evt.Completion(); c != nil {
+			c.AddError(core.ErrQueueFull)
+			c.Resolve()
+		}
+	}
 
 	e.sch.Start(func(evt *core.Event) {
 		e.dispatchDirect(evt)
 		e.processed.Add(1)
+		if c := evt.Completion(); c != nil {
+			c.Resolve()
+		}
 	})
 
 	return e
@@ -151,8 +309,41 @@ func New(cfg *Config) *Bus {
 
 // On 订阅事件
 func (e *Bus) On(pattern string, handler core.Handler) uint64 {
+	return e.OnWith(pattern, handler)
+}
+
+// OnWith 带选项订阅事件（实现 core.OptionSubscriber）
+// pattern 语法无效时不订阅并返回 0，错误经 ErrorHandler 上报；需要错误值时用 TryOn。
+func (e *Bus) OnWith(pattern string, handler core.Handler, opts ...core.SubOption) uint64 {
+	id, _ := e.TryOn(pattern, handler, opts...)
+	return id
+}
+
+// TryOn 带选项订阅事件（实现 core.TrySubscriber）
+// pattern 语法无效时不订阅，返回 0 与包装 core.ErrBadPattern 的错误。
+func (e *Bus) TryOn(pattern string, handler core.Handler, opts ...core.SubOption) (uint64, error) {
+	o := core.NewSubOptions(opts...)
+	if _, err := e.matcher.Compile(pattern); err != nil {
+		return 0, e.badPattern(err, pattern, &o)
+	}
 	id := globalSubID.Add(1)
-	s := &sub{id: id, pattern: pattern, handler: handler}
+	s := &sub{
+		id:      id,
+		pattern: pattern,
+		handler: handler,
+		onError: o.ErrorHandler,
+		group:   o.Group,
+		balance: o.GroupBalance,
+	}
+	deliver := func(h core.Handler, evt *core.Event) {
+		if !e.closed.Load() {
+			e.callOut(s, h, evt)
+		}
+	}
+	s.ops = opchain.New(e.delay, deliver)
+	s.handler = ratelimit.Wrap(e.lat.Wrap(pattern, handler), o, &e.subHits, s.ops)
+	s.handler = s.ops.Build(s.handler, o.Operators)
+	s.handler, s.pred = e.preds.Bind(pattern, s.handler, o)
 
 	e.mu.Lock()
 	old := e.subs.Load()
@@ -165,7 +356,60 @@ func (e *Bus) On(pattern string, handler core.Handler) uint64 {
 	e.matcher.Add(pattern)
 	e.mu.Unlock()
 
-	return id
+	// 保留事件在锁外投递（handler 内可再次 On/Off）；订阅组成员不接收
+	if s.group == "" {
+		for _, evt := range e.ret.Match(pattern) {
+			e.callOut(s, s.handler, evt)
+		}
+	}
+	return id, nil
+}
+
+// callOut 在分发路径之外调用订阅的 handler h（保留事件回放、限流合并投递），panic/error 照常上报
+func (e *Bus) callOut(s *sub, h core.Handler, evt *core.Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			e.recovered(r, evt, s)
+		}
+	}()
+	if err := h(evt); err != nil {
+		e.failed(err, evt, s)
+	}
+}
+
+// EmitAfter d 后发布事件（Emit 语义，实现 core.Delayer）
+func (e *Bus) EmitAfter(d time.Duration, evt *core.Event) core.CancelFunc {
+	return e.delay.Add(time.Now().Add(d), evt)
+}
+
+// EmitAt 在 t 时刻发布事件（Emit 语义，实现 core.Delayer）
+func (e *Bus) EmitAt(t time.Time, evt *core.Event) core.CancelFunc {
+	return e.delay.Add(t, evt)
+}
+
+// EmitRetain 保留事件副本后发布（实现 core.Retainer）
+func (e *Bus) EmitRetain(evt *core.Event) error {
+	if evt == nil || e.closed.Load() {
+		return nil
+	}
+	e.ret.Put(evt)
+	return e.Emit(evt)
+}
+
+// Retained 返回类型匹配 pattern 的保留事件副本（实现 core.Retainer）
+func (e *Bus) Retained(pattern string) []*core.Event {
+	return e.ret.Match(pattern)
+}
+
+// ClearRetained 清除类型匹配 pattern 的保留事件（实现 core.Retainer）
+func (e *Bus) ClearRetained(pattern string) int {
+	return e.ret.Clear(pattern)
+}
+
+// OnGroup 加入竞争消费者订阅组（实现 core.GroupSubscriber）
+// 同一 pattern 下同名组的成员每个事件只有一个执行；成员 Off 后在新快照中重新平衡。
+func (e *Bus) OnGroup(pattern, name string, handler core.Handler, opts ...core.SubOption) uint64 {
+	return e.OnWith(pattern, handler, append([]core.SubOption{core.WithGroup(name)}, opts...)...)
 }
 
 // Off 取消订阅
@@ -178,6 +422,9 @@ func (e *Bus) Off(id uint64) {
 		for _, s := range subs {
 			if s.id != id {
 				filtered = append(filtered, s)
+			} else {
+				s.ops.Stop()
+				s.pred.Release()
 			}
 		}
 		if len(filtered) > 0 {
@@ -194,11 +441,68 @@ func (e *Bus) Off(id uint64) {
 }
 
 // Emit 发布事件 — 零分配入队
+// 有序模式下 Key 非空的事件按 key 哈希进入固定 ring，保证同 key 的处理顺序与发布顺序一致。
+// ring 满时按溢出策略处理，仅 OverflowBlockTimeout 超时返回 core.ErrQueueFull。
 func (e *Bus) Emit(evt *core.Event) error {
 	if evt == nil || e.closed.Load() {
 		return nil
 	}
-	e.sch.Submit(evt)
+	if e.dedup.Duplicate(evt) {
+		return nil
+	}
+	if ok, err := e.limits.Admit(evt); !ok {
+		e.dedup.Release(evt)
+		return err
+	}
+	held := e.ret.Hold(evt)
+	var err error
+	if e.ordered && evt.Key != "" {
+		err = e.sch.SubmitKeyed(evt.Key, evt)
+	} else {
+		err = e.sch.Submit(evt)
+	}
+	if err != nil {
+		// 未入队: 撤销去重记录，同一 ID 的重试照常发布
+		e.dedup.Release(evt)
+		return err
+	}
+	e.ret.Keep(held)
+	return nil
+}
+
+// EmitCtx 携带 context 发布事件（实现 core.ContextEmitter）
+// ring 满需要等待时，ctx 结束即放弃入队并返回 ctx.Err()。
+func (e *Bus) EmitCtx(ctx context.Context, evt *core.Event) error {
+	if ctx == nil {
+		return e.Emit(evt)
+	}
+	if evt == nil || e.closed.Load() {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	evt.SetContext(ctx)
+	if e.dedup.Duplicate(evt) {
+		return nil
+	}
+	if ok, err := e.limits.Admit(evt); !ok {
+		e.dedup.Release(evt)
+		return err
+	}
+	held := e.ret.Hold(evt)
+	var err error
+	if e.ordered && evt.Key != "" {
+		err = e.sch.SubmitKeyedCtx(ctx, evt.Key, evt)
+	} else {
+		err = e.sch.SubmitCtx(ctx, evt)
+	}
+	if err != nil {
+		// 未入队: 撤销去重记录，同一 ID 的重试照常发布
+		e.dedup.Release(evt)
+		return err
+	}
+	e.ret.Keep(held)
 	return nil
 }
 
@@ -211,18 +515,70 @@ func (e *Bus) UnsafeEmit(evt *core.Event) error {
 // 注意: Async 模式下 EmitMatch 走同步路径（而非 SPSC ring），
 // 因为通配符需要在发布侧展开所有匹配 pattern 后同步分发。
 // 这保证了 EmitMatch 的返回值语义（handler error 直接返回）。
+// handler panic 被捕获并以 error 返回（同 Sync Emit 语义）。
 func (e *Bus) EmitMatch(evt *core.Event) error {
 	if evt == nil || e.closed.Load() {
 		return nil
 	}
+	if e.dedup.Duplicate(evt) {
+		return nil
+	}
+	if ok, err := e.limits.Admit(evt); !ok {
+		e.dedup.Release(evt)
+		return err
+	}
+	e.ret.Offer(evt)
+	return e.emitMatch(nil, evt)
+}
+
+// EmitMatchCtx 携带 context 发布事件，支持通配符（实现 core.ContextEmitter）
+// 同步分发: 每个 handler 调用前检查 ctx，结束后返回 ctx.Err() 且不再调用剩余 handler。
+func (e *Bus) EmitMatchCtx(ctx context.Context, evt *core.Event) error {
+	if ctx == nil {
+		return e.EmitMatch(evt)
+	}
+	if evt == nil || e.closed.Load() {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	evt.SetContext(ctx)
+	if e.dedup.Duplicate(evt) {
+		return nil
+	}
+	if ok, err := e.limits.Admit(evt); !ok {
+		e.dedup.Release(evt)
+		return err
+	}
+	e.ret.Offer(evt)
+	return e.emitMatch(ctx, evt)
+}
 
+// emitMatch 通配符同步分发（ctx 非 nil 时每个 handler 调用前检查是否已结束）
+func (e *Bus) emitMatch(ctx context.Context, evt *core.Event) (retErr error) {
 	snap := e.subs.Load()
 	patterns := e.matcher.Match(evt.Type)
-	defer e.matcher.Put(patterns)
+	var cur *sub
+	defer func() {
+		e.matcher.Put(patterns)
+		if r := recover(); r != nil {
+			retErr = e.recovered(r, evt, cur)
+		}
+	}()
 
 	for _, pattern := range *patterns {
-		for _, h := range snap.handlers[pattern] {
+		hs, subs := snap.handlers[pattern], snap.slots[pattern]
+		for i := 0; i < len(hs); i++ {
+			if ctx != nil {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+			var h core.Handler
+			cur, h = subs[i].pick(evt, hs[i])
 			if err := h(evt); err != nil {
+				e.failed(err, evt, cur)
 				return err
 			}
 		}
@@ -252,43 +608,149 @@ func (e *Bus) UnsafeEmitMatch(evt *core.Event) error {
 	return nil
 }
 
+// EmitAwait 发布事件并返回完成句柄（实现 core.Awaiter）
+// 句柄在 worker 执行完全部匹配 handler 后完成；入队失败或被溢出策略丢弃时以对应 error 完成。
+func (e *Bus) EmitAwait(ctx context.Context, evt *core.Event) *core.Completion {
+	if evt == nil {
+		return core.ResolvedCompletion(nil)
+	}
+	if e.closed.Load() {
+		return core.ResolvedCompletion(core.ErrClosed)
+	}
+	c := core.NewCompletion()
+	evt.SetCompletion(c)
+	if err := e.EmitCtx(ctx, evt); err != nil {
+		// 未入队: 事件仍归调用方所有
+		evt.SetCompletion(nil)
+		c.AddError(err)
+		c.Resolve()
+	}
+	return c
+}
+
+// EmitMatchAwait 通配符发布并返回完成句柄（实现 core.Awaiter）
+// EmitMatch 为同步分发，返回时句柄已完成。
+func (e *Bus) EmitMatchAwait(ctx context.Context, evt *core.Event) *core.Completion {
+	return core.ResolvedCompletion(e.EmitMatchCtx(ctx, evt))
+}
+
+// Barrier 等待调用前已发布的事件全部处理完毕（实现 core.Awaiter）
+func (e *Bus) Barrier(ctx context.Context) error {
+	if e.closed.Load() {
+		return core.ErrClosed
+	}
+	return e.sch.Barrier(ctx)
+}
+
+// Resize 将 worker 数调整为 n（实现 core.Resizer）
+// 按 [Scale.Min, Scale.Max] 截断；未开启弹性 worker 时不调整，返回当前 worker 数。
+func (e *Bus) Resize(n int) int {
+	return e.sch.Resize(n)
+}
+
+// Workers 返回当前 worker 数（实现 core.Resizer）
+func (e *Bus) Workers() int {
+	return e.sch.Workers()
+}
+
+// HandlerLatency 返回各订阅 pattern 的 handler 耗时分布（实现 core.LatencyStatter；未开启时返回 nil）
+func (e *Bus) HandlerLatency() map[string]util.HistogramSnapshot {
+	return e.lat.Snapshot()
+}
+
+// ResetHandlerLatency 清零全部 pattern 的 handler 耗时分布（实现 core.LatencyStatter）
+func (e *Bus) ResetHandlerLatency() {
+	e.lat.Reset()
+}
+
+// QueueStats 返回各 ring 的积压与延迟（实现 core.QueueStatter）
+func (e *Bus) QueueStats() []core.QueueStat {
+	return e.sch.QueueStats()
+}
+
+// Request 发布请求并返回第一个回复（实现 core.Requester）
+// 回复经临时订阅 core.InboxPrefix+ID 路由回来（同样经 worker 投递）。
+func (e *Bus) Request(ctx context.Context, evt *core.Event) (*core.Event, error) {
+	replies, err := core.SendRequest(ctx, e, evt, false, false)
+	if len(replies) == 0 {
+		return nil, err
+	}
+	return replies[0], err
+}
+
+// RequestAll 发布请求并收集全部回复（实现 core.Requester）
+func (e *Bus) RequestAll(ctx context.Context, evt *core.Event) ([]*core.Event, error) {
+	return core.SendRequest(ctx, e, evt, false, true)
+}
+
 // EmitBatch 批量发布
+// 被限流拒绝的事件跳过，其余照常发布；没有其他 error 时返回 ErrRateLimited。
 func (e *Bus) EmitBatch(events []*core.Event) error {
 	if len(events) == 0 || e.closed.Load() {
 		return nil
 	}
+	var lerr error
 	for _, evt := range events {
 		if evt == nil {
 			continue
 		}
 		if err := e.Emit(evt); err != nil {
+			if err == core.ErrRateLimited {
+				lerr = err
+				continue
+			}
 			return err
 		}
 	}
-	return nil
+	return lerr
 }
 
-// EmitMatchBatch 批量发布（带匹配）
+// EmitMatchBatch 批量发布（带匹配；限流语义同 EmitBatch）
 func (e *Bus) EmitMatchBatch(events []*core.Event) error {
 	if len(events) == 0 || e.closed.Load() {
 		return nil
 	}
+	var lerr error
 	for _, evt := range events {
 		if err := e.EmitMatch(evt); err != nil {
+			if err == core.ErrRateLimited {
+				lerr = err
+				continue
+			}
 			return err
 		}
 	}
-	return nil
+	return lerr
 }
 
 // Stats 返回运行时统计
 // emitted 近似等于 processed（差值 = ring 中未消费事件数）
 func (e *Bus) Stats() core.Stats {
 	processed := e.processed.Read()
+	byPattern := e.errs.Snapshot()
+	var errs int64
+	for _, n := range byPattern {
+		errs += n
+	}
+	retained, retainedBytes := e.ret.Stats()
+	hits, misses, evictions := e.matcher.CacheStats()
 	return core.Stats{
-		Emitted:   processed,
-		Processed: processed,
-		Panics:    e.panics.Read(),
+		Emitted:             processed,
+		Processed:           processed,
+		Panics:              e.panics.Read(),
+		Errors:              errs,
+		Depth:               e.sch.Depth() + e.delay.Len(),
+		Duplicates:          e.dedup.Duplicates(),
+		RateLimited:         e.limits.Hits(),
+		HandlerRateLimited:  e.subHits.Read(),
+		Filtered:            e.preds.Filtered(),
+		MatchCacheHits:      hits,
+		MatchCacheMisses:    misses,
+		MatchCacheEvictions: evictions,
+		Dropped:             e.sch.Dropped(),
+		Retained:            retained,
+		RetainedBytes:       retainedBytes,
+		ErrorsByPattern:     byPattern,
 	}
 }
 
@@ -297,23 +759,40 @@ func (e *Bus) Close() {
 	if !e.closed.CompareAndSwap(false, true) {
 		return
 	}
+	e.delay.Close()
+	e.stopOps()
 	e.sch.Stop()
 }
 
+// stopOps 停止全部订阅的算子（取消未到期的定时投递）
+func (e *Bus) stopOps() {
+	for _, subs := range e.subs.Load().byID {
+		for _, s := range subs {
+			s.ops.Stop()
+		}
+	}
+}
+
 // Drain 优雅关闭
 func (e *Bus) Drain(timeout time.Duration) error {
 	if timeout <= 0 {
 		e.Close()
 		return nil
 	}
+	// 先按 DelayPolicy 处理未到期的延迟事件，再等待已入队事件（含刚发布的延迟事件）处理完毕
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	var derr error
 	done := make(chan struct{})
 	go func() {
+		derr = e.delay.Drain()
+		_ = e.Barrier(ctx)
 		e.Close()
 		close(done)
 	}()
 	select {
 	case <-done:
-		return nil
+		return derr
 	case <-time.After(timeout):
 		return fmt.Errorf("async: graceful close timed out after %v", timeout)
 	}
@@ -322,19 +801,94 @@ func (e *Bus) Drain(timeout time.Duration) error {
 // ─── 内部方法 ─────────────────────────────────────────────────────
 
 // dispatchDirect 精确匹配分发（消费者热路径）
-// 优化: RCU 快照 + 预扁平化 handler + 单类型快速路径 + 2-key inline cache
+// 优化: RCU 快照 + 预扁平化 handler + 单类型快速路径（lookup 内 ≈16ns）
 func (e *Bus) dispatchDirect(evt *core.Event) {
-	snap := e.subs.Load()
-	// 快速路径: 仅 1 种事件类型时跳过 map hash+lookup（≈16ns）
-	if snap.singleKey == evt.Type {
-		for _, h := range snap.singleHandlers {
-			_ = h(evt)
+	hs, subs := e.subs.Load().lookup(evt.Type)
+	for i := 0; i < len(hs); {
+		i = e.invokeFrom(evt, hs, subs, i)
+	}
+}
+
+// invokeFrom 从第 i 个 handler 开始依次调用，handler error 交给错误回调
+// handler panic 时上报并返回下一个索引，保证同一事件的其余 handler 继续执行。
+// 常态路径仅一次 open-coded defer，无额外分配。
+func (e *Bus) invokeFrom(evt *core.Event, hs []core.Handler, subs []*sub, i int) (next int) {
+	var cur *sub
+	defer func() {
+		if r := recover(); r != nil {
+			e.recovered(r, evt, cur)
+			next = i + 1
+		}
+	}()
+	for ; i < len(hs); i++ {
+		var h core.Handler
+		cur, h = subs[i].pick(evt, hs[i])
+		if err := h(evt); err != nil {
+			e.failed(err, evt, cur)
 		}
+	}
+	return i
+}
+
+// recovered 处理已捕获的 handler panic：计数 + 回调通知，返回对应 error
+func (e *Bus) recovered(r interface{}, evt *core.Event, s *sub) error {
+	e.panics.Add(1)
+	if h := e.onPanic.Load(); h != nil {
+		(*h)(r, evt, s.info())
+	}
+	err := fmt.Errorf("handler panic: %v", r)
+	if c := evt.Completion(); c != nil {
+		c.AddError(err)
+	}
+	return err
+}
+
+// SetPanicHandler 注册 panic 回调（实现 core.PanicNotifier）
+func (e *Bus) SetPanicHandler(h core.PanicHandler) {
+	e.SetPanicInfoHandler(core.PanicInfo(h))
+}
+
+// SetPanicInfoHandler 注册带订阅信息的 panic 回调（实现 core.PanicNotifier）
+func (e *Bus) SetPanicInfoHandler(h core.PanicInfoHandler) {
+	if h == nil {
+		e.onPanic.Store(nil)
 		return
 	}
-	// 通用路径: map lookup（多事件类型）
-	handlers := snap.handlers[evt.Type]
-	for _, h := range handlers {
-		_ = h(evt)
+	e.onPanic.Store(&h)
+}
+
+// failed 处理 handler 返回的 error：按模式计数 + 订阅级/Bus 级回调
+func (e *Bus) failed(err error, evt *core.Event, s *sub) {
+	e.errs.Add(s.pattern, 1)
+	if c := evt.Completion(); c != nil {
+		c.AddError(err)
+	}
+	info := s.info()
+	if s.onError != nil {
+		s.onError(err, evt, info)
+	}
+	if h := e.onError.Load(); h != nil {
+		(*h)(err, evt, info)
+	}
+}
+
+// badPattern 上报语法无效的订阅 pattern（evt 为 nil，sub 仅含 Pattern），返回原错误
+func (e *Bus) badPattern(err error, pattern string, o *core.SubOptions) error {
+	info := core.SubInfo{Pattern: pattern}
+	if o.ErrorHandler != nil {
+		o.ErrorHandler(err, nil, info)
+	}
+	if h := e.onError.Load(); h != nil {
+		(*h)(err, nil, info)
+	}
+	return err
+}
+
+// SetErrorHandler 注册错误回调（实现 core.ErrorNotifier）
+func (e *Bus) SetErrorHandler(h core.ErrorHandler) {
+	if h == nil {
+		e.onError.Store(nil)
+		return
 	}
+	e.onError.Store(&h)
 }
@@ -2,6 +2,7 @@
 package flow
 
 import (
+	"context"
 	"fmt"
 	"runtime"
 	"sync"
@@ -9,26 +10,88 @@ import (
 	"time"
 
 	"github.com/uniyakcom/beat/core"
+	"github.com/uniyakcom/beat/internal/support/dedup"
+	"github.com/uniyakcom/beat/internal/support/group"
+	"github.com/uniyakcom/beat/internal/support/latency"
+	"github.com/uniyakcom/beat/internal/support/opchain"
+	"github.com/uniyakcom/beat/internal/support/predicate"
+	"github.com/uniyakcom/beat/internal/support/ratelimit"
+	"github.com/uniyakcom/beat/internal/support/retain"
+	"github.com/uniyakcom/beat/internal/support/spsc"
+	"github.com/uniyakcom/beat/internal/support/wheel"
 	"github.com/uniyakcom/beat/util"
 )
 
-// Stage 处理阶段定义，支持批量处理和错误返回
+// Stage 处理阶段定义，支持批量处理和错误返回（仅观察批次，不改变批次内容）
+// 需要过滤/转换/丢弃事件时使用 core.Stage（通过 Config.Stages 注册）。
 type Stage func([]*core.Event) error
 
+// toCore 适配为 core.Stage（原样透传批次）
+func (s Stage) toCore() core.Stage {
+	return func(batch []*core.Event) ([]*core.Event, error) {
+		return batch, s(batch)
+	}
+}
+
+// Config Flow 配置
+type Config struct {
+	Stages            []core.Stage           // Pipeline 阶段（按顺序执行）
+	StageErrorPolicy  core.StageErrorPolicy  // Stage 错误策略（默认 StageSkipBatch）
+	StageErrorHandler core.StageErrorHandler // StageRouteError 策略下的错误回调
+	BatchSize         int                    // 批次大小（0=100）
+	BatchTimeout      time.Duration          // 批次超时（0=100ms）
+	PanicHandler      core.PanicInfoHandler  // handler/Stage panic 回调（nil=仅计数）
+	ErrorHandler      core.ErrorHandler      // handler error 回调（nil=仅计数）
+	Retain            []string               // 自动保留的事件 pattern（支持通配符；匹配的事件发布时保留最新副本）
+	DelayPolicy       core.DelayPolicy       // Drain 时未到期延迟事件的处理方式（默认 DelayFire）
+	DedupWindow       time.Duration          // >0 时按 Event.ID 去重，窗口内重复 ID 的事件在匹配前丢弃
+	DedupMaxEntries   int                    // 去重最多记录的 ID 数（0=65536）
+	RateLimits        []core.RateLimit       // 发布侧限流规则（按事件类型 pattern 的令牌桶）
+	PatternSyntax     core.PatternSyntax     // pattern 语法（订阅、保留与限流 pattern 共用；零值=DotSyntax）
+	MatchCacheSize    int                    // 匹配结果缓存容量（条目数；0=core.DefaultMatchCacheSize，<0 不缓存）
+	HandlerLatency    bool                   // 按订阅 pattern 记录 handler 耗时（core.LatencyStatter）
+}
+
 // subscription 订阅信息（支持CoW模式）
 type subscription struct {
 	id      uint64
 	pattern string
 	handler core.Handler
+	onError core.ErrorHandler // 订阅级错误回调（可为 nil）
+
+	// 订阅组（OnGroup）：成员记录组名与策略；组槽位额外持有 grp（id=0，仅出现在 byPattern 中）
+	group   string
+	balance core.GroupBalance
+	grp     *group.Group[*subscription]
+
+	ops  *opchain.Chain     // 定时投递环境（算子、限流合并投递；Off 时 Stop）
+	pred *predicate.Binding // 负载谓词（nil=未挂载）
+}
+
+// info 返回上报给回调的订阅信息
+func (s *subscription) info() core.SubInfo {
+	return core.SubInfo{ID: s.id, Pattern: s.pattern, Group: s.group}
+}
+
+// pick 返回本次调用实际执行的订阅与 handler（组槽位按均衡策略选出一个成员）
+func (s *subscription) pick(evt *core.Event, h core.Handler) (*subscription, core.Handler) {
+	if s.grp == nil {
+		return s, h
+	}
+	m := s.grp.Pick(evt.Key)
+	return m, m.handler
 }
 
 // flowSnapshot CoW 快照 — 预构建 handler map，消除消费者侧双循环
+// byPattern[k][i] 与 handlers[k][i] 一一对应（订阅组成员折叠为一个组槽位），panic 上报时据此定位订阅。
 type flowSnapshot struct {
 	subs           []*subscription
-	handlers       map[string][]core.Handler // key=pattern, 扁平化 handler
-	singleKey      string                    // 仅 1 种事件类型时的 key（跳过 map hash+lookup）
-	singleHandlers []core.Handler            // 仅 1 种事件类型时的 handler 列表
-	hasWildcard    bool                      // 是否包含通配符模式
+	handlers       map[string][]core.Handler  // key=pattern, 扁平化 handler
+	byPattern      map[string][]*subscription // key=pattern, 与 handlers 对齐
+	singleKey      string                     // 仅 1 种事件类型时的 key（跳过 map hash+lookup）
+	singleHandlers []core.Handler             // 仅 1 种事件类型时的 handler 列表
+	singleSubs     []*subscription            // 仅 1 种事件类型时的订阅列表
+	hasWildcard    bool                       // 是否包含通配符模式
 }
 
 // slot Disruptor风格槽位（与queue包一致的设计）
@@ -47,21 +110,40 @@ type RB struct {
 	head atomic.Uint64
 	_2   [56]byte // 缓存行填充
 
+	// 累计完成数（consumer 处理完一批后累加，Barrier 据此等待）
+	done atomic.Uint64
+	_3   [56]byte // 缓存行填充
+
 	buf  []flowSlot
 	cap  uint64
 	mask uint64
+
+	// 延迟采样: 每 32 个序号采样一个，生产者在提交槽位前写入入队时间戳，消费者在释放槽位前记录延迟
+	// 时间戳槽位复用间隔 ≥ cap: 被覆盖前其采样事件必已出队
+	stamps []atomic.Int64
+	smask  uint64
+	lat    util.Histogram
 }
 
+// stampShift 采样间隔 = 1<<stampShift 个序号
+const stampShift = 5
+
 // newRB 创建环形缓冲区
 func newRB(capacity int) *RB {
 	sz := 1
 	for sz < capacity {
 		sz *= 2
 	}
+	slots := sz >> stampShift
+	if slots == 0 {
+		slots = 1
+	}
 	rb := &RB{
-		buf:  make([]flowSlot, sz),
-		cap:  uint64(sz),
-		mask: uint64(sz - 1),
+		buf:    make([]flowSlot, sz),
+		cap:    uint64(sz),
+		mask:   uint64(sz - 1),
+		stamps: make([]atomic.Int64, slots),
+		smask:  uint64(slots - 1),
 	}
 	for i := 0; i < sz; i++ {
 		rb.buf[i].seq.Store(int64(i))
@@ -79,6 +161,9 @@ func (r *RB) push(evt *core.Event) bool {
 		if diff == 0 {
 			if r.tail.CompareAndSwap(tail, tail+1) {
 				s.data.Store(evt)
+				if tail&(1<<stampShift-1) == 0 {
+					r.stamps[tail>>stampShift&r.smask].Store(spsc.Nanotime())
+				}
 				s.seq.Store(int64(tail + 1))
 				return true
 			}
@@ -102,6 +187,9 @@ func (r *RB) popBatch(batch []*core.Event) int {
 		}
 		batch[count] = s.data.Load()
 		s.data.Store(nil)
+		if pos := head + uint64(i); pos&(1<<stampShift-1) == 0 {
+			r.lat.Record(time.Duration(spsc.Nanotime() - r.stamps[pos>>stampShift&r.smask].Load()))
+		}
 		s.seq.Store(int64(head+uint64(i)) + int64(r.cap))
 		count++
 	}
@@ -112,6 +200,27 @@ func (r *RB) popBatch(batch []*core.Event) int {
 	return count
 }
 
+// stat 返回分片的积压、容量、最早采样事件的等待时长与入队→出队延迟分位数
+func (r *RB) stat(i int) core.QueueStat {
+	head, tail := r.head.Load(), r.tail.Load()
+	q := core.QueueStat{
+		Kind:     "shard",
+		Index:    i,
+		Depth:    int64(tail - head),
+		Capacity: int64(r.cap),
+	}
+	lat := r.lat.Snapshot()
+	q.Samples, q.LatencyP50, q.LatencyP99, q.LatencyMax = lat.Count, lat.Percentile(50), lat.Percentile(99), lat.Max
+	// 不早于 head 的第一个采样序号；仅在其槽位已提交（时间戳已写入）时读取
+	pos := (head + 1<<stampShift - 1) &^ (1<<stampShift - 1)
+	if pos < tail && r.buf[pos&r.mask].seq.Load() == int64(pos+1) {
+		if age := spsc.Nanotime() - r.stamps[pos>>stampShift&r.smask].Load(); age > 0 {
+			q.OldestAge = time.Duration(age)
+		}
+	}
+	return q
+}
+
 // Bus 高性能批处理事件总线（字段按访问频率排列）
 type Bus struct {
 	// === Reader 热路径 ===
@@ -125,7 +234,9 @@ type Bus struct {
 	closed  atomic.Bool
 
 	// Pipeline阶段
-	stages []Stage
+	stages     []core.Stage
+	stagePol   core.StageErrorPolicy
+	stageErrFn core.StageErrorHandler
 
 	// 批处理配置（只读）
 	batchSz      int
@@ -150,15 +261,60 @@ type Bus struct {
 	batches   atomic.Uint64
 	panics    *util.PerCPUCounter
 
+	// 未完成的 EmitAwait 句柄数（为 0 时消费侧跳过句柄收集）
+	awaiting atomic.Int64
+
+	// panic/错误回调（冷路径，仅 panic/error 时读取）
+	onPanic atomic.Pointer[core.PanicInfoHandler]
+	onError atomic.Pointer[core.ErrorHandler]
+	errs    util.KeyedCounter // pattern → handler error 次数
+
 	// emitSlow 降级专用（预分配复用，避免堆分配）
 	slowBuf []*core.Event
 	slowMu  sync.Mutex
+
+	// 保留事件（sticky）
+	ret *retain.Store
+
+	// 延迟发布（时间轮）
+	delay *wheel.Wheel
+
+	// 按 Event.ID 去重（nil=未开启）
+	dedup *dedup.Set
+
+	// 限流（发布侧 nil=未配置规则；订阅侧命中计数）
+	limits  *ratelimit.Limits
+	subHits ratelimit.Hits
+
+	// 负载谓词（WithWhere）
+	preds predicate.Registry
+
+	// handler 耗时直方图（nil=未开启）
+	lat *latency.Recorder
 }
 
 // New 创建批处理处理器
 // 自适应: 分片数量跟随 NumCPU，低核环境（2 vCPU）不再强制 4 分片。
 // 每个分片独立 notify 通道，消除跨分片虚假唤醒。
 func New(stages []Stage, batchSz int, timeout time.Duration) *Bus {
+	cs := make([]core.Stage, len(stages))
+	for i, s := range stages {
+		cs[i] = s.toCore()
+	}
+	return NewWithConfig(&Config{
+		Stages:       cs,
+		BatchSize:    batchSz,
+		BatchTimeout: timeout,
+	})
+}
+
+// NewWithConfig 使用配置创建批处理处理器
+func NewWithConfig(cfg *Config) *Bus {
+	if cfg == nil {
+		cfg = &Config{}
+	}
+	batchSz := cfg.BatchSize
+	timeout := cfg.BatchTimeout
 	if batchSz <= 0 {
 		batchSz = 100
 	}
@@ -184,7 +340,9 @@ func New(stages []Stage, batchSz int, timeout time.Duration) *Bus {
 	}
 
 	p := &Bus{
-		stages:       stages,
+		stages:       cfg.Stages,
+		stagePol:     cfg.StageErrorPolicy,
+		stageErrFn:   cfg.StageErrorHandler,
 		numShards:    shards,
 		shardMask:    uint64(shards - 1),
 		buffers:      make([]*RB, shards),
@@ -194,6 +352,7 @@ func New(stages []Stage, batchSz int, timeout time.Duration) *Bus {
 		notifyChs:    notifyChs,
 		panics:       util.NewPerCPUCounter(),
 		slowBuf:      make([]*core.Event, 1),
+		ret:          retain.New(cfg.Retain, cfg.PatternSyntax),
 	}
 
 	// 初始化RingBuffer（每个分片独立）
@@ -211,10 +370,19 @@ func New(stages []Stage, batchSz int, timeout time.Duration) *Bus {
 
 	// 初始化空订阅快照和匹配器
 	p.subsPtr.Store(&flowSnapshot{
-		subs:     make([]*subscription, 0),
-		handlers: make(map[string][]core.Handler),
+		subs:      make([]*subscription, 0),
+		handlers:  make(map[string][]core.Handler),
+		byPattern: make(map[string][]*subscription),
 	})
-	p.matcher = core.NewTrieMatcher()
+	p.matcher = core.NewTrieMatcherSize(cfg.PatternSyntax, cfg.MatchCacheSize)
+	p.delay = wheel.New(p.Emit, cfg.DelayPolicy)
+	if cfg.DedupWindow > 0 {
+		p.dedup = dedup.New(cfg.DedupWindow, cfg.DedupMaxEntries)
+	}
+	p.limits = ratelimit.New(cfg.RateLimits, cfg.PatternSyntax)
+	p.lat = latency.New(cfg.HandlerLatency)
+	p.SetPanicInfoHandler(cfg.PanicHandler)
+	p.SetErrorHandler(cfg.ErrorHandler)
 
 	// 为每个分片启动消费者
 	for i := 0; i < shards; i++ {
@@ -251,7 +419,7 @@ func (p *Bus) consumer(shardIdx int) {
 		// 先尝试无阻塞批量弹出
 		count := rb.popBatch(batch)
 		if count > 0 {
-			p.safeProcessBatch(batch[:count])
+			p.consume(rb, batch[:count])
 			// 有数据时继续紧凑轮询（短窗口内可能还有更多数据）
 			continue
 		}
@@ -263,7 +431,7 @@ func (p *Bus) consumer(shardIdx int) {
 			for {
 				n := rb.popBatch(batch)
 				if n > 0 {
-					p.safeProcessBatch(batch[:n])
+					p.consume(rb, batch[:n])
 				} else {
 					break
 				}
@@ -274,29 +442,71 @@ func (p *Bus) consumer(shardIdx int) {
 			// 定时唤醒：处理可能积压的事件
 			n := rb.popBatch(batch)
 			if n > 0 {
-				p.safeProcessBatch(batch[:n])
+				p.consume(rb, batch[:n])
 			}
 
 		case <-notifyCh:
 			// 生产者信号唤醒：立即消费（仅本分片有数据时触发）
 			n := rb.popBatch(batch)
 			if n > 0 {
-				p.safeProcessBatch(batch[:n])
+				p.consume(rb, batch[:n])
 			}
 		}
 	}
 }
 
+// consume 处理从 rb 弹出的批次并累加其完成数
+func (p *Bus) consume(rb *RB, events []*core.Event) {
+	p.safeProcessBatch(events)
+	rb.done.Add(uint64(len(events)))
+}
+
 // safeProcessBatch 安全处理批次：捕获 panic，防止 consumer 崩溃
+// handler panic 已在 invokeFrom 中逐个捕获，此处仅兜底 Stage panic（整批丢弃）。
+// 有未完成的 EmitAwait 句柄时，先于 Stage 收集批内句柄（Stage 可能过滤/替换事件），
+// 批次处理结束后统一完成，被 Stage 过滤的事件同样完成。
 func (p *Bus) safeProcessBatch(events []*core.Event) {
+	var pending []*core.Completion
+	if p.awaiting.Load() > 0 {
+		pending = collectCompletions(events)
+	}
 	defer func() {
-		if r := recover(); r != nil {
-			p.panics.Add(1)
+		r := recover()
+		if r != nil {
+			p.notifyPanic(r, nil, nil)
+		}
+		if pending != nil {
+			p.resolveAll(pending, r)
 		}
 	}()
 	p.processBatch(events)
 }
 
+// collectCompletions 收集批次内事件关联的完成句柄（无句柄时返回 nil）
+func collectCompletions(events []*core.Event) []*core.Completion {
+	var pending []*core.Completion
+	for _, evt := range events {
+		if evt == nil {
+			continue
+		}
+		if c := evt.Completion(); c != nil {
+			pending = append(pending, c)
+		}
+	}
+	return pending
+}
+
+// resolveAll 完成批次内的句柄（r 非 nil 表示 Stage panic，记入每个句柄）
+func (p *Bus) resolveAll(pending []*core.Completion, r interface{}) {
+	for _, c := range pending {
+		if r != nil {
+			c.AddError(fmt.Errorf("stage panic: %v", r))
+		}
+		c.Resolve()
+	}
+	p.awaiting.Add(-int64(len(pending)))
+}
+
 // processBatch 处理一个批次的事件
 // 精确匹配: 直接索引 handlers[evt.Type]，零分配
 // 通配符: fallback 到 TrieMatcher.Match，仅在有通配符订阅时触发
@@ -305,51 +515,168 @@ func (p *Bus) processBatch(events []*core.Event) {
 		return
 	}
 
-	// 执行Pipeline阶段
+	// 执行Pipeline阶段，订阅者仅看到最终批次
+	current := p.runStages(events)
+	if len(current) > 0 {
+		p.dispatchBatch(p.subsPtr.Load(), current)
+	}
+
+	// 更新统计（按出队事件数计，含被 Stage 过滤/丢弃的事件）
+	p.processed.Add(uint64(len(events)))
+	p.batches.Add(1)
+}
+
+// runStages 依次执行 Pipeline 阶段，返回交给订阅者的批次
+// 返回空批次表示整批丢弃（阶段过滤为空，或 Stage 出错且策略为丢弃）。
+func (p *Bus) runStages(events []*core.Event) []*core.Event {
 	current := events
 	for _, stage := range p.stages {
 		if len(current) == 0 {
-			break
+			return nil
 		}
-		if err := stage(current); err != nil {
-			break
+		out, err := stage(current)
+		if err != nil {
+			switch p.stagePol {
+			case core.StageContinue:
+				if out == nil {
+					out = current
+				}
+			case core.StageRouteError:
+				if p.stageErrFn != nil {
+					p.stageErrFn(err, current)
+				}
+				return nil
+			default:
+				return nil
+			}
 		}
+		current = out
 	}
+	return current
+}
 
-	// 调用订阅handler
-	snap := p.subsPtr.Load()
-	if len(snap.handlers) > 0 && len(current) > 0 {
-		if snap.singleKey != "" {
-			// 最快路径: 仅 1 种事件类型，跳过 map hash+lookup
-			for _, evt := range current {
-				for _, h := range snap.singleHandlers {
-					h(evt)
-				}
-			}
-		} else if !snap.hasWildcard {
-			// 快速路径: 仅精确匹配，直接 map 索引，零分配
-			for _, evt := range current {
-				for _, h := range snap.handlers[evt.Type] {
-					h(evt)
-				}
+// dispatchBatch 将批次分发给订阅 handler
+// 单个 handler panic 仅跳过该 handler，批次内其余 handler/事件继续处理。
+func (p *Bus) dispatchBatch(snap *flowSnapshot, events []*core.Event) {
+	if len(snap.handlers) == 0 {
+		return
+	}
+	if snap.singleKey != "" {
+		// 最快路径: 仅 1 种事件类型，跳过 map hash+lookup
+		for _, evt := range events {
+			if evt.Type == snap.singleKey {
+				p.invoke(evt, snap.singleHandlers, snap.singleSubs)
 			}
-		} else {
-			// 通配符路径: 需要 TrieMatcher 解析
-			for _, evt := range current {
-				patterns := p.matcher.Match(evt.Type)
-				for _, pat := range *patterns {
-					for _, h := range snap.handlers[pat] {
-						h(evt)
-					}
-				}
-				p.matcher.Put(patterns)
+		}
+	} else if !snap.hasWildcard {
+		// 快速路径: 仅精确匹配，直接 map 索引，零分配
+		for _, evt := range events {
+			p.invoke(evt, snap.handlers[evt.Type], snap.byPattern[evt.Type])
+		}
+	} else {
+		// 通配符路径: 需要 TrieMatcher 解析
+		for _, evt := range events {
+			patterns := p.matcher.Match(evt.Type)
+			for _, pat := range *patterns {
+				p.invoke(evt, snap.handlers[pat], snap.byPattern[pat])
 			}
+			p.matcher.Put(patterns)
 		}
 	}
+}
 
-	// 更新统计
-	p.processed.Add(uint64(len(events)))
-	p.batches.Add(1)
+// invoke 依次调用 handler（panic 隔离到单个 handler）
+func (p *Bus) invoke(evt *core.Event, hs []core.Handler, subs []*subscription) {
+	for i := 0; i < len(hs); {
+		i = p.invokeFrom(evt, hs, subs, i)
+	}
+}
+
+// invokeFrom 从第 i 个 handler 开始依次调用，panic 时上报并返回下一个索引
+// handler error 交给错误回调，不影响同批次其余 handler。
+func (p *Bus) invokeFrom(evt *core.Event, hs []core.Handler, subs []*subscription, i int) (next int) {
+	var cur *subscription
+	defer func() {
+		if r := recover(); r != nil {
+			p.notifyPanic(r, evt, cur)
+			next = i + 1
+		}
+	}()
+	for ; i < len(hs); i++ {
+		var h core.Handler
+		cur, h = subs[i].pick(evt, hs[i])
+		if err := h(evt); err != nil {
+			p.failed(err, evt, cur)
+		}
+	}
+	return i
+}
+
+// failed 处理 handler 返回的 error：按模式计数 + 订阅级/Bus 级回调
+func (p *Bus) failed(err error, evt *core.Event, s *subscription) {
+	p.errs.Add(s.pattern, 1)
+	if c := evt.Completion(); c != nil {
+		c.AddError(err)
+	}
+	info := s.info()
+	if s.onError != nil {
+		s.onError(err, evt, info)
+	}
+	if h := p.onError.Load(); h != nil {
+		(*h)(err, evt, info)
+	}
+}
+
+// badPattern 上报语法无效的订阅 pattern（evt 为 nil，sub 仅含 Pattern），返回原错误
+func (p *Bus) badPattern(err error, pattern string, o *core.SubOptions) error {
+	info := core.SubInfo{Pattern: pattern}
+	if o.ErrorHandler != nil {
+		o.ErrorHandler(err, nil, info)
+	}
+	if h := p.onError.Load(); h != nil {
+		(*h)(err, nil, info)
+	}
+	return err
+}
+
+// SetErrorHandler 注册错误回调（实现 core.ErrorNotifier）
+func (p *Bus) SetErrorHandler(h core.ErrorHandler) {
+	if h == nil {
+		p.onError.Store(nil)
+		return
+	}
+	p.onError.Store(&h)
+}
+
+// notifyPanic 计数 + 回调通知（s 为 nil 表示 Stage panic）
+func (p *Bus) notifyPanic(r interface{}, evt *core.Event, s *subscription) {
+	p.panics.Add(1)
+	if evt != nil {
+		if c := evt.Completion(); c != nil {
+			c.AddError(fmt.Errorf("handler panic: %v", r))
+		}
+	}
+	if h := p.onPanic.Load(); h != nil {
+		var info core.SubInfo
+		if s != nil {
+			info = s.info()
+		}
+		(*h)(r, evt, info)
+	}
+}
+
+// SetPanicHandler 注册 panic 回调（实现 core.PanicNotifier）
+func (p *Bus) SetPanicHandler(h core.PanicHandler) {
+	p.SetPanicInfoHandler(core.PanicInfo(h))
+}
+
+// SetPanicInfoHandler 注册带订阅信息的 panic 回调（实现 core.PanicNotifier）
+func (p *Bus) SetPanicInfoHandler(h core.PanicInfoHandler) {
+	if h == nil {
+		p.onPanic.Store(nil)
+		return
+	}
+	p.onPanic.Store(&h)
 }
 
 // processSingle 处理单个事件（emitSlow 降级专用，零分配）
@@ -359,91 +686,104 @@ func (p *Bus) processBatch(events []*core.Event) {
 //go:noinline
 func (p *Bus) processSingle(evt *core.Event) {
 	p.slowMu.Lock()
-
-	// 复用预分配切片
+	// 复用预分配切片；Stage panic 由 safeProcessBatch 捕获，丢弃该事件，不传播到 Emit 调用方
 	p.slowBuf[0] = evt
-
-	// 执行 Pipeline 阶段
-	for _, stage := range p.stages {
-		if err := stage(p.slowBuf); err != nil {
-			break
-		}
-	}
-
-	// 调用订阅 handler
-	snap := p.subsPtr.Load()
-	if len(snap.handlers) > 0 {
-		if snap.singleKey != "" {
-			// 最快路径: 仅 1 种事件类型，跳过 map hash+lookup
-			for _, h := range snap.singleHandlers {
-				h(evt)
-			}
-		} else if !snap.hasWildcard {
-			for _, h := range snap.handlers[evt.Type] {
-				h(evt)
-			}
-		} else {
-			patterns := p.matcher.Match(evt.Type)
-			for _, pat := range *patterns {
-				for _, h := range snap.handlers[pat] {
-					h(evt)
-				}
-			}
-			p.matcher.Put(patterns)
-		}
-	}
-
+	p.safeProcessBatch(p.slowBuf)
 	p.slowBuf[0] = nil // 防止 GC 保留引用
 	p.slowMu.Unlock()
-
-	p.processed.Add(1)
-	p.batches.Add(1)
 }
 
 // buildFlowSnapshot 从订阅列表构建快照（On/Off 时调用，非热路径）
-func buildFlowSnapshot(subs []*subscription) *flowSnapshot {
-	handlers := make(map[string][]core.Handler)
+func buildFlowSnapshot(subs []*subscription, syntax core.PatternSyntax) *flowSnapshot {
+	byPattern := make(map[string][]*subscription)
 	hasWild := false
 	for _, s := range subs {
-		handlers[s.pattern] = append(handlers[s.pattern], s.handler)
-		if !hasWild && containsWildcard(s.pattern) {
+		byPattern[s.pattern] = append(byPattern[s.pattern], s)
+		if !hasWild && syntax.HasWildcard(s.pattern) {
 			hasWild = true
 		}
 	}
-	snap := &flowSnapshot{subs: subs, handlers: handlers, hasWildcard: hasWild}
+	// 订阅组成员折叠为一个组槽位，handlers 与折叠后的槽位对齐
+	handlers := make(map[string][]core.Handler, len(byPattern))
+	for k, ps := range byPattern {
+		slots := group.Collapse(ps, groupInfo, groupSlot)
+		hs := make([]core.Handler, len(slots))
+		for i, s := range slots {
+			hs[i] = s.handler
+		}
+		byPattern[k] = slots
+		handlers[k] = hs
+	}
+	snap := &flowSnapshot{subs: subs, handlers: handlers, byPattern: byPattern, hasWildcard: hasWild}
 	// 单类型快速路径：仅 1 种精确匹配事件类型时缓存 key+handlers，跳过 map hash+lookup（≈10-16ns/event）
 	// 注意: 通配符模式不能走此路径，必须经过 TrieMatcher
 	if !hasWild && len(handlers) == 1 {
 		for k, hs := range handlers {
 			snap.singleKey = k
 			snap.singleHandlers = hs
+			snap.singleSubs = byPattern[k]
 		}
 	}
 	return snap
 }
 
-// containsWildcard 检查 pattern 是否包含通配符
-func containsWildcard(pattern string) bool {
-	for i := 0; i < len(pattern); i++ {
-		if pattern[i] == '*' {
-			return true
-		}
+// groupInfo 返回订阅所属的组与均衡策略（group.Collapse 回调）
+func groupInfo(s *subscription) (string, core.GroupBalance) {
+	return s.group, s.balance
+}
+
+// groupSlot 为订阅组创建分发槽位：handler 按均衡策略转交给一个成员
+func groupSlot(g *group.Group[*subscription]) *subscription {
+	return &subscription{
+		pattern: g.Members()[0].pattern,
+		group:   g.Name(),
+		grp:     g,
+		handler: func(evt *core.Event) error {
+			return g.Pick(evt.Key).handler(evt)
+		},
 	}
-	return false
 }
 
 // On 订阅事件
 func (p *Bus) On(pattern string, handler core.Handler) uint64 {
+	return p.OnWith(pattern, handler)
+}
+
+// OnWith 带选项订阅事件（实现 core.OptionSubscriber）
+// pattern 语法无效时不订阅并返回 0，错误经 ErrorHandler 上报；需要错误值时用 TryOn。
+func (p *Bus) OnWith(pattern string, handler core.Handler, opts ...core.SubOption) uint64 {
+	id, _ := p.TryOn(pattern, handler, opts...)
+	return id
+}
+
+// TryOn 带选项订阅事件（实现 core.TrySubscriber）
+// pattern 语法无效时不订阅，返回 0 与包装 core.ErrBadPattern 的错误。
+func (p *Bus) TryOn(pattern string, handler core.Handler, opts ...core.SubOption) (uint64, error) {
 	if handler == nil {
-		return 0
+		return 0, nil
+	}
+	o := core.NewSubOptions(opts...)
+	if _, err := p.matcher.Compile(pattern); err != nil {
+		return 0, p.badPattern(err, pattern, &o)
 	}
-
 	id := p.nextID.Add(1)
 	sub := &subscription{
 		id:      id,
 		pattern: pattern,
 		handler: handler,
+		onError: o.ErrorHandler,
+		group:   o.Group,
+		balance: o.GroupBalance,
+	}
+	deliver := func(h core.Handler, evt *core.Event) {
+		if !p.closed.Load() {
+			p.callOut(sub, h, evt)
+		}
 	}
+	sub.ops = opchain.New(p.delay, deliver)
+	sub.handler = ratelimit.Wrap(p.lat.Wrap(pattern, handler), o, &p.subHits, sub.ops)
+	sub.handler = sub.ops.Build(sub.handler, o.Operators)
+	sub.handler, sub.pred = p.preds.Bind(pattern, sub.handler, o)
 
 	p.matcher.Add(pattern)
 
@@ -453,12 +793,65 @@ func (p *Bus) On(pattern string, handler core.Handler) uint64 {
 		copy(newSubs, old.subs)
 		newSubs[len(old.subs)] = sub
 
-		if p.subsPtr.CompareAndSwap(old, buildFlowSnapshot(newSubs)) {
+		if p.subsPtr.CompareAndSwap(old, buildFlowSnapshot(newSubs, p.matcher.Syntax())) {
 			break
 		}
 	}
 
-	return id
+	// 保留事件直接投递给新订阅（不经过 Pipeline 阶段）；订阅组成员不接收
+	if sub.group == "" {
+		for _, evt := range p.ret.Match(pattern) {
+			p.callOut(sub, sub.handler, evt)
+		}
+	}
+	return id, nil
+}
+
+// callOut 在分发路径之外调用订阅的 handler h（保留事件回放、限流合并投递），panic/error 照常上报
+func (p *Bus) callOut(s *subscription, h core.Handler, evt *core.Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			p.notifyPanic(r, evt, s)
+		}
+	}()
+	if err := h(evt); err != nil {
+		p.failed(err, evt, s)
+	}
+}
+
+// EmitAfter d 后发布事件（Emit 语义，实现 core.Delayer）
+func (p *Bus) EmitAfter(d time.Duration, evt *core.Event) core.CancelFunc {
+	return p.delay.Add(time.Now().Add(d), evt)
+}
+
+// EmitAt 在 t 时刻发布事件（Emit 语义，实现 core.Delayer）
+func (p *Bus) EmitAt(t time.Time, evt *core.Event) core.CancelFunc {
+	return p.delay.Add(t, evt)
+}
+
+// EmitRetain 保留事件副本后发布（实现 core.Retainer）
+func (p *Bus) EmitRetain(evt *core.Event) error {
+	if evt == nil || p.closed.Load() {
+		return nil
+	}
+	p.ret.Put(evt)
+	return p.Emit(evt)
+}
+
+// Retained 返回类型匹配 pattern 的保留事件副本（实现 core.Retainer）
+func (p *Bus) Retained(pattern string) []*core.Event {
+	return p.ret.Match(pattern)
+}
+
+// ClearRetained 清除类型匹配 pattern 的保留事件（实现 core.Retainer）
+func (p *Bus) ClearRetained(pattern string) int {
+	return p.ret.Clear(pattern)
+}
+
+// OnGroup 加入竞争消费者订阅组（实现 core.GroupSubscriber）
+// 同一 pattern 下同名组的成员每个事件只有一个执行；成员 Off 后在新快照中重新平衡。
+func (p *Bus) OnGroup(pattern, name string, handler core.Handler, opts ...core.SubOption) uint64 {
+	return p.OnWith(pattern, handler, append([]core.SubOption{core.WithGroup(name)}, opts...)...)
 }
 
 // Off 取消订阅
@@ -478,7 +871,9 @@ func (p *Bus) Off(id uint64) {
 
 				p.matcher.Remove(sub.pattern)
 
-				if p.subsPtr.CompareAndSwap(old, buildFlowSnapshot(newSubs)) {
+				if p.subsPtr.CompareAndSwap(old, buildFlowSnapshot(newSubs, p.matcher.Syntax())) {
+					sub.ops.Stop()
+					sub.pred.Release()
 					return
 				}
 				found = true
@@ -498,6 +893,28 @@ func (p *Bus) Emit(evt *core.Event) error {
 		return nil
 	}
 
+	if ok, err := p.admit(evt); !ok {
+		return err
+	}
+	p.enqueue(evt)
+	return nil
+}
+
+// admit 发布前的去重与限流：返回 false 时事件不发布（err 为限流错误，去重或丢弃时为 nil）
+func (p *Bus) admit(evt *core.Event) (bool, error) {
+	if p.dedup.Duplicate(evt) {
+		return false, nil
+	}
+	if ok, err := p.limits.Admit(evt); !ok {
+		p.dedup.Release(evt)
+		return false, err
+	}
+	return true, nil
+}
+
+// enqueue 将已通过 admit 的事件写入分片 ring
+func (p *Bus) enqueue(evt *core.Event) {
+	p.ret.Offer(evt)
 	p.emitted.Add(1)
 
 	shard := p.getShard(evt.Type)
@@ -511,10 +928,109 @@ func (p *Bus) Emit(evt *core.Event) error {
 	case p.notifyChs[shard] <- struct{}{}:
 	default:
 	}
+}
 
+// EmitCtx 携带 context 发布事件（实现 core.ContextEmitter）
+// Flow 入队从不阻塞（满时降级为同步处理），ctx 仅在发布前检查并随事件传给 Stage/handler。
+func (p *Bus) EmitCtx(ctx context.Context, evt *core.Event) error {
+	if ctx == nil || evt == nil {
+		return p.Emit(evt)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	evt.SetContext(ctx)
+	return p.Emit(evt)
+}
+
+// EmitMatchCtx 同 EmitCtx（Flow 消费侧统一做通配符匹配）
+func (p *Bus) EmitMatchCtx(ctx context.Context, evt *core.Event) error {
+	return p.EmitCtx(ctx, evt)
+}
+
+// EmitAwait 发布事件并返回完成句柄（实现 core.Awaiter）
+// 句柄在事件所在批次处理完毕后完成（含被 Stage 过滤的事件），携带每个 handler 的 error；
+// Stage panic 导致整批丢弃时同样完成并携带对应 error。
+// 未入队的事件（去重丢弃、限流拒绝或丢弃）返回已完成的句柄，限流拒绝时携带对应 error。
+func (p *Bus) EmitAwait(ctx context.Context, evt *core.Event) *core.Completion {
+	if evt == nil {
+		return core.ResolvedCompletion(nil)
+	}
+	if p.closed.Load() {
+		return core.ResolvedCompletion(core.ErrClosed)
+	}
+	if ctx != nil {
+		if err := ctx.Err(); err != nil {
+			return core.ResolvedCompletion(err)
+		}
+		evt.SetContext(ctx)
+	}
+	// 先去重与限流，只有确定入队的事件才挂句柄并计入 awaiting（消费侧完成时递减）
+	if ok, err := p.admit(evt); !ok {
+		return core.ResolvedCompletion(err)
+	}
+	c := core.NewCompletion()
+	evt.SetCompletion(c)
+	p.awaiting.Add(1) // 先于入队，保证消费侧可见
+	p.enqueue(evt)
+	return c
+}
+
+// EmitMatchAwait 同 EmitAwait（Flow 消费侧统一做通配符匹配）
+func (p *Bus) EmitMatchAwait(ctx context.Context, evt *core.Event) *core.Completion {
+	return p.EmitAwait(ctx, evt)
+}
+
+// Barrier 等待调用前已发布的事件全部处理完毕（实现 core.Awaiter）
+// 快照各分片 ring 的 tail，等待 consumer 的累计完成数追上（缓冲满时的同步降级在 Emit 内即已完成）；已关闭时返回 core.ErrClosed。
+func (p *Bus) Barrier(ctx context.Context) error {
+	if p.closed.Load() {
+		return core.ErrClosed
+	}
+	targets := make([]uint64, len(p.buffers))
+	for i, rb := range p.buffers {
+		targets[i] = rb.tail.Load()
+	}
+	backoff := time.Duration(0)
+	for i, rb := range p.buffers {
+		for rb.done.Load() < targets[i] {
+			if p.closed.Load() {
+				return core.ErrClosed
+			}
+			if ctx != nil {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+			// 唤醒该分片 consumer（可能正阻塞在 select 上）
+			select {
+			case p.notifyChs[i] <- struct{}{}:
+			default:
+			}
+			if backoff < time.Millisecond {
+				backoff += 50 * time.Microsecond
+			}
+			time.Sleep(backoff)
+		}
+	}
 	return nil
 }
 
+// Request 发布请求并返回第一个回复（实现 core.Requester）
+// 回复经临时订阅 core.InboxPrefix+ID 路由回来（回复事件同样经过 Pipeline 阶段）。
+func (p *Bus) Request(ctx context.Context, evt *core.Event) (*core.Event, error) {
+	replies, err := core.SendRequest(ctx, p, evt, false, false)
+	if len(replies) == 0 {
+		return nil, err
+	}
+	return replies[0], err
+}
+
+// RequestAll 发布请求并收集全部回复（实现 core.Requester）
+func (p *Bus) RequestAll(ctx context.Context, evt *core.Event) ([]*core.Event, error) {
+	return core.SendRequest(ctx, p, evt, false, true)
+}
+
 // UnsafeEmit 同 Emit（Flow 模式本身即零开销，panic 由 consumer 捕获）
 func (p *Bus) UnsafeEmit(evt *core.Event) error {
 	return p.Emit(evt)
@@ -553,10 +1069,13 @@ func (p *Bus) UnsafeEmitMatch(evt *core.Event) error {
 // EmitBatch 批量发射事件
 // 优化: 单次 atomic 计数整批，避免 N 次 atomic 开销
 // 仅唤醒有数据写入的分片，避免惊群
+// 被限流拒绝的事件从批次中移除，其余照常发布并返回 ErrRateLimited。
 func (p *Bus) EmitBatch(events []*core.Event) error {
 	if len(events) == 0 || p.closed.Load() {
 		return nil
 	}
+	events = p.dedup.Filter(events)
+	events, lerr := p.limits.Filter(events, p.dedup.Release)
 
 	p.emitted.Add(uint64(len(events)))
 
@@ -566,6 +1085,7 @@ func (p *Bus) EmitBatch(events []*core.Event) error {
 		if evt == nil {
 			continue
 		}
+		p.ret.Offer(evt)
 		shard := p.getShard(evt.Type)
 		if !p.buffers[shard].push(evt) {
 			p.emitSlow(evt, shard)
@@ -583,7 +1103,7 @@ func (p *Bus) EmitBatch(events []*core.Event) error {
 		}
 	}
 
-	return nil
+	return lerr
 }
 
 // EmitMatchBatch 批量发射匹配事件
@@ -606,6 +1126,10 @@ func (p *Bus) Close() {
 	if !p.closed.CompareAndSwap(false, true) {
 		return
 	}
+	p.delay.Close()
+	for _, sub := range p.subsPtr.Load().subs {
+		sub.ops.Stop()
+	}
 
 	close(p.done)
 	p.wg.Wait()
@@ -617,14 +1141,17 @@ func (p *Bus) Drain(timeout time.Duration) error {
 		p.Close()
 		return nil
 	}
+	// 先按 DelayPolicy 处理未到期的延迟事件，再排空队列
+	var derr error
 	done := make(chan struct{})
 	go func() {
+		derr = p.delay.Drain()
 		p.Close()
 		close(done)
 	}()
 	select {
 	case <-done:
-		return nil
+		return derr
 	case <-time.After(timeout):
 		return fmt.Errorf("flow: graceful close timed out after %v", timeout)
 	}
@@ -637,12 +1164,50 @@ func (p *Bus) Stats() core.Stats {
 		d := rb.tail.Load() - rb.head.Load()
 		depth += int64(d)
 	}
+	depth += p.delay.Len()
+	byPattern := p.errs.Snapshot()
+	var errs int64
+	for _, n := range byPattern {
+		errs += n
+	}
+	retained, retainedBytes := p.ret.Stats()
+	hits, misses, evictions := p.matcher.CacheStats()
 	return core.Stats{
-		Emitted:   int64(p.emitted.Load()),
-		Processed: int64(p.processed.Load()),
-		Panics:    p.panics.Read(),
-		Depth:     depth,
+		Emitted:             int64(p.emitted.Load()),
+		Processed:           int64(p.processed.Load()),
+		Panics:              p.panics.Read(),
+		Depth:               depth,
+		Duplicates:          p.dedup.Duplicates(),
+		RateLimited:         p.limits.Hits(),
+		HandlerRateLimited:  p.subHits.Read(),
+		Filtered:            p.preds.Filtered(),
+		MatchCacheHits:      hits,
+		MatchCacheMisses:    misses,
+		MatchCacheEvictions: evictions,
+		Errors:              errs,
+		Retained:            retained,
+		RetainedBytes:       retainedBytes,
+		ErrorsByPattern:     byPattern,
+	}
+}
+
+// HandlerLatency 返回各订阅 pattern 的 handler 耗时分布（实现 core.LatencyStatter；未开启时返回 nil）
+func (p *Bus) HandlerLatency() map[string]util.HistogramSnapshot {
+	return p.lat.Snapshot()
+}
+
+// ResetHandlerLatency 清零全部 pattern 的 handler 耗时分布（实现 core.LatencyStatter）
+func (p *Bus) ResetHandlerLatency() {
+	p.lat.Reset()
+}
+
+// QueueStats 返回各分片 ring 的积压与延迟（实现 core.QueueStatter）
+func (p *Bus) QueueStats() []core.QueueStat {
+	stats := make([]core.QueueStat, len(p.buffers))
+	for i, rb := range p.buffers {
+		stats[i] = rb.stat(i)
 	}
+	return stats
 }
 
 // BatchStats 获取批处理统计（processed, batches）
@@ -1,6 +1,8 @@
 package flow
 
 import (
+	"context"
+	"errors"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -237,6 +239,111 @@ func TestFlowMultipleStages(t *testing.T) {
 	}
 }
 
+// TestFlowStageFilter 测试 Stage 过滤/转换后订阅者仅看到缩减批次
+func TestFlowStageFilter(t *testing.T) {
+	keepEven := func(batch []*core.Event) ([]*core.Event, error) {
+		out := batch[:0]
+		for _, e := range batch {
+			if e.Data[0]%2 == 0 {
+				out = append(out, e)
+			}
+		}
+		return out, nil
+	}
+	enrich := func(batch []*core.Event) ([]*core.Event, error) {
+		for _, e := range batch {
+			e.Source = "enriched"
+		}
+		return batch, nil
+	}
+
+	proc := NewWithConfig(&Config{
+		Stages:       []core.Stage{keepEven, enrich},
+		BatchSize:    8,
+		BatchTimeout: 20 * time.Millisecond,
+	})
+	defer proc.Close()
+
+	var received, enriched atomic.Int32
+	proc.On("stage.filter", func(e *core.Event) error {
+		received.Add(1)
+		if e.Source == "enriched" {
+			enriched.Add(1)
+		}
+		return nil
+	})
+
+	for i := 0; i < 20; i++ {
+		proc.Emit(&core.Event{Type: "stage.filter", Data: []byte{byte(i)}})
+	}
+	time.Sleep(150 * time.Millisecond)
+
+	if received.Load() != 10 {
+		t.Errorf("Expected 10 events after filter, got %d", received.Load())
+	}
+	if enriched.Load() != received.Load() {
+		t.Errorf("Expected all delivered events enriched, got %d/%d", enriched.Load(), received.Load())
+	}
+	if processed, _ := proc.BatchStats(); processed != 20 {
+		t.Errorf("Expected 20 consumed events, got %d", processed)
+	}
+}
+
+// TestFlowStageErrorPolicy 测试 Stage 错误策略
+func TestFlowStageErrorPolicy(t *testing.T) {
+	errStage := errors.New("stage failed")
+	failing := func(batch []*core.Event) ([]*core.Event, error) {
+		return nil, errStage
+	}
+
+	cases := []struct {
+		name     string
+		policy   core.StageErrorPolicy
+		wantRecv int32
+		wantErrs int32
+	}{
+		{"skip", core.StageSkipBatch, 0, 0},
+		{"route", core.StageRouteError, 0, 5},
+		{"continue", core.StageContinue, 5, 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var routed atomic.Int32
+			proc := NewWithConfig(&Config{
+				Stages:           []core.Stage{failing},
+				StageErrorPolicy: tc.policy,
+				StageErrorHandler: func(err error, batch []*core.Event) {
+					if errors.Is(err, errStage) {
+						routed.Add(int32(len(batch)))
+					}
+				},
+				BatchSize:    10,
+				BatchTimeout: 20 * time.Millisecond,
+			})
+			defer proc.Close()
+
+			var received atomic.Int32
+			proc.On("stage.err", func(e *core.Event) error {
+				received.Add(1)
+				return nil
+			})
+
+			for i := 0; i < 5; i++ {
+				proc.Emit(&core.Event{Type: "stage.err", Data: []byte{byte(i)}})
+			}
+			time.Sleep(100 * time.Millisecond)
+
+			if received.Load() != tc.wantRecv {
+				t.Errorf("received = %d, want %d", received.Load(), tc.wantRecv)
+			}
+			if routed.Load() != tc.wantErrs {
+				t.Errorf("routed = %d, want %d", routed.Load(), tc.wantErrs)
+			}
+		})
+	}
+}
+
 // TestFlowEmitBatch 测试批量发送
 func TestFlowEmitBatch(t *testing.T) {
 	var count atomic.Int32
@@ -319,6 +426,42 @@ func TestFlowClose(t *testing.T) {
 	proc.Close()
 }
 
+// TestFlowEmitAwaitNotQueued 被去重或限流挡下的 EmitAwait 句柄立即完成（限流拒绝携带 error），且不计入 awaiting
+func TestFlowEmitAwaitNotQueued(t *testing.T) {
+	proc := NewWithConfig(&Config{
+		BatchSize:    10,
+		BatchTimeout: 10 * time.Millisecond,
+		DedupWindow:  time.Minute,
+		RateLimits: []core.RateLimit{
+			{Pattern: "rejected", Rate: 0.01, Burst: 1, Policy: core.RateReject},
+			{Pattern: "dropped", Rate: 0.01, Burst: 1, Policy: core.RateDrop},
+		},
+	})
+	defer proc.Close()
+	proc.On("*", func(*core.Event) error { return nil })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, c := range []struct {
+		evt  *core.Event
+		want error
+	}{
+		{&core.Event{Type: "rejected"}, nil},
+		{&core.Event{Type: "rejected"}, core.ErrRateLimited},
+		{&core.Event{Type: "dropped"}, nil},
+		{&core.Event{Type: "dropped"}, nil},
+		{&core.Event{Type: "job", ID: "j-1"}, nil},
+		{&core.Event{Type: "job", ID: "j-1"}, nil},
+	} {
+		if err := proc.EmitAwait(ctx, c.evt).Wait(ctx); !errors.Is(err, c.want) {
+			t.Errorf("EmitAwait(%s %q) = %v, want %v", c.evt.Type, c.evt.ID, err, c.want)
+		}
+	}
+	if n := proc.awaiting.Load(); n != 0 {
+		t.Errorf("awaiting = %d after all completions, want 0", n)
+	}
+}
+
 // TestFlowConcurrent 测试并发安全
 func TestFlowConcurrent(t *testing.T) {
 	var count atomic.Int32
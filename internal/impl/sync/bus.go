@@ -2,8 +2,11 @@
 package sync
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"runtime"
+	"sort"
 	stdsync "sync"
 	"sync/atomic"
 	"time"
@@ -11,42 +14,198 @@ import (
 	"github.com/uniyakcom/beat/core"
 	"github.com/uniyakcom/beat/util"
 
+	"github.com/uniyakcom/beat/internal/support/dedup"
+	"github.com/uniyakcom/beat/internal/support/group"
+	"github.com/uniyakcom/beat/internal/support/latency"
+	"github.com/uniyakcom/beat/internal/support/opchain"
+	"github.com/uniyakcom/beat/internal/support/predicate"
+	"github.com/uniyakcom/beat/internal/support/ratelimit"
+	"github.com/uniyakcom/beat/internal/support/retain"
 	"github.com/uniyakcom/beat/internal/support/sched"
+	"github.com/uniyakcom/beat/internal/support/wheel"
 )
 
 // subsSnapshot CoW 快照 — 双层结构
-//   - byID: On/Off 管理路径（含 sub.ID 用于删除）
+//   - byID: On/Off 管理路径（含 sub.ID 用于删除，按注册顺序）
+//   - slots: 分发槽位（订阅组成员折叠为一个组槽位，按优先级稳定排序；无组且无优先级时与 byID 共享切片）
 //   - handlers: Emit 热路径（预扁平化 []core.Handler，消除 *sub 间接访问）
 //   - singleKey/singleHandlers: 单事件类型快速路径（跳过 map hash+lookup）
+//
+// slots[k][i] 与 handlers[k][i] 一一对应，panic/error 上报时据此定位订阅。
 type subsSnapshot struct {
 	byID           map[string][]*sub
+	slots          map[string][]*sub
 	handlers       map[string][]core.Handler
 	singleKey      string
 	singleHandlers []core.Handler
+	singleSubs     []*sub
+}
+
+// lookup 返回 key 对应的 handler 列表及其订阅（单类型快速路径跳过 map lookup）
+func (s *subsSnapshot) lookup(key string) ([]core.Handler, []*sub) {
+	if s.singleKey == key {
+		return s.singleHandlers, s.singleSubs
+	}
+	return s.handlers[key], s.slots[key]
 }
 
-// buildSnapshot 从 byID 构建完整快照（On/Off 时调用，非热路径）
-func buildSnapshot(byID map[string][]*sub) *subsSnapshot {
+// buildSnapshot 由 old 派生新快照（On/Off 时调用，非热路径）
+// 只重建 key 的槽位与 handler 列表（subs 为空表示 key 已无订阅），其余 key 沿用 old 中的不可变切片。
+func buildSnapshot(old *subsSnapshot, key string, subs []*sub) *subsSnapshot {
 	snap := &subsSnapshot{
-		byID:     byID,
-		handlers: make(map[string][]core.Handler, len(byID)),
+		byID:     make(map[string][]*sub, len(old.byID)+1),
+		slots:    make(map[string][]*sub, len(old.byID)+1),
+		handlers: make(map[string][]core.Handler, len(old.byID)+1),
 	}
-	for k, subs := range byID {
-		hs := make([]core.Handler, len(subs))
-		for i, s := range subs {
-			hs[i] = s.handler
+	for k, v := range old.byID {
+		if k != key {
+			snap.byID[k] = v
+			snap.slots[k] = old.slots[k]
+			snap.handlers[k] = old.handlers[k]
 		}
-		snap.handlers[k] = hs
 	}
-	if len(byID) == 1 {
+	if len(subs) > 0 {
+		snap.byID[key] = subs
+		snap.slots[key], snap.handlers[key] = buildSlots(subs)
+	}
+	if len(snap.byID) == 1 {
 		for k, hs := range snap.handlers {
 			snap.singleKey = k
 			snap.singleHandlers = hs
+			snap.singleSubs = snap.slots[k]
 		}
 	}
 	return snap
 }
 
+// buildSlots 构建一个 key 的分发槽位及其扁平化 handler 列表
+// 常见情形（无订阅组且已按优先级有序）一次遍历完成，槽位与 subs 共享切片；否则折叠订阅组并排序。
+func buildSlots(subs []*sub) ([]*sub, []core.Handler) {
+	hs := make([]core.Handler, len(subs))
+	for i, s := range subs {
+		if s.group != "" || (i > 0 && s.priority > subs[i-1].priority) {
+			slots := prioritize(group.Collapse(subs, groupInfo, groupSlot))
+			hs = hs[:len(slots)]
+			for j, s := range slots {
+				hs[j] = s.handler
+			}
+			return slots, hs
+		}
+		hs[i] = s.handler
+	}
+	return subs, hs
+}
+
+// prioritize 按优先级降序稳定排序槽位（相同优先级保持注册顺序）
+// 已有序（含全部为默认优先级）时原样返回；否则复制后排序，不修改 byID 中共享的切片。
+func prioritize(slots []*sub) []*sub {
+	if sort.SliceIsSorted(slots, func(i, j int) bool { return slots[i].priority > slots[j].priority }) {
+		return slots
+	}
+	out := append([]*sub(nil), slots...)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].priority > out[j].priority })
+	return out
+}
+
+// groupInfo 返回订阅所属的组与均衡策略（group.Collapse 回调）
+func groupInfo(s *sub) (string, core.GroupBalance) {
+	return s.group, s.balance
+}
+
+// groupSlot 为订阅组创建分发槽位：handler 按均衡策略转交给一个成员（Unsafe 路径直接调用）
+// 槽位的排序位置（id、priority）取组内最早加入的成员。
+func groupSlot(g *group.Group[*sub]) *sub {
+	first := g.Members()[0]
+	return &sub{
+		id:       first.id,
+		priority: first.priority,
+		pattern:  first.pattern,
+		group:    g.Name(),
+		grp:      g,
+		handler: func(evt *core.Event) error {
+			return g.Pick(evt.Key).handler(evt)
+		},
+	}
+}
+
+// pick 返回本次调用实际执行的订阅与 handler（组槽位按均衡策略选出一个成员）
+func (s *sub) pick(evt *core.Event, h core.Handler) (*sub, core.Handler) {
+	if s.grp == nil {
+		return s, h
+	}
+	m := s.grp.Pick(evt.Key)
+	return m, m.handler
+}
+
+// chainCap 合并链在栈上容纳的 pattern 数（超出时预先归并到堆上的单个列表）
+const chainCap = 8
+
+// chain 通配符分发的合并调用链
+// 命中同一事件的各 pattern（精确与通配符）的槽位按 (优先级降序, 注册顺序) 归并为一条链，
+// 使 user.** 上的优先级与 user.created 上的 handler 相互比较。调用方栈上分配，零堆分配。
+// 槽位的 handler 即 slots 对应的扁平化 handler（组槽位为转交闭包）。
+type chain struct {
+	n    int
+	pos  [chainCap]int
+	subs [chainCap][]*sub
+}
+
+// init 以 snap 中 patterns 对应的槽位初始化合并链
+func (c *chain) init(snap *subsSnapshot, patterns []string) {
+	c.n = 0
+	if len(patterns) > chainCap {
+		var subs []*sub
+		for _, p := range patterns {
+			subs = append(subs, snap.slots[p]...)
+		}
+		sort.SliceStable(subs, func(i, j int) bool { return subs[i].before(subs[j]) })
+		c.subs[0], c.pos[0], c.n = subs, 0, 1
+		return
+	}
+	for _, p := range patterns {
+		if subs := snap.slots[p]; len(subs) > 0 {
+			c.subs[c.n], c.pos[c.n] = subs, 0
+			c.n++
+		}
+	}
+}
+
+// one 以单个已排序的槽位列表初始化（精确匹配路径）
+func (c *chain) one(subs []*sub) {
+	c.subs[0], c.pos[0], c.n = subs, 0, 1
+}
+
+// next 返回链上下一个槽位（nil 表示已遍历完）
+// 游标先于调用前推进，handler panic 后从下一个槽位继续。
+func (c *chain) next() *sub {
+	best := 0
+	if c.n != 1 {
+		best = -1
+		for j := 0; j < c.n; j++ {
+			if c.pos[j] == len(c.subs[j]) {
+				continue
+			}
+			if best < 0 || c.subs[j][c.pos[j]].before(c.subs[best][c.pos[best]]) {
+				best = j
+			}
+		}
+		if best < 0 {
+			return nil
+		}
+	}
+	i := c.pos[best]
+	if i == len(c.subs[best]) {
+		return nil
+	}
+	c.pos[best]++
+	return c.subs[best][i]
+}
+
+// stopped 判断 handler error 是否为 core.ErrStopPropagation（结束分发，不作为错误上报）
+func stopped(err error) bool {
+	return errors.Is(err, core.ErrStopPropagation)
+}
+
 // Bus 同步事件总线（字段按访问频率+大小对齐排列）
 // Reader 热路径字段在前（Emit读取），Writer 冷路径字段在后（On/Off写入）
 type Bus struct {
@@ -55,7 +214,8 @@ type Bus struct {
 	subs    atomic.Pointer[subsSnapshot] // 8B — CoW快照（含扁平化 handlers + singleKey）
 	closed  atomic.Bool                  // 1B
 	async   bool                         // 1B
-	_       [6]byte                      // padding到cache line
+	runAll  bool                         // 1B — core.ErrorRunAll（同步模式汇总全部 handler 失败）
+	_       [5]byte                      // padding到cache line
 
 	// === sync 热路径独立缓存行 ===
 	// syncCnt 已移除 — Emit 热路径不再有计数开销
@@ -81,6 +241,30 @@ type Bus struct {
 	emitted   *util.PerCPUCounter
 	processed *util.PerCPUCounter
 	panics    *util.PerCPUCounter
+
+	// === panic/错误回调（冷路径，仅 panic/error 时读取） ===
+	onPanic atomic.Pointer[core.PanicInfoHandler]
+	onError atomic.Pointer[core.ErrorHandler]
+	errs    util.KeyedCounter // pattern → handler error 次数
+
+	// === 保留事件（sticky）===
+	ret *retain.Store
+
+	// === 延迟发布（时间轮）===
+	delay *wheel.Wheel
+
+	// === 按 Event.ID 去重（nil=未开启）===
+	dedup *dedup.Set
+
+	// === 限流（发布侧 nil=未配置规则；订阅侧命中计数）===
+	limits  *ratelimit.Limits
+	subHits ratelimit.Hits
+
+	// === 负载谓词（WithWhere）===
+	preds predicate.Registry
+
+	// === handler 耗时直方图（nil=未开启）===
+	lat *latency.Recorder
 }
 
 // dispatchAsync SPSC 消费端分发 — 替代 asyncTask
@@ -88,24 +272,188 @@ type Bus struct {
 // 优化: RCU 快照 + 预扁平化 handler + 单类型快速路径
 func (e *Bus) dispatchAsync(evt *core.Event) {
 	if e.closed.Load() {
+		if c := evt.Completion(); c != nil {
+			c.AddError(core.ErrClosed)
+			c.Resolve()
+		}
 		return
 	}
-	snap := e.subs.Load()
-	// 快速路径: 仅 1 种事件类型时跳过 map hash+lookup
-	if snap.singleKey == evt.Type {
-		for _, h := range snap.singleHandlers {
-			if err := h(evt); err != nil {
-				e.reportError(err)
+	// 快速路径: 仅 1 种事件类型时跳过 map hash+lookup（lookup 内联）
+	hs, subs := e.subs.Load().lookup(evt.Type)
+	for i := 0; i < len(hs); {
+		i = e.invokeFrom(evt, hs, subs, i)
+	}
+	e.processed.Add(1)
+	if c := evt.Completion(); c != nil {
+		c.Resolve()
+	}
+}
+
+// invokeFrom 从第 i 个 handler 开始依次调用，错误上报到 errChan 与错误回调
+// handler panic 时上报并返回下一个索引，保证同一事件的其余 handler 继续执行。
+func (e *Bus) invokeFrom(evt *core.Event, hs []core.Handler, subs []*sub, i int) (next int) {
+	var cur *sub
+	defer func() {
+		if r := recover(); r != nil {
+			e.reportError(e.recovered(r, evt, cur))
+			next = i + 1
+		}
+	}()
+	for ; i < len(hs); i++ {
+		var h core.Handler
+		cur, h = subs[i].pick(evt, hs[i])
+		if err := h(evt); err != nil {
+			if stopped(err) {
+				return len(hs)
 			}
+			e.failed(err, evt, cur)
+			e.reportError(err)
 		}
-	} else {
-		for _, h := range snap.handlers[evt.Type] {
-			if err := h(evt); err != nil {
-				e.reportError(err)
+	}
+	return i
+}
+
+// invokeChain 依次调用合并链上的 handler（语义同 invokeFrom）
+// 返回 false 表示 handler panic 已上报而链尚未遍历完，调用方应再次调用以继续。
+func (e *Bus) invokeChain(evt *core.Event, c *chain) (done bool) {
+	var cur *sub
+	defer func() {
+		if r := recover(); r != nil {
+			e.reportError(e.recovered(r, evt, cur))
+		}
+	}()
+	for {
+		s := c.next()
+		if s == nil {
+			return true
+		}
+		var h core.Handler
+		cur, h = s.pick(evt, s.handler)
+		if err := h(evt); err != nil {
+			if stopped(err) {
+				return true
 			}
+			e.failed(err, evt, cur)
+			e.reportError(err)
 		}
 	}
-	e.processed.Add(1)
+}
+
+// invokeAll 依次调用合并链上的 handler，失败记入 failures 后继续（ErrorRunAll）
+// 返回 done=false 表示 handler panic 已记录而链尚未遍历完，调用方应再次调用以继续；
+// ctx 结束时返回 ctx.Err()，handler 返回 core.ErrStopPropagation 时结束且不记录。
+func (e *Bus) invokeAll(ctx context.Context, evt *core.Event, c *chain, failures *[]*core.HandlerError) (done bool, err error) {
+	var cur *sub
+	defer func() {
+		if r := recover(); r != nil {
+			perr := e.recovered(r, evt, cur)
+			*failures = append(*failures, &core.HandlerError{Sub: cur.info(), Event: evt, Err: perr, Panic: true})
+		}
+	}()
+	for {
+		if ctx != nil {
+			if err := ctx.Err(); err != nil {
+				return true, err
+			}
+		}
+		s := c.next()
+		if s == nil {
+			return true, nil
+		}
+		var h core.Handler
+		cur, h = s.pick(evt, s.handler)
+		if err := h(evt); err != nil {
+			if stopped(err) {
+				return true, nil
+			}
+			e.failed(err, evt, cur)
+			*failures = append(*failures, &core.HandlerError{Sub: cur.info(), Event: evt, Err: err})
+		}
+	}
+}
+
+// runChain 以 ErrorRunAll 方式分发一个事件，返回 ctx 错误（失败记入 failures）
+func (e *Bus) runChain(ctx context.Context, evt *core.Event, c *chain, failures *[]*core.HandlerError) error {
+	for {
+		if done, err := e.invokeAll(ctx, evt, c, failures); done {
+			return err
+		}
+	}
+}
+
+// aggregate 将 ErrorRunAll 收集的失败汇总为 *core.EmitError（无失败时返回 err）
+func aggregate(failures []*core.HandlerError, err error) error {
+	if len(failures) == 0 {
+		return err
+	}
+	agg := &core.EmitError{Failures: failures}
+	if err != nil {
+		return errors.Join(agg, err)
+	}
+	return agg
+}
+
+// recovered 处理已捕获的 handler panic：计数 + 回调通知，返回对应 error
+func (e *Bus) recovered(r interface{}, evt *core.Event, s *sub) error {
+	e.panics.Add(1)
+	if h := e.onPanic.Load(); h != nil {
+		(*h)(r, evt, s.info())
+	}
+	err := fmt.Errorf("handler panic: %v", r)
+	if c := evt.Completion(); c != nil {
+		c.AddError(err)
+	}
+	return err
+}
+
+// SetPanicHandler 注册 panic 回调（实现 core.PanicNotifier）
+func (e *Bus) SetPanicHandler(h core.PanicHandler) {
+	e.SetPanicInfoHandler(core.PanicInfo(h))
+}
+
+// SetPanicInfoHandler 注册带订阅信息的 panic 回调（实现 core.PanicNotifier）
+func (e *Bus) SetPanicInfoHandler(h core.PanicInfoHandler) {
+	if h == nil {
+		e.onPanic.Store(nil)
+		return
+	}
+	e.onPanic.Store(&h)
+}
+
+// failed 处理 handler 返回的 error：按模式计数 + 订阅级/Bus 级回调
+func (e *Bus) failed(err error, evt *core.Event, s *sub) {
+	e.errs.Add(s.pattern, 1)
+	if c := evt.Completion(); c != nil {
+		c.AddError(err)
+	}
+	info := s.info()
+	if s.onError != nil {
+		s.onError(err, evt, info)
+	}
+	if h := e.onError.Load(); h != nil {
+		(*h)(err, evt, info)
+	}
+}
+
+// badPattern 上报语法无效的订阅 pattern（evt 为 nil，sub 仅含 Pattern），返回原错误
+func (e *Bus) badPattern(err error, pattern string, o *core.SubOptions) error {
+	info := core.SubInfo{Pattern: pattern}
+	if o.ErrorHandler != nil {
+		o.ErrorHandler(err, nil, info)
+	}
+	if h := e.onError.Load(); h != nil {
+		(*h)(err, nil, info)
+	}
+	return err
+}
+
+// SetErrorHandler 注册错误回调（实现 core.ErrorNotifier）
+func (e *Bus) SetErrorHandler(h core.ErrorHandler) {
+	if h == nil {
+		e.onError.Store(nil)
+		return
+	}
+	e.onError.Store(&h)
 }
 
 // reportError 上报错误到 errChan（非阻塞，满时写 lastErr）
@@ -121,9 +469,32 @@ func (e *Bus) reportError(err error) {
 
 // sub 订阅者
 type sub struct {
-	pattern string
-	handler core.Handler
-	id      uint64
+	pattern  string
+	handler  core.Handler
+	id       uint64
+	priority int               // 调用优先级（越大越先调用）
+	onError  core.ErrorHandler // 订阅级错误回调（可为 nil）
+
+	// 订阅组（OnGroup）：成员记录组名与策略；组槽位额外持有 grp（仅出现在 slots 中）
+	group   string
+	balance core.GroupBalance
+	grp     *group.Group[*sub]
+
+	ops  *opchain.Chain     // 定时投递环境（算子、限流合并投递；Off 时 Stop）
+	pred *predicate.Binding // 负载谓词（nil=未挂载）
+}
+
+// before 合并链排序：优先级高者在前，相同优先级先注册者在前
+func (s *sub) before(o *sub) bool {
+	if s.priority != o.priority {
+		return s.priority > o.priority
+	}
+	return s.id < o.id
+}
+
+// info 返回上报给回调的订阅信息
+func (s *sub) info() core.SubInfo {
+	return core.SubInfo{ID: s.id, Pattern: s.pattern, Group: s.group}
 }
 
 var subID atomic.Uint64
@@ -133,11 +504,13 @@ func NewBus() *Bus {
 	emitter := &Bus{
 		matcher:   core.NewTrieMatcher(),
 		async:     false,
+		ret:       retain.New(nil, core.DotSyntax),
 		emitted:   util.NewPerCPUCounter(),
 		processed: util.NewPerCPUCounter(),
 		panics:    util.NewPerCPUCounter(),
 	}
-	emitter.subs.Store(buildSnapshot(make(map[string][]*sub)))
+	emitter.subs.Store(buildSnapshot(&subsSnapshot{}, "", nil))
+	emitter.delay = wheel.New(emitter.Emit, core.DelayFire)
 	emitter.pool = stdsync.Pool{
 		New: func() interface{} {
 			return &core.Event{}
@@ -175,13 +548,15 @@ func NewAsync(poolSize int) (*Bus, error) {
 	emitter := &Bus{
 		matcher:   core.NewTrieMatcher(),
 		async:     true,
+		ret:       retain.New(nil, core.DotSyntax),
 		errChan:   make(chan error, 1024),
 		errDone:   make(chan struct{}),
 		emitted:   util.NewPerCPUCounter(),
 		processed: util.NewPerCPUCounter(),
 		panics:    util.NewPerCPUCounter(),
 	}
-	emitter.subs.Store(buildSnapshot(make(map[string][]*sub)))
+	emitter.subs.Store(buildSnapshot(&subsSnapshot{}, "", nil))
+	emitter.delay = wheel.New(emitter.Emit, core.DelayFire)
 	emitter.pool = stdsync.Pool{
 		New: func() interface{} { return &core.Event{} },
 	}
@@ -205,26 +580,103 @@ func NewAsync(poolSize int) (*Bus, error) {
 
 // On 订阅事件 - 使用CoW（Copy-on-Write）机制
 func (e *Bus) On(pattern string, handler core.Handler) uint64 {
+	return e.OnWith(pattern, handler)
+}
+
+// OnWith 带选项订阅事件（实现 core.OptionSubscriber）
+// pattern 语法无效时不订阅并返回 0，错误经 ErrorHandler 上报；需要错误值时用 TryOn。
+func (e *Bus) OnWith(pattern string, handler core.Handler, opts ...core.SubOption) uint64 {
+	id, _ := e.TryOn(pattern, handler, opts...)
+	return id
+}
+
+// TryOn 带选项订阅事件（实现 core.TrySubscriber）
+// pattern 语法无效时不订阅，返回 0 与包装 core.ErrBadPattern 的错误。
+func (e *Bus) TryOn(pattern string, handler core.Handler, opts ...core.SubOption) (uint64, error) {
+	o := core.NewSubOptions(opts...)
+	if _, err := e.matcher.Compile(pattern); err != nil {
+		return 0, e.badPattern(err, pattern, &o)
+	}
 	id := subID.Add(1)
 	s := &sub{
-		id:      id,
-		pattern: pattern,
-		handler: handler,
+		id:       id,
+		pattern:  pattern,
+		handler:  handler,
+		priority: o.Priority,
+		onError:  o.ErrorHandler,
+		group:    o.Group,
+		balance:  o.GroupBalance,
+	}
+	deliver := func(h core.Handler, evt *core.Event) {
+		if !e.closed.Load() {
+			e.callOut(s, h, evt)
+		}
 	}
+	s.ops = opchain.New(e.delay, deliver)
+	s.handler = ratelimit.Wrap(e.lat.Wrap(pattern, handler), o, &e.subHits, s.ops)
+	s.handler = s.ops.Build(s.handler, o.Operators)
+	s.handler, s.pred = e.preds.Bind(pattern, s.handler, o)
 
 	e.mu.Lock()
-	defer e.mu.Unlock()
-
 	old := e.subs.Load()
-	newByID := make(map[string][]*sub, len(old.byID)+1)
-	for k, v := range old.byID {
-		newByID[k] = v
-	}
-	newByID[pattern] = append(newByID[pattern], s)
-	e.subs.Store(buildSnapshot(newByID))
+	e.subs.Store(buildSnapshot(old, pattern, append(old.byID[pattern], s)))
 	e.matcher.Add(pattern)
+	e.mu.Unlock()
 
-	return id
+	// 保留事件在锁外投递（handler 内可再次 On/Off）；订阅组成员不接收
+	if s.group == "" {
+		for _, evt := range e.ret.Match(pattern) {
+			e.callOut(s, s.handler, evt)
+		}
+	}
+	return id, nil
+}
+
+// callOut 在分发路径之外调用订阅的 handler h（保留事件回放、限流合并投递），panic/error 照常上报
+func (e *Bus) callOut(s *sub, h core.Handler, evt *core.Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			e.recovered(r, evt, s)
+		}
+	}()
+	if err := h(evt); err != nil && !stopped(err) {
+		e.failed(err, evt, s)
+	}
+}
+
+// EmitRetain 保留事件副本后发布（实现 core.Retainer）
+func (e *Bus) EmitRetain(evt *core.Event) error {
+	if evt == nil {
+		return nil
+	}
+	e.ret.Put(evt)
+	return e.Emit(evt)
+}
+
+// Retained 返回类型匹配 pattern 的保留事件副本（实现 core.Retainer）
+func (e *Bus) Retained(pattern string) []*core.Event {
+	return e.ret.Match(pattern)
+}
+
+// ClearRetained 清除类型匹配 pattern 的保留事件（实现 core.Retainer）
+func (e *Bus) ClearRetained(pattern string) int {
+	return e.ret.Clear(pattern)
+}
+
+// EmitAfter d 后发布事件（Emit 语义，实现 core.Delayer）
+func (e *Bus) EmitAfter(d time.Duration, evt *core.Event) core.CancelFunc {
+	return e.delay.Add(time.Now().Add(d), evt)
+}
+
+// EmitAt 在 t 时刻发布事件（Emit 语义，实现 core.Delayer）
+func (e *Bus) EmitAt(t time.Time, evt *core.Event) core.CancelFunc {
+	return e.delay.Add(t, evt)
+}
+
+// OnGroup 加入竞争消费者订阅组（实现 core.GroupSubscriber）
+// 同一 pattern 下同名组的成员每个事件只有一个执行；成员 Off 后在新快照中重新平衡。
+func (e *Bus) OnGroup(pattern, name string, handler core.Handler, opts ...core.SubOption) uint64 {
+	return e.OnWith(pattern, handler, append([]core.SubOption{core.WithGroup(name)}, opts...)...)
 }
 
 // Off 取消订阅 - 使用CoW（Copy-on-Write）机制
@@ -233,24 +685,25 @@ func (e *Bus) Off(id uint64) {
 	defer e.mu.Unlock()
 
 	old := e.subs.Load()
-	newByID := make(map[string][]*sub, len(old.byID))
 	for k, subs := range old.byID {
-		filtered := make([]*sub, 0, len(subs))
-		for _, s := range subs {
+		for i, s := range subs {
 			if s.id != id {
-				filtered = append(filtered, s)
+				continue
 			}
-		}
-		if len(filtered) > 0 {
-			newByID[k] = filtered
-		} else {
-			// 安全: Remove 次数等于该 pattern 的 Add 次数（refCount 匹配）
-			for range subs {
-				e.matcher.Remove(k)
+			s.ops.Stop()
+			s.pred.Release()
+			filtered := make([]*sub, 0, len(subs)-1)
+			filtered = append(append(filtered, subs[:i]...), subs[i+1:]...)
+			if len(filtered) == 0 {
+				// 安全: Remove 次数等于该 pattern 的 Add 次数（refCount 匹配）
+				for range subs {
+					e.matcher.Remove(k)
+				}
 			}
+			e.subs.Store(buildSnapshot(old, k, filtered))
+			return
 		}
 	}
-	e.subs.Store(buildSnapshot(newByID))
 }
 
 // UnsafeEmit 发布事件 — 零保护极致性能路径
@@ -265,35 +718,45 @@ func (e *Bus) UnsafeEmit(evt *core.Event) error {
 	if snap.singleKey == evt.Type {
 		for _, h := range snap.singleHandlers {
 			if err := h(evt); err != nil {
-				return err
+				return unstopped(err)
 			}
 		}
 		return nil
 	}
 	for _, h := range snap.handlers[evt.Type] {
 		if err := h(evt); err != nil {
-			return err
+			return unstopped(err)
 		}
 	}
 	return nil
 }
 
+// unstopped 将 core.ErrStopPropagation 转为 nil（Unsafe 路径的返回值）
+func unstopped(err error) error {
+	if stopped(err) {
+		return nil
+	}
+	return err
+}
+
 // UnsafeEmitMatch 通配符匹配发布 — 零保护极致性能路径
 //
 //go:nosplit
 func (e *Bus) UnsafeEmitMatch(evt *core.Event) error {
 	snap := e.subs.Load()
 	patterns := e.matcher.Match(evt.Type)
-	for _, pattern := range *patterns {
-		for _, h := range snap.handlers[pattern] {
-			if err := h(evt); err != nil {
-				e.matcher.Put(patterns)
-				return err
-			}
+	var c chain
+	c.init(snap, *patterns)
+	e.matcher.Put(patterns)
+	for {
+		s := c.next()
+		if s == nil {
+			return nil
+		}
+		if err := s.handler(evt); err != nil {
+			return unstopped(err)
 		}
 	}
-	e.matcher.Put(patterns)
-	return nil
 }
 
 // Emit 发布事件 — 带 panic 保护的安全路径
@@ -303,40 +766,184 @@ func (e *Bus) Emit(evt *core.Event) error {
 	if evt == nil {
 		return nil
 	}
+	if e.dedup.Duplicate(evt) {
+		return nil
+	}
+	if ok, err := e.limits.Admit(evt); !ok {
+		e.dedup.Release(evt)
+		return err
+	}
 	if e.async {
-		return e.emitAsync(evt)
+		return e.emitAsync(nil, evt)
 	}
-	return e.emitSyncSafe(evt)
+	e.ret.Offer(evt)
+	return e.emitSyncSafe(nil, evt)
+}
+
+// EmitCtx 携带 context 发布事件（实现 core.ContextEmitter）
+// 同步模式: 每个 handler 调用前检查 ctx，结束后返回 ctx.Err() 且不再调用剩余 handler。
+// 异步模式: ring 满需要等待时，ctx 结束即放弃入队并返回 ctx.Err()。
+func (e *Bus) EmitCtx(ctx context.Context, evt *core.Event) error {
+	if evt == nil {
+		return nil
+	}
+	if ctx == nil {
+		return e.Emit(evt)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	evt.SetContext(ctx)
+	if e.dedup.Duplicate(evt) {
+		return nil
+	}
+	if ok, err := e.limits.Admit(evt); !ok {
+		e.dedup.Release(evt)
+		return err
+	}
+	if e.async {
+		return e.emitAsync(ctx, evt)
+	}
+	e.ret.Offer(evt)
+	return e.emitSyncSafe(ctx, evt)
+}
+
+// EmitAwait 发布事件并返回完成句柄（实现 core.Awaiter）
+// 同步模式: 返回时 handler 已执行完毕，句柄已完成（携带首个 error，语义同 Emit）。
+// 异步模式: worker 执行完全部 handler 后完成，句柄携带每个 handler 的 error；
+// 入队失败或被溢出策略丢弃时以对应 error 完成。
+func (e *Bus) EmitAwait(ctx context.Context, evt *core.Event) *core.Completion {
+	if evt == nil || !e.async {
+		return core.ResolvedCompletion(e.EmitCtx(ctx, evt))
+	}
+	if e.closed.Load() {
+		return core.ResolvedCompletion(core.ErrClosed)
+	}
+	c := core.NewCompletion()
+	evt.SetCompletion(c)
+	if err := e.EmitCtx(ctx, evt); err != nil {
+		// 未入队: 事件仍归调用方所有
+		evt.SetCompletion(nil)
+		c.AddError(err)
+		c.Resolve()
+	}
+	return c
+}
+
+// EmitMatchAwait 通配符发布并返回完成句柄（实现 core.Awaiter）
+// EmitMatch 在两种模式下均为同步分发，返回时句柄已完成。
+func (e *Bus) EmitMatchAwait(ctx context.Context, evt *core.Event) *core.Completion {
+	return core.ResolvedCompletion(e.EmitMatchCtx(ctx, evt))
+}
+
+// Barrier 等待调用前已发布的事件全部处理完毕（实现 core.Awaiter）
+// 同步模式下 Emit 返回即处理完毕，直接返回 nil；已关闭时返回 core.ErrClosed。
+func (e *Bus) Barrier(ctx context.Context) error {
+	if e.closed.Load() {
+		return core.ErrClosed
+	}
+	if !e.async {
+		return nil
+	}
+	return e.spsc.Barrier(ctx)
+}
+
+// HandlerLatency 返回各订阅 pattern 的 handler 耗时分布（实现 core.LatencyStatter；未开启时返回 nil）
+func (e *Bus) HandlerLatency() map[string]util.HistogramSnapshot {
+	return e.lat.Snapshot()
+}
+
+// ResetHandlerLatency 清零全部 pattern 的 handler 耗时分布（实现 core.LatencyStatter）
+func (e *Bus) ResetHandlerLatency() {
+	e.lat.Reset()
+}
+
+// QueueStats 返回各 SPSC ring 的积压与延迟（实现 core.QueueStatter）
+// 同步模式无队列，返回 nil。
+func (e *Bus) QueueStats() []core.QueueStat {
+	if e.spsc == nil {
+		return nil
+	}
+	return e.spsc.QueueStats()
+}
+
+// Request 发布请求并返回第一个回复（实现 core.Requester）
+// 同步模式: handler 在 Emit 内执行，回复直接返回；异步模式: 回复经临时订阅 core.InboxPrefix+ID 路由回来。
+func (e *Bus) Request(ctx context.Context, evt *core.Event) (*core.Event, error) {
+	replies, err := core.SendRequest(ctx, e, evt, !e.async, false)
+	if len(replies) == 0 {
+		return nil, err
+	}
+	return replies[0], err
+}
+
+// RequestAll 发布请求并收集全部回复（实现 core.Requester）
+func (e *Bus) RequestAll(ctx context.Context, evt *core.Event) ([]*core.Event, error) {
+	return core.SendRequest(ctx, e, evt, !e.async, true)
 }
 
 // emitSyncSafe 同步安全路径 — defer recover 隔离在独立函数中
 // 将 defer 限制在最小作用域，减少非 panic 路径的固定开销
+// 当前订阅 cur 由 defer 读取，用于定位 panic 的订阅（栈变量，零分配）
+// ctx 非 nil 时每个 handler 调用前检查是否已结束（EmitCtx 路径）
 //
 //go:noinline
-func (e *Bus) emitSyncSafe(evt *core.Event) (retErr error) {
+func (e *Bus) emitSyncSafe(ctx context.Context, evt *core.Event) (retErr error) {
+	hs, subs := e.subs.Load().lookup(evt.Type)
+	if e.runAll {
+		var (
+			c        chain
+			failures []*core.HandlerError
+		)
+		c.one(subs)
+		e.emitted.Add(1)
+		err := e.runChain(ctx, evt, &c, &failures)
+		return aggregate(failures, err)
+	}
+	var cur *sub
 	defer func() {
 		if r := recover(); r != nil {
-			e.panics.Add(1)
-			retErr = fmt.Errorf("handler panic: %v", r)
+			retErr = e.recovered(r, evt, cur)
 		}
 	}()
-	return e.emitSyncCounted(evt)
-}
-
-// emitSyncCounted 带计数的同步分发 — Emit 和 EmitBatch 共用
-// 独立函数避免 defer 作用域覆盖计数操作
-//
-//go:nosplit
-func (e *Bus) emitSyncCounted(evt *core.Event) error {
 	e.emitted.Add(1)
-	return e.UnsafeEmit(evt)
+	for i := 0; i < len(hs); i++ {
+		if ctx != nil {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		var h core.Handler
+		cur, h = subs[i].pick(evt, hs[i])
+		if err := h(evt); err != nil {
+			if stopped(err) {
+				return nil
+			}
+			e.failed(err, evt, cur)
+			return err
+		}
+	}
+	return nil
 }
 
 // emitAsync 异步 Emit — SPSC ring 入队（与 async 包架构一致）
 // 生产者仅做单次 Submit（~20 ns），消费端做 handler 分发
-func (e *Bus) emitAsync(evt *core.Event) error {
+// ring 满时按溢出策略处理，仅 OverflowBlockTimeout 超时返回 core.ErrQueueFull；ctx 非 nil 时等待可被 ctx 取消。
+// 入队成功后才保留事件、计入 Emitted；未入队时撤销去重记录，同一 ID 的重试照常发布。
+func (e *Bus) emitAsync(ctx context.Context, evt *core.Event) error {
+	held := e.ret.Hold(evt)
+	var err error
+	if ctx != nil {
+		err = e.spsc.SubmitCtx(ctx, evt)
+	} else {
+		err = e.spsc.Submit(evt)
+	}
+	if err != nil {
+		e.dedup.Release(evt)
+		return err
+	}
+	e.ret.Keep(held)
 	e.emitted.Add(1)
-	e.spsc.Submit(evt)
 	return nil
 }
 
@@ -345,79 +952,190 @@ func (e *Bus) EmitMatch(evt *core.Event) error {
 	if evt == nil {
 		return nil
 	}
+	if e.dedup.Duplicate(evt) {
+		return nil
+	}
+	if ok, err := e.limits.Admit(evt); !ok {
+		e.dedup.Release(evt)
+		return err
+	}
+	e.ret.Offer(evt)
 	if e.async {
 		return e.emitMatchAsync(evt)
 	}
-	return e.emitMatchSyncSafe(evt)
+	return e.emitMatchSyncSafe(nil, evt)
 }
 
-// emitMatchSyncSafe 同步通配符安全路径
+// EmitMatchCtx 携带 context 发布事件，支持通配符（实现 core.ContextEmitter）
+func (e *Bus) EmitMatchCtx(ctx context.Context, evt *core.Event) error {
+	if evt == nil {
+		return nil
+	}
+	if ctx == nil {
+		return e.EmitMatch(evt)
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	evt.SetContext(ctx)
+	if e.dedup.Duplicate(evt) {
+		return nil
+	}
+	if ok, err := e.limits.Admit(evt); !ok {
+		e.dedup.Release(evt)
+		return err
+	}
+	e.ret.Offer(evt)
+	if e.async {
+		return e.emitMatchAsync(evt)
+	}
+	return e.emitMatchSyncSafe(ctx, evt)
+}
+
+// emitMatchSyncSafe 同步通配符安全路径（ctx 语义同 emitSyncSafe）
+// 命中的各 pattern 的 handler 经合并链按优先级统一排序调用。
 //
 //go:noinline
-func (e *Bus) emitMatchSyncSafe(evt *core.Event) (retErr error) {
+func (e *Bus) emitMatchSyncSafe(ctx context.Context, evt *core.Event) (retErr error) {
+	var c chain
+	patterns := e.matcher.Match(evt.Type)
+	c.init(e.subs.Load(), *patterns)
+	e.matcher.Put(patterns)
+	if e.runAll {
+		var failures []*core.HandlerError
+		e.emitted.Add(1)
+		err := e.runChain(ctx, evt, &c, &failures)
+		return aggregate(failures, err)
+	}
+	var cur *sub
 	defer func() {
 		if r := recover(); r != nil {
-			e.panics.Add(1)
-			retErr = fmt.Errorf("handler panic: %v", r)
+			retErr = e.recovered(r, evt, cur)
 		}
 	}()
 	e.emitted.Add(1)
-	return e.UnsafeEmitMatch(evt)
+	for {
+		if ctx != nil {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		s := c.next()
+		if s == nil {
+			return nil
+		}
+		var h core.Handler
+		cur, h = s.pick(evt, s.handler)
+		if err := h(evt); err != nil {
+			if stopped(err) {
+				return nil
+			}
+			e.failed(err, evt, cur)
+			return err
+		}
+	}
 }
 
 // emitMatchAsync 异步通配符匹配 — 同步分发（与 async 包行为一致）
 // 通配符需要在发布侧展开所有匹配 pattern，因此走同步路径。
-func (e *Bus) emitMatchAsync(evt *core.Event) (retErr error) {
-	defer func() {
-		if r := recover(); r != nil {
-			e.panics.Add(1)
-			retErr = fmt.Errorf("handler panic: %v", r)
-		}
-	}()
+// handler panic 被捕获并上报，其余 handler 继续执行。
+func (e *Bus) emitMatchAsync(evt *core.Event) error {
 	e.emitted.Add(1)
-	snap := e.subs.Load()
+	var c chain
 	patterns := e.matcher.Match(evt.Type)
-	defer e.matcher.Put(patterns)
-
-	for _, pattern := range *patterns {
-		for _, h := range snap.handlers[pattern] {
-			if err := h(evt); err != nil {
-				e.reportError(err)
-			}
-		}
+	c.init(e.subs.Load(), *patterns)
+	e.matcher.Put(patterns)
+	for !e.invokeChain(evt, &c) {
 	}
 	e.processed.Add(1)
 	return nil
 }
 
 // EmitBatch 批量发布事件
+// 被限流拒绝的事件从批次中移除，其余照常发布；handler 无 error 时返回 ErrRateLimited。
+func (e *Bus) EmitBatch(events []*core.Event) error {
+	events = e.dedup.Filter(events)
+	if e.limits == nil {
+		return e.emitBatch(events)
+	}
+	events, lerr := e.limits.Filter(events, e.dedup.Release)
+	if err := e.emitBatch(events); err != nil {
+		return err
+	}
+	return lerr
+}
+
+// emitBatch 批量发布（已去重、限流）
 // 优化: 整批共用一次 defer recover + 批量计数器（1 次 atomic 替代 N 次）
-func (e *Bus) EmitBatch(events []*core.Event) (retErr error) {
+func (e *Bus) emitBatch(events []*core.Event) (retErr error) {
+	e.offerAll(events)
 	if e.async {
 		e.emitted.Add(int64(len(events)))
 		for _, evt := range events {
-			e.spsc.Submit(evt)
+			if err := e.spsc.Submit(evt); err != nil {
+				return err
+			}
 		}
 		return nil
 	}
+	snap := e.subs.Load()
+	if e.runAll {
+		var (
+			c        chain
+			failures []*core.HandlerError
+		)
+		e.emitted.Add(int64(len(events)))
+		for _, evt := range events {
+			_, subs := snap.lookup(evt.Type)
+			c.one(subs)
+			_ = e.runChain(nil, evt, &c, &failures)
+		}
+		return aggregate(failures, nil)
+	}
+	var (
+		cur *core.Event
+		s   *sub
+	)
 	defer func() {
 		if r := recover(); r != nil {
-			e.panics.Add(1)
-			retErr = fmt.Errorf("handler panic: %v", r)
+			retErr = e.recovered(r, cur, s)
 		}
 	}()
 	e.emitted.Add(int64(len(events)))
-	for _, evt := range events {
-		if err := e.UnsafeEmit(evt); err != nil {
-			return err
+	for _, cur = range events {
+		hs, subs := snap.lookup(cur.Type)
+		for i := 0; i < len(hs); i++ {
+			var h core.Handler
+			s, h = subs[i].pick(cur, hs[i])
+			if err := h(cur); err != nil {
+				if stopped(err) {
+					break
+				}
+				e.failed(err, cur, s)
+				return err
+			}
 		}
 	}
 	return nil
 }
 
-// EmitMatchBatch 批量发布支持通配符匹配的事件
+// EmitMatchBatch 批量发布支持通配符匹配的事件（限流语义同 EmitBatch）
+func (e *Bus) EmitMatchBatch(events []*core.Event) error {
+	events = e.dedup.Filter(events)
+	if e.limits == nil {
+		return e.emitMatchBatch(events)
+	}
+	events, lerr := e.limits.Filter(events, e.dedup.Release)
+	if err := e.emitMatchBatch(events); err != nil {
+		return err
+	}
+	return lerr
+}
+
+// emitMatchBatch 批量通配符发布（已去重、限流）
 // 优化: 整批共用一次 defer recover + 批量计数器
-func (e *Bus) EmitMatchBatch(events []*core.Event) (retErr error) {
+func (e *Bus) emitMatchBatch(events []*core.Event) (retErr error) {
+	e.offerAll(events)
 	if e.async {
 		for _, evt := range events {
 			if err := e.emitMatchAsync(evt); err != nil {
@@ -426,21 +1144,67 @@ func (e *Bus) EmitMatchBatch(events []*core.Event) (retErr error) {
 		}
 		return nil
 	}
+	snap := e.subs.Load()
+	if e.runAll {
+		var (
+			c        chain
+			failures []*core.HandlerError
+		)
+		e.emitted.Add(int64(len(events)))
+		for _, evt := range events {
+			patterns := e.matcher.Match(evt.Type)
+			c.init(snap, *patterns)
+			e.matcher.Put(patterns)
+			_ = e.runChain(nil, evt, &c, &failures)
+		}
+		return aggregate(failures, nil)
+	}
+	var (
+		cur *core.Event
+		s   *sub
+		c   chain
+	)
 	defer func() {
 		if r := recover(); r != nil {
-			e.panics.Add(1)
-			retErr = fmt.Errorf("handler panic: %v", r)
+			retErr = e.recovered(r, cur, s)
 		}
 	}()
 	e.emitted.Add(int64(len(events)))
-	for _, evt := range events {
-		if err := e.UnsafeEmitMatch(evt); err != nil {
-			return err
+	for _, cur = range events {
+		patterns := e.matcher.Match(cur.Type)
+		c.init(snap, *patterns)
+		e.matcher.Put(patterns)
+		for {
+			slot := c.next()
+			if slot == nil {
+				break
+			}
+			var h core.Handler
+			s, h = slot.pick(cur, slot.handler)
+			if err := h(cur); err != nil {
+				if stopped(err) {
+					break
+				}
+				e.failed(err, cur, s)
+				return err
+			}
 		}
 	}
 	return nil
 }
 
+// offerAll 批量发布路径的自动保留检查
+func (e *Bus) offerAll(events []*core.Event) {
+	if !e.ret.Auto() {
+		return
+	}
+	for _, evt := range events {
+		if evt != nil {
+			e.ret.Offer(evt)
+		}
+	}
+}
+
 // Preload 预加载event types（兼容API）
 func (e *Bus) Preload(eventTypes []string) {
 	// 为来来扩展
@@ -456,10 +1220,36 @@ func (e *Bus) Stats() core.Stats {
 		// 同步模式: emit 完成即处理完成，无需单独计数
 		processed = emitted
 	}
+	byPattern := e.errs.Snapshot()
+	var errs int64
+	for _, n := range byPattern {
+		errs += n
+	}
+	var dropped int64
+	depth := e.delay.Len()
+	if e.spsc != nil {
+		dropped = e.spsc.Dropped()
+		depth += e.spsc.Depth()
+	}
+	retained, retainedBytes := e.ret.Stats()
+	hits, misses, evictions := e.matcher.CacheStats()
 	return core.Stats{
-		Emitted:   emitted,
-		Processed: processed,
-		Panics:    e.panics.Read(),
+		Emitted:             emitted,
+		Processed:           processed,
+		Panics:              e.panics.Read(),
+		Errors:              errs,
+		Depth:               depth,
+		Duplicates:          e.dedup.Duplicates(),
+		RateLimited:         e.limits.Hits(),
+		HandlerRateLimited:  e.subHits.Read(),
+		Filtered:            e.preds.Filtered(),
+		MatchCacheHits:      hits,
+		MatchCacheMisses:    misses,
+		MatchCacheEvictions: evictions,
+		Dropped:             dropped,
+		Retained:            retained,
+		RetainedBytes:       retainedBytes,
+		ErrorsByPattern:     byPattern,
 	}
 }
 
@@ -468,6 +1258,8 @@ func (e *Bus) Close() {
 	if !e.closed.CompareAndSwap(false, true) {
 		return // 已关闭
 	}
+	e.delay.Close()
+	e.stopOps()
 
 	// 停止 SPSC 调度器（等待所有 worker 退出）
 	if e.spsc != nil {
@@ -483,21 +1275,35 @@ func (e *Bus) Close() {
 	// channel会GC自动回收
 }
 
+// stopOps 停止全部订阅的算子（取消未到期的定时投递）
+func (e *Bus) stopOps() {
+	for _, subs := range e.subs.Load().byID {
+		for _, s := range subs {
+			s.ops.Stop()
+		}
+	}
+}
+
 // Drain 优雅关闭（等待异步任务完成或超时）
 func (e *Bus) Drain(timeout time.Duration) error {
 	if timeout <= 0 {
 		e.Close()
 		return nil
 	}
-	// ants.Pool.Release() 本身会等待所有任务完成
+	// 先按 DelayPolicy 处理未到期的延迟事件，再等待已入队事件（含刚发布的延迟事件）处理完毕
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	var derr error
 	done := make(chan struct{})
 	go func() {
+		derr = e.delay.Drain()
+		_ = e.Barrier(ctx)
 		e.Close()
 		close(done)
 	}()
 	select {
 	case <-done:
-		return nil
+		return derr
 	case <-time.After(timeout):
 		return fmt.Errorf("sync: graceful close timed out after %v", timeout)
 	}
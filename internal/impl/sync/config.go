@@ -5,10 +5,16 @@ import (
 	"fmt"
 	"runtime"
 	stdsync "sync"
+	"time"
 
 	"github.com/uniyakcom/beat/core"
+	"github.com/uniyakcom/beat/internal/support/dedup"
+	"github.com/uniyakcom/beat/internal/support/latency"
 	"github.com/uniyakcom/beat/internal/support/pool"
+	"github.com/uniyakcom/beat/internal/support/ratelimit"
+	"github.com/uniyakcom/beat/internal/support/retain"
 	"github.com/uniyakcom/beat/internal/support/sched"
+	"github.com/uniyakcom/beat/internal/support/wheel"
 	"github.com/uniyakcom/beat/util"
 )
 
@@ -27,8 +33,40 @@ type Config struct {
 	PoolSize int  // 异步池大小
 	Async    bool // 是否启用异步模式
 
+	Overflow        core.OverflowPolicy // 异步模式 ring 满时的处理策略（默认 OverflowBlock）
+	OverflowTimeout time.Duration       // OverflowBlockTimeout 的等待上限（0=100ms）
+
 	// Arena
 	EnableArena bool // 是否启用Arena自动分配Data
+
+	// 回调
+	PanicHandler core.PanicInfoHandler // handler panic 回调（nil=仅计数）
+	ErrorHandler core.ErrorHandler     // handler error 回调（nil=仅计数）
+
+	// 自动保留的事件 pattern（支持通配符；匹配的事件发布时保留最新副本）
+	Retain []string
+
+	// 同步模式 handler 失败处理方式（默认 ErrorFailFast；ErrorRunAll 汇总为 *core.EmitError）
+	ErrorMode core.ErrorMode
+
+	// Drain 时未到期延迟事件的处理方式（默认 DelayFire）
+	DelayPolicy core.DelayPolicy
+
+	// 按 Event.ID 去重: DedupWindow>0 时开启，窗口内重复 ID 的事件在匹配前丢弃
+	DedupWindow     time.Duration
+	DedupMaxEntries int // 最多记录的 ID 数（0=65536）
+
+	// 发布侧限流规则（按事件类型 pattern 的令牌桶）
+	RateLimits []core.RateLimit
+
+	// pattern 语法（订阅、保留与限流 pattern 共用；零值=DotSyntax）
+	PatternSyntax core.PatternSyntax
+
+	// 匹配结果缓存容量（条目数；0=core.DefaultMatchCacheSize，<0 不缓存）
+	MatchCacheSize int
+
+	// 按订阅 pattern 记录 handler 耗时（core.LatencyStatter）
+	HandlerLatency bool
 }
 
 // DefaultConfig 返回默认配置
@@ -69,15 +107,24 @@ func New(cfg *Config) (core.Bus, error) {
 	pool.SetEnableArena(cfg.EnableArena)
 
 	e := &Bus{
-		matcher:   core.NewTrieMatcher(),
+		matcher:   core.NewTrieMatcherSize(cfg.PatternSyntax, cfg.MatchCacheSize),
 		async:     cfg.Async,
+		runAll:    cfg.ErrorMode == core.ErrorRunAll,
+		ret:       retain.New(cfg.Retain, cfg.PatternSyntax),
 		emitted:   util.NewPerCPUCounter(),
 		processed: util.NewPerCPUCounter(),
 		panics:    util.NewPerCPUCounter(),
 	}
 
-	m := make(map[string][]*sub)
-	e.subs.Store(buildSnapshot(m))
+	e.subs.Store(buildSnapshot(&subsSnapshot{}, "", nil))
+	e.delay = wheel.New(e.Emit, cfg.DelayPolicy)
+	if cfg.DedupWindow > 0 {
+		e.dedup = dedup.New(cfg.DedupWindow, cfg.DedupMaxEntries)
+	}
+	e.limits = ratelimit.New(cfg.RateLimits, cfg.PatternSyntax)
+	e.lat = latency.New(cfg.HandlerLatency)
+	e.SetPanicInfoHandler(cfg.PanicHandler)
+	e.SetErrorHandler(cfg.ErrorHandler)
 
 	e.pool = stdsync.Pool{
 		New: func() interface{} {
@@ -97,6 +144,13 @@ func New(cfg *Config) (core.Bus, error) {
 			err := fmt.Errorf("handler panic: %v", r)
 			e.reportError(err)
 		}
+		e.spsc.OnDrop = func(evt *core.Event) {
+			if c := evt.Completion(); c != nil {
+				c.AddError(core.ErrQueueFull)
+				c.Resolve()
+			}
+		}
+		e.spsc.SetOverflow(cfg.Overflow, cfg.OverflowTimeout)
 		e.spsc.Start(func(evt *core.Event) {
 			e.dispatchAsync(evt)
 		})
@@ -0,0 +1,196 @@
+// Package dedup 提供按 ID 去重的分片已见集合（三种 Bus 实现与 middleware/dedup 共用）
+//
+// 设计:
+//   - 按 ID 哈希分为 shards 个分片，每个分片一把锁，并发发布只在同分片上竞争
+//   - 每个分片容量固定（总容量 / 分片数），按记录顺序组成 FIFO 环：
+//     满时淘汰最早记录的 ID，内存上界与 ID 到达速率无关
+//   - 记录带过期时间，过期后同一 ID 视为新事件；环头的过期记录在写入时顺带清理
+package dedup
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/uniyakcom/beat/core"
+)
+
+const (
+	shards = 32
+
+	// DefaultWindow 默认去重窗口
+	DefaultWindow = time.Minute
+	// DefaultMaxEntries 默认最多记录的 ID 数
+	DefaultMaxEntries = 1 << 16
+)
+
+// Set 分片已见集合
+type Set struct {
+	window time.Duration
+	shards [shards]shard
+	dups   atomic.Int64 // Duplicate 丢弃的重复事件数
+}
+
+// shard 单个分片: map 查重 + 定长 FIFO 环记录写入顺序
+type shard struct {
+	mu   sync.Mutex
+	m    map[string]entry
+	ring []slot // 定长环（容量 = 分片容量）
+	head int    // 最早记录的位置
+	n    int    // 环内记录数（含已被 Forget / 覆盖的失效槽位）
+	seq  uint64
+}
+
+type entry struct {
+	expire int64  // UnixNano
+	seq    uint64 // 对应环槽位的写入序号（环槽位失效判断）
+}
+
+type slot struct {
+	id  string
+	seq uint64
+}
+
+// New 创建已见集合；window<=0 时为 DefaultWindow，maxEntries<=0 时为 DefaultMaxEntries
+func New(window time.Duration, maxEntries int) *Set {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	per := (maxEntries + shards - 1) / shards
+	s := &Set{window: window}
+	for i := range s.shards {
+		s.shards[i].m = make(map[string]entry)
+		s.shards[i].ring = make([]slot, per)
+	}
+	return s
+}
+
+// Duplicate 发布路径调用：evt.ID 在窗口内已出现过时计数并返回 true（调用方丢弃事件）
+// s 为 nil（未开启去重）或 ID 为空时返回 false。
+// 请求的回复（Type 以 core.InboxPrefix 开头）沿用请求 ID 作为关联 ID，不参与去重。
+// 被丢弃的事件若带完成句柄（EmitAwait）则直接完成，等待方不会阻塞。
+func (s *Set) Duplicate(evt *core.Event) bool {
+	if s == nil || evt.ID == "" || strings.HasPrefix(evt.Type, core.InboxPrefix) {
+		return false
+	}
+	if !s.Seen(evt.ID, s.window) {
+		return false
+	}
+	s.dups.Add(1)
+	if c := evt.Completion(); c != nil {
+		c.Resolve()
+	}
+	return true
+}
+
+// Release 撤销 Duplicate 为 evt.ID 写入的记录（事件通过去重后最终未发布时调用，如被限流拒绝或丢弃），
+// 使同一 ID 的重投不被当作重复事件。s 为 nil 时无操作。
+func (s *Set) Release(evt *core.Event) {
+	if s == nil || evt == nil || evt.ID == "" {
+		return
+	}
+	s.Forget(evt.ID)
+}
+
+// Filter 批量发布路径调用：返回去掉重复事件后的切片
+// 没有重复事件时原样返回 events；否则返回新切片，不修改调用方的 events。
+func (s *Set) Filter(events []*core.Event) []*core.Event {
+	if s == nil {
+		return events
+	}
+	var out []*core.Event
+	for i, evt := range events {
+		if evt == nil || !s.Duplicate(evt) {
+			if out != nil {
+				out = append(out, evt)
+			}
+			continue
+		}
+		if out == nil {
+			out = make([]*core.Event, i, len(events)-1)
+			copy(out, events[:i])
+		}
+	}
+	if out == nil {
+		return events
+	}
+	return out
+}
+
+// Seen 检查并记录 id：ttl 内已记录过返回 true，否则记录（有效期 ttl）并返回 false
+func (s *Set) Seen(id string, ttl time.Duration) bool {
+	now := time.Now().UnixNano()
+	sh := &s.shards[hash(id)%shards]
+	sh.mu.Lock()
+	if e, ok := sh.m[id]; ok && e.expire > now {
+		sh.mu.Unlock()
+		return true
+	}
+	sh.add(id, now, now+int64(ttl))
+	sh.mu.Unlock()
+	return false
+}
+
+// Forget 删除 id 的记录（处理失败需要允许重投时调用）
+func (s *Set) Forget(id string) {
+	sh := &s.shards[hash(id)%shards]
+	sh.mu.Lock()
+	delete(sh.m, id)
+	sh.mu.Unlock()
+}
+
+// Duplicates 返回 Duplicate 丢弃的重复事件数（s 为 nil 时为 0）
+func (s *Set) Duplicates() int64 {
+	if s == nil {
+		return 0
+	}
+	return s.dups.Load()
+}
+
+// Len 返回当前记录的 ID 数（含尚未清理的过期记录）
+func (s *Set) Len() int {
+	n := 0
+	for i := range s.shards {
+		sh := &s.shards[i]
+		sh.mu.Lock()
+		n += len(sh.m)
+		sh.mu.Unlock()
+	}
+	return n
+}
+
+// add 写入记录（调用方持锁）：先清理环头的过期记录，环满时淘汰最早的记录
+func (sh *shard) add(id string, now, expire int64) {
+	for sh.n > 0 {
+		old := sh.ring[sh.head]
+		e, ok := sh.m[old.id]
+		live := ok && e.seq == old.seq
+		if live && e.expire > now && sh.n < len(sh.ring) {
+			break
+		}
+		if live {
+			delete(sh.m, old.id)
+		}
+		sh.ring[sh.head] = slot{}
+		sh.head = (sh.head + 1) % len(sh.ring)
+		sh.n--
+	}
+	sh.seq++
+	sh.ring[(sh.head+sh.n)%len(sh.ring)] = slot{id: id, seq: sh.seq}
+	sh.n++
+	sh.m[id] = entry{expire: expire, seq: sh.seq}
+}
+
+// hash FNV-1a 64 位哈希（零分配）
+func hash(s string) uint64 {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= 1099511628211
+	}
+	return h
+}
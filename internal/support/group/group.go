@@ -0,0 +1,85 @@
+// Package group 提供竞争消费者订阅组的成员选择（三种 Bus 实现共用）
+//
+// 订阅组随 RCU 快照重建：
+//   - 构建快照时，同一 pattern 下同名组的成员折叠为一个槽位（位于该组最早成员处）
+//   - 分发时槽位按均衡策略选出一个成员执行，其余槽位不受影响
+//   - 成员加入/离开只生成新快照，正在使用旧快照的分发不受影响
+package group
+
+import (
+	"sync/atomic"
+
+	"github.com/uniyakcom/beat/core"
+)
+
+// Group 同一 pattern 下同名订阅组的不可变成员视图
+type Group[S any] struct {
+	name    string
+	balance core.GroupBalance
+	members []S
+	next    atomic.Uint64 // 轮询游标
+}
+
+// Name 返回组名
+func (g *Group[S]) Name() string {
+	return g.name
+}
+
+// Members 返回组成员（按加入顺序，只读）
+func (g *Group[S]) Members() []S {
+	return g.members
+}
+
+// Pick 选择处理事件的成员
+// GroupByKey 且 key 非空时按 key 哈希固定成员，否则轮询。
+func (g *Group[S]) Pick(key string) S {
+	n := uint64(len(g.members))
+	if g.balance == core.GroupByKey && key != "" {
+		return g.members[hash(key)%n]
+	}
+	return g.members[(g.next.Add(1)-1)%n]
+}
+
+// Collapse 将 subs 中同组成员折叠为一个槽位，其余订阅保持原有顺序
+// info 返回订阅的组名与均衡策略（组名为空表示普通订阅）；slot 为每个组创建占位订阅。
+// 组的均衡策略取最早加入的成员。subs 中没有组成员时原样返回（零分配）。
+func Collapse[S any](subs []S, info func(S) (string, core.GroupBalance), slot func(*Group[S]) S) []S {
+	grouped := false
+	for _, s := range subs {
+		if name, _ := info(s); name != "" {
+			grouped = true
+			break
+		}
+	}
+	if !grouped {
+		return subs
+	}
+
+	groups := make(map[string]*Group[S])
+	out := make([]S, 0, len(subs))
+	for _, s := range subs {
+		name, balance := info(s)
+		if name == "" {
+			out = append(out, s)
+			continue
+		}
+		if g, ok := groups[name]; ok {
+			g.members = append(g.members, s)
+			continue
+		}
+		g := &Group[S]{name: name, balance: balance, members: []S{s}}
+		groups[name] = g
+		out = append(out, slot(g)) // slot 创建时至少已有一个成员
+	}
+	return out
+}
+
+// hash FNV-1a 64 位哈希（零分配）
+func hash(key string) uint64 {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(key); i++ {
+		h ^= uint64(key[i])
+		h *= 1099511628211
+	}
+	return h
+}
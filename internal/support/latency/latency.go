@@ -0,0 +1,57 @@
+// Package latency 按订阅 pattern 记录 handler 执行耗时（三种 Bus 实现共用，beat.WithHandlerLatency 开启）
+//
+// 设计:
+//   - 订阅时取出该 pattern 的 per-CPU 直方图并为 handler 包一层计时，分发路径不再查表
+//   - 同一 pattern 的多个订阅写入同一直方图
+//   - *Recorder 为 nil 表示未开启: Wrap 原样返回 handler，分发路径零开销
+package latency
+
+import (
+	"time"
+
+	"github.com/uniyakcom/beat/core"
+	"github.com/uniyakcom/beat/util"
+)
+
+// Recorder 按 pattern 分组的 handler 耗时直方图
+type Recorder struct {
+	byPattern util.KeyedHistogram
+}
+
+// New 创建 Recorder；enabled 为 false 时返回 nil
+func New(enabled bool) *Recorder {
+	if !enabled {
+		return nil
+	}
+	return &Recorder{}
+}
+
+// Wrap 返回记录耗时的 handler（r 为 nil 时原样返回 h）
+// handler panic 时不记录（panic 由调用方的 recover 处理）。
+func (r *Recorder) Wrap(pattern string, h core.Handler) core.Handler {
+	if r == nil {
+		return h
+	}
+	hist := r.byPattern.Get(pattern)
+	return func(evt *core.Event) error {
+		start := time.Now()
+		err := h(evt)
+		hist.Record(time.Since(start))
+		return err
+	}
+}
+
+// Snapshot 返回各 pattern 的耗时分布（r 为 nil 或尚无订阅时返回 nil）
+func (r *Recorder) Snapshot() map[string]util.HistogramSnapshot {
+	if r == nil {
+		return nil
+	}
+	return r.byPattern.Snapshot()
+}
+
+// Reset 清零全部 pattern 的耗时分布（r 为 nil 时无操作）
+func (r *Recorder) Reset() {
+	if r != nil {
+		r.byPattern.Reset()
+	}
+}
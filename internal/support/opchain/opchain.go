@@ -0,0 +1,146 @@
+// Package opchain 为订阅构建算子链（三种 Bus 实现共用）
+//
+// Chain 同时是订阅在分发路径之外的定时投递环境：订阅侧限流的合并模式（ratelimit）也经它调度。
+//
+// 设计:
+//   - 算子定时器挂在所属 Bus 的时间轮上（与延迟发布共用，Bus 关闭时随时间轮一起丢弃）
+//   - 每个订阅一个 Chain，登记尚未到期的定时器；Off 时 Stop 逐个取消并拒绝新的定时器与投递
+//   - 定时投递不在时间轮的驱动 goroutine 中执行 handler：排入订阅的投递队列，
+//     由按需启动的 goroutine 按顺序经 Bus 提供的 deliver 调用（panic/error 按订阅上报），队列空时退出
+package opchain
+
+import (
+	"sync"
+	"time"
+
+	"github.com/uniyakcom/beat/core"
+	"github.com/uniyakcom/beat/internal/support/wheel"
+)
+
+// Chain 单个订阅的算子运行环境（实现 core.OperatorRuntime）
+type Chain struct {
+	timers  *wheel.Wheel
+	deliver func(core.Handler, *core.Event)
+
+	mu       sync.Mutex
+	stopped  bool
+	pending  map[*entry]struct{} // 未到期的定时器
+	queue    []delivery          // 待投递（到期后排队，不占用时间轮 goroutine）
+	draining bool                // 投递 goroutine 运行中
+}
+
+// entry 已登记的定时器
+type entry struct {
+	cancel core.CancelFunc
+}
+
+// delivery 一次待执行的定时投递
+type delivery struct {
+	h   core.Handler
+	evt *core.Event
+}
+
+// New 创建订阅的运行环境
+// w 为所属 Bus 的时间轮；deliver 供定时投递在分发路径之外调用 handler（由 Bus 负责 panic 恢复与错误上报）。
+func New(w *wheel.Wheel, deliver func(core.Handler, *core.Event)) *Chain {
+	return &Chain{timers: w, deliver: deliver}
+}
+
+// Build 按 ops 顺序包装 h（ops[0] 在最外层）；ops 为空时原样返回 h
+func (c *Chain) Build(h core.Handler, ops []core.Operator) core.Handler {
+	if len(ops) == 0 || h == nil {
+		return h
+	}
+	var build func(i int, rt core.OperatorRuntime) core.Handler
+	build = func(i int, rt core.OperatorRuntime) core.Handler {
+		if i == len(ops) {
+			return h
+		}
+		return ops[i].Build(func(rt core.OperatorRuntime) core.Handler { return build(i+1, rt) }, rt)
+	}
+	return build(0, c)
+}
+
+// After 实现 core.OperatorRuntime
+func (c *Chain) After(d time.Duration, fn func()) core.CancelFunc {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stopped {
+		return func() bool { return false }
+	}
+	if c.pending == nil {
+		c.pending = make(map[*entry]struct{})
+	}
+	e := &entry{}
+	c.pending[e] = struct{}{}
+	e.cancel = c.timers.AddFunc(time.Now().Add(d), func() {
+		if c.take(e) {
+			fn()
+		}
+	})
+	return func() bool {
+		if !c.take(e) {
+			return false
+		}
+		e.cancel() // 已从时间轮取出但尚未执行时，回调内 take 失败，同样不会调用 fn
+		return true
+	}
+}
+
+// take 注销定时器，返回其此前是否仍登记（未到期、未取消）
+func (c *Chain) take(e *entry) bool {
+	c.mu.Lock()
+	_, ok := c.pending[e]
+	delete(c.pending, e)
+	c.mu.Unlock()
+	return ok
+}
+
+// Deliver 实现 core.OperatorRuntime：排入投递队列，由投递 goroutine 按顺序调用 h
+func (c *Chain) Deliver(h core.Handler, evt *core.Event) {
+	c.mu.Lock()
+	if c.stopped {
+		c.mu.Unlock()
+		return
+	}
+	c.queue = append(c.queue, delivery{h: h, evt: evt})
+	if c.draining {
+		c.mu.Unlock()
+		return
+	}
+	c.draining = true
+	c.mu.Unlock()
+	go c.drain()
+}
+
+// drain 按顺序执行排队的投递，队列空或订阅已取消时退出
+func (c *Chain) drain() {
+	for {
+		c.mu.Lock()
+		if c.stopped || len(c.queue) == 0 {
+			c.draining = false
+			c.mu.Unlock()
+			return
+		}
+		d := c.queue[0]
+		c.queue[0] = delivery{}
+		c.queue = c.queue[1:]
+		c.mu.Unlock()
+		c.deliver(d.h, d.evt)
+	}
+}
+
+// Stop 取消全部未到期的定时器，此后不再调度与投递（c 为 nil 时无操作）
+func (c *Chain) Stop() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.stopped = true
+	for e := range c.pending {
+		e.cancel()
+	}
+	c.pending = nil
+	c.queue = nil
+	c.mu.Unlock()
+}
@@ -83,13 +83,18 @@ func (p *EventPool) Acquire() *core.Event {
 }
 
 // Release 归还 Event（最小化清零后放回池）
-// 仅清零 hot fields (Data, Type)，cold fields 在 Acquire 后由调用方覆盖
+// 仅清零 hot fields (Data, Type)、路由相关的 Key 与 context/完成句柄/回复通道（避免池中对象延长其生命周期），
+// 其余 cold fields 在 Acquire 后由调用方覆盖
 func (p *EventPool) Release(evt *core.Event) {
 	if evt == nil {
 		return
 	}
 	evt.Data = nil
 	evt.Type = ""
+	evt.Key = ""
+	evt.SetContext(nil)
+	evt.SetCompletion(nil)
+	evt.SetReplyTo(nil)
 	p.pool.Put(evt)
 }
 
@@ -0,0 +1,164 @@
+// Package predicate 为订阅挂载负载谓词（where.Expr，三种 Bus 实现共用）
+//
+// 设计:
+//   - 谓词包装在 handler 最外层（先于订阅侧限流与算子）：被过滤的事件不消耗令牌、不进入算子
+//   - 同一 pattern 上有多个谓词订阅时共享字段查找缓存：同一事件在这些订阅间相同路径只查找一次
+//   - 缓存以事件指针 + Data 识别同一次分发；每个订阅（订阅组按组）在缓存上占一位，
+//     遇到本订阅已置位的缓存说明是复用 Event 对象的又一次发布，重新查找
+//   - 只有一个谓词订阅的 pattern 在栈上求值，零分配；未挂载谓词的订阅不经过任何额外逻辑
+package predicate
+
+import (
+	"math/bits"
+	"sync"
+	"sync/atomic"
+
+	"github.com/uniyakcom/beat/core"
+	"github.com/uniyakcom/beat/util"
+	"github.com/uniyakcom/beat/where"
+)
+
+// Registry 一个 Bus 的谓词订阅登记（零值可用）
+type Registry struct {
+	mu       sync.Mutex
+	tables   map[string]*table // pattern → 共享缓存
+	filtered atomic.Pointer[util.PerCPUCounter]
+}
+
+// table 同一 pattern 的谓词订阅共享的查找缓存
+type table struct {
+	last   atomic.Pointer[memo]
+	shared atomic.Bool // 不少于两个单位（订阅或订阅组）时才共享
+
+	// 以下由 Registry.mu 保护
+	used   uint64           // 已分配的位
+	units  int              // 单位数
+	groups map[string]*unit // 订阅组成员共用一个单位
+}
+
+// unit 在缓存上占一位的订阅或订阅组（bit 为 0 表示位已用尽，不参与共享）
+type unit struct {
+	bit   uint64
+	group string
+	refs  int
+}
+
+// memo 一次分发的字段查找结果
+type memo struct {
+	evt  *core.Event
+	data []byte
+	seen uint64 // 已求值的单位
+	f    where.Fields
+}
+
+// Binding 一个订阅的谓词绑定（Off 时 Release）
+type Binding struct {
+	reg     *Registry
+	pattern string
+	t       *table
+	u       *unit
+	x       *where.Expr
+}
+
+// Bind 按订阅选项包装 handler；未挂载谓词（o.Where 为 nil）时原样返回 h 与 nil
+func (r *Registry) Bind(pattern string, h core.Handler, o core.SubOptions) (core.Handler, *Binding) {
+	if o.Where == nil || h == nil {
+		return h, nil
+	}
+	r.mu.Lock()
+	if r.tables == nil {
+		r.tables = make(map[string]*table)
+	}
+	t := r.tables[pattern]
+	if t == nil {
+		t = &table{groups: make(map[string]*unit)}
+		r.tables[pattern] = t
+	}
+	u := t.groups[o.Group]
+	if u == nil || o.Group == "" {
+		u = &unit{group: o.Group}
+		if free := ^t.used; free != 0 {
+			u.bit = 1 << bits.TrailingZeros64(free)
+			t.used |= u.bit
+		}
+		if o.Group != "" {
+			t.groups[o.Group] = u
+		}
+		t.units++
+		t.shared.Store(t.units > 1)
+	}
+	u.refs++
+	r.mu.Unlock()
+
+	b := &Binding{reg: r, pattern: pattern, t: t, u: u, x: o.Where}
+	return func(evt *core.Event) error {
+		if b.match(evt) {
+			return h(evt)
+		}
+		r.count()
+		return nil
+	}, b
+}
+
+// match 对 evt 求值谓词
+func (b *Binding) match(evt *core.Event) bool {
+	bit := b.u.bit
+	if bit == 0 || !b.t.shared.Load() {
+		var f where.Fields
+		f.Reset(evt.Data)
+		return b.x.MatchFields(&f)
+	}
+	m := b.t.last.Load()
+	if m == nil || m.evt != evt || !sameData(m.data, evt.Data) || m.seen&bit != 0 {
+		m = &memo{evt: evt, data: evt.Data}
+		m.f.Reset(evt.Data)
+		b.t.last.Store(m)
+	}
+	m.seen |= bit
+	return b.x.MatchFields(&m.f)
+}
+
+// sameData 判断 a 与 b 是否为同一段内存
+func sameData(a, b []byte) bool {
+	return len(a) == len(b) && (len(a) == 0 || &a[0] == &b[0])
+}
+
+// Release 解除绑定（b 为 nil 时无操作）
+func (b *Binding) Release() {
+	if b == nil {
+		return
+	}
+	r, t, u := b.reg, b.t, b.u
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if u.refs--; u.refs > 0 {
+		return
+	}
+	t.used &^= u.bit
+	if u.group != "" {
+		delete(t.groups, u.group)
+	}
+	t.units--
+	t.shared.Store(t.units > 1)
+	if t.units == 0 {
+		delete(r.tables, b.pattern)
+	}
+}
+
+// count 过滤计数加一
+func (r *Registry) count() {
+	c := r.filtered.Load()
+	if c == nil {
+		r.filtered.CompareAndSwap(nil, util.NewPerCPUCounter())
+		c = r.filtered.Load()
+	}
+	c.Add(1)
+}
+
+// Filtered 返回被谓词过滤掉的 handler 调用次数
+func (r *Registry) Filtered() int64 {
+	if c := r.filtered.Load(); c != nil {
+		return c.Read()
+	}
+	return 0
+}
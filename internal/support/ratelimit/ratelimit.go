@@ -0,0 +1,337 @@
+// Package ratelimit 提供三种 Bus 实现共用的令牌桶限流（发布侧按 pattern、订阅侧按 handler）
+//
+// 设计:
+//   - 令牌桶采用 GCRA（理论到达时间）算法，状态只有一个 atomic int64，
+//     Allow/Reserve 为无锁 CAS 循环，零分配
+//   - 发布侧 Limits 仅在配置了规则时创建；未配置时 Bus 持有 nil，热路径只多一次 nil 判断
+//   - 订阅侧限流在 OnWith 时包装 handler，未限流的订阅不经过任何额外逻辑
+//   - 命中次数写入惰性创建的 PerCPU 计数器，未命中限流时不分配
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/uniyakcom/beat/core"
+	"github.com/uniyakcom/beat/util"
+)
+
+// epoch 单调时钟基准（令牌桶时间均为相对 epoch 的纳秒数）
+var epoch = time.Now()
+
+// now 返回相对 epoch 的单调纳秒数
+func now() int64 {
+	return int64(time.Since(epoch))
+}
+
+// Bucket GCRA 令牌桶
+// tat 为下一个令牌的理论到达时间；tat-now 不超过 limit 时放行。
+type Bucket struct {
+	interval int64 // 每个令牌的间隔（ns）
+	limit    int64 // burst * interval
+	tat      atomic.Int64
+}
+
+// NewBucket 创建令牌桶：每秒 rate 个令牌，容量 burst（<=0 时为 1）；初始为满桶
+func NewBucket(rate float64, burst int) *Bucket {
+	if burst <= 0 {
+		burst = 1
+	}
+	iv := int64(float64(time.Second) / rate)
+	if iv < 1 {
+		iv = 1
+	}
+	return &Bucket{interval: iv, limit: int64(burst) * iv}
+}
+
+// Allow 取一个令牌：有令牌时消耗并返回 true，否则不消耗并返回 false
+func (b *Bucket) Allow() bool {
+	t := now()
+	for {
+		tat := b.tat.Load()
+		next := max(tat, t) + b.interval
+		if next-t > b.limit {
+			return false
+		}
+		if b.tat.CompareAndSwap(tat, next) {
+			return true
+		}
+	}
+}
+
+// Reserve 预定一个令牌，返回令牌可用前需等待的时长（0 表示立即可用）
+// 预定后不再使用时调用 Cancel 归还。
+func (b *Bucket) Reserve() time.Duration {
+	t := now()
+	for {
+		tat := b.tat.Load()
+		next := max(tat, t) + b.interval
+		if b.tat.CompareAndSwap(tat, next) {
+			if w := next - t - b.limit; w > 0 {
+				return time.Duration(w)
+			}
+			return 0
+		}
+	}
+}
+
+// Cancel 归还一个 Reserve 预定的令牌
+func (b *Bucket) Cancel() {
+	b.tat.Add(-b.interval)
+}
+
+// Hits 限流命中计数（零值可用；首次命中时才创建 PerCPU 计数器）
+type Hits struct {
+	c atomic.Pointer[util.PerCPUCounter]
+}
+
+// Add 计数加一
+func (h *Hits) Add() {
+	c := h.c.Load()
+	if c == nil {
+		h.c.CompareAndSwap(nil, util.NewPerCPUCounter())
+		c = h.c.Load()
+	}
+	c.Add(1)
+}
+
+// Read 返回累计命中次数
+func (h *Hits) Read() int64 {
+	if c := h.c.Load(); c != nil {
+		return c.Read()
+	}
+	return 0
+}
+
+// rule 一条发布侧规则
+type rule struct {
+	bucket *Bucket
+	policy core.RatePolicy
+}
+
+// Limits 发布侧限流规则集（构造后只读）
+type Limits struct {
+	matcher *core.TrieMatcher
+	rules   map[string][]rule // pattern → 规则（同一 pattern 可配置多条）
+	hits    Hits
+}
+
+// New 创建发布侧规则集（pattern 按 syntax 解析）；limits 中没有有效规则（Rate>0）时返回 nil
+// 语法无效的 pattern 被忽略（由 optimize.Build 预先校验）。
+func New(limits []core.RateLimit, syntax core.PatternSyntax) *Limits {
+	var l *Limits
+	for _, rl := range limits {
+		if rl.Rate <= 0 || rl.Pattern == "" {
+			continue
+		}
+		if l == nil {
+			l = &Limits{matcher: core.NewTrieMatcherWith(syntax), rules: make(map[string][]rule)}
+		}
+		if _, ok := l.rules[rl.Pattern]; !ok {
+			if l.matcher.Add(rl.Pattern) != nil {
+				continue
+			}
+		}
+		l.rules[rl.Pattern] = append(l.rules[rl.Pattern], rule{
+			bucket: NewBucket(rl.Rate, rl.Burst),
+			policy: rl.Policy,
+		})
+	}
+	return l
+}
+
+// Admit 发布路径调用：evt 是否可以发布
+// 返回 false 时调用方不发布并返回 err（RateReject 为 ErrRateLimited，RateDrop 为 nil，
+// RateDelay 等待期间 evt.Context() 结束为 ctx.Err()）。l 为 nil（未配置限流）时直接放行。
+// RateDrop 丢弃的事件若带完成句柄（EmitAwait）则直接完成，等待方不会阻塞。
+func (l *Limits) Admit(evt *core.Event) (bool, error) {
+	if l == nil {
+		return true, nil
+	}
+	return l.admit(evt)
+}
+
+// admit 两阶段检查: 先在全部匹配规则的令牌桶上预定令牌，任一 RateReject / RateDrop 规则令牌不足时
+// 归还已预定的令牌并拒绝（前面规则的令牌不被白白消耗）；全部通过后按最长的 RateDelay 等待时长等待一次。
+func (l *Limits) admit(evt *core.Event) (bool, error) {
+	sp := l.matcher.Match(evt.Type)
+	defer l.matcher.Put(sp)
+	var (
+		buf  [8]*Bucket
+		held = buf[:0]
+		wait time.Duration
+	)
+	for _, pattern := range *sp {
+		for _, r := range l.rules[pattern] {
+			w := r.bucket.Reserve()
+			held = append(held, r.bucket)
+			if w == 0 {
+				continue
+			}
+			if r.policy == core.RateDelay {
+				wait = max(wait, w)
+				continue
+			}
+			cancelAll(held)
+			l.hits.Add()
+			if r.policy == core.RateDrop {
+				if c := evt.Completion(); c != nil {
+					c.Resolve()
+				}
+				return false, nil
+			}
+			return false, core.ErrRateLimited
+		}
+	}
+	if wait > 0 {
+		l.hits.Add()
+		if err := sleep(evt.Context(), wait); err != nil {
+			cancelAll(held)
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// cancelAll 归还预定的令牌
+func cancelAll(held []*Bucket) {
+	for _, b := range held {
+		b.Cancel()
+	}
+}
+
+// Filter 批量发布路径调用：返回可以发布的事件与拒绝错误
+// 被拒绝或丢弃的事件从结果中移除（不修改调用方的 events）；
+// 有事件被拒绝时 err 为 ErrRateLimited（RateDelay 等待中 ctx 结束时为 ctx.Err()），其余事件照常返回。
+// refused 非 nil 时对每个被移除的事件调用一次（如撤销去重记录）。
+func (l *Limits) Filter(events []*core.Event, refused func(*core.Event)) ([]*core.Event, error) {
+	if l == nil {
+		return events, nil
+	}
+	var (
+		out  []*core.Event
+		rerr error
+	)
+	for i, evt := range events {
+		ok := true
+		if evt != nil {
+			var err error
+			if ok, err = l.admit(evt); err != nil && rerr == nil {
+				rerr = err
+			}
+		}
+		if ok {
+			if out != nil {
+				out = append(out, evt)
+			}
+			continue
+		}
+		if refused != nil {
+			refused(evt)
+		}
+		if out == nil {
+			out = make([]*core.Event, i, len(events)-1)
+			copy(out, events[:i])
+		}
+	}
+	if out == nil {
+		return events, rerr
+	}
+	return out, rerr
+}
+
+// Hits 返回发布侧限流命中次数（拒绝、丢弃与延迟均计入；l 为 nil 时为 0）
+func (l *Limits) Hits() int64 {
+	if l == nil {
+		return 0
+	}
+	return l.hits.Read()
+}
+
+// sleep 等待 d，ctx 结束时提前返回 ctx.Err()
+func sleep(ctx context.Context, d time.Duration) error {
+	if ctx.Done() == nil {
+		time.Sleep(d)
+		return nil
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Wrap 按订阅选项包装 handler；未开启订阅侧限流（Rate<=0）时原样返回
+// rt 为订阅的运行环境：合并模式在 Bus 的时间轮上等待令牌，经 rt.Deliver 投递被保留的最新事件
+// （由 Bus 负责 panic 恢复与错误上报；Off 后未投递的事件丢弃）。命中次数计入 hits。
+func Wrap(h core.Handler, o core.SubOptions, hits *Hits, rt core.OperatorRuntime) core.Handler {
+	if o.Rate <= 0 || h == nil {
+		return h
+	}
+	s := &subLimiter{
+		next:   h,
+		bucket: NewBucket(o.Rate, o.Burst),
+		hits:   hits,
+		rt:     rt,
+	}
+	if o.RateMode == core.SubRateCoalesce {
+		return s.coalesce
+	}
+	return s.drop
+}
+
+// subLimiter 订阅侧限流状态
+type subLimiter struct {
+	next   core.Handler
+	bucket *Bucket
+	hits   *Hits
+	rt     core.OperatorRuntime
+
+	mu      sync.Mutex
+	pending *core.Event // 合并模式下等待投递的最新事件（非 nil 表示定时器已启动）
+}
+
+// drop 令牌不足时丢弃事件
+func (s *subLimiter) drop(evt *core.Event) error {
+	if s.bucket.Allow() {
+		return s.next(evt)
+	}
+	s.hits.Add()
+	return nil
+}
+
+// coalesce 令牌不足时保留最新事件，令牌到达时投递
+func (s *subLimiter) coalesce(evt *core.Event) error {
+	s.mu.Lock()
+	if s.pending != nil {
+		// 已有待投递事件: 替换为最新
+		s.pending = evt
+		s.mu.Unlock()
+		s.hits.Add()
+		return nil
+	}
+	if s.bucket.Allow() {
+		s.mu.Unlock()
+		return s.next(evt)
+	}
+	s.pending = evt
+	w := s.bucket.Reserve() // 为待投递事件预定下一个令牌
+	s.mu.Unlock()
+	s.hits.Add()
+	s.rt.After(w, s.flush)
+	return nil
+}
+
+// flush 投递合并后的最新事件
+func (s *subLimiter) flush() {
+	s.mu.Lock()
+	evt := s.pending
+	s.pending = nil
+	s.mu.Unlock()
+	s.rt.Deliver(s.next, evt)
+}
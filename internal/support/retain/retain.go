@@ -0,0 +1,169 @@
+// Package retain 提供保留（sticky）事件存储（三种 Bus 实现共用）
+//
+// 每个事件类型只保留最新一个事件的副本：
+//   - EmitRetain 发布的事件，以及类型匹配配置的保留 pattern 的事件，发布前写入存储
+//     （异步入队的事件先取副本，入队成功后才写入，入队失败的事件不保留）
+//   - 新订阅注册时，按订阅 pattern（含通配符，按 Bus 的 pattern 语法匹配）取出保留事件立即投递
+//   - 存储的是副本（Data/Metadata 深拷贝），发布方复用或池化原事件不影响保留内容
+package retain
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/uniyakcom/beat/core"
+)
+
+// Store 保留事件存储
+type Store struct {
+	auto     bool              // 配置了自动保留的 pattern（创建后只读）
+	patterns *core.TrieMatcher // 自动保留的 pattern（auto=false 时为 nil）
+	syntax   core.PatternSyntax
+
+	mu     sync.RWMutex
+	events map[string]*core.Event // 事件类型 → 最新事件副本
+	bytes  int64                  // 保留事件 Data 总字节数
+}
+
+// New 创建保留事件存储；patterns 中的 pattern（支持通配符，按 syntax 解析）匹配的事件发布时自动保留
+// 语法无效的 pattern 被忽略（由 optimize.Build 预先校验）。
+func New(patterns []string, syntax core.PatternSyntax) *Store {
+	s := &Store{events: make(map[string]*core.Event), syntax: syntax}
+	if len(patterns) > 0 {
+		s.auto = true
+		s.patterns = core.NewTrieMatcherWith(syntax)
+		for _, p := range patterns {
+			s.patterns.Add(p)
+		}
+	}
+	return s
+}
+
+// Auto 是否配置了自动保留的 pattern（批量发布路径据此跳过逐事件检查）
+func (s *Store) Auto() bool {
+	return s.auto
+}
+
+// Offer 发布路径调用：事件类型匹配自动保留 pattern 时写入存储
+// 未配置保留 pattern 时仅一次字段读取（可内联）。
+func (s *Store) Offer(evt *core.Event) {
+	if s.auto {
+		s.offer(evt)
+	}
+}
+
+func (s *Store) offer(evt *core.Event) {
+	if s.patterns.HasMatch(evt.Type) {
+		s.Put(evt)
+	}
+}
+
+// Hold 异步入队前调用：事件类型匹配自动保留 pattern 时返回其副本，否则返回 nil
+// 入队成功后以 Keep 写入；入队后事件归 worker 所有，发布方不再读取原事件。
+func (s *Store) Hold(evt *core.Event) *core.Event {
+	if s.auto && s.patterns.HasMatch(evt.Type) {
+		return clone(evt)
+	}
+	return nil
+}
+
+// Keep 写入 Hold 返回的副本（nil 时无操作）
+func (s *Store) Keep(c *core.Event) {
+	if c != nil {
+		s.keep(c)
+	}
+}
+
+// Put 保留 evt 的副本，替换同类型的旧保留事件
+func (s *Store) Put(evt *core.Event) {
+	s.keep(clone(evt))
+}
+
+// keep 写入副本 c，替换同类型的旧保留事件
+func (s *Store) keep(c *core.Event) {
+	s.mu.Lock()
+	if old, ok := s.events[c.Type]; ok {
+		s.bytes -= int64(len(old.Data))
+	}
+	s.events[c.Type] = c
+	s.bytes += int64(len(c.Data))
+	s.mu.Unlock()
+}
+
+// Match 返回匹配 pattern 的保留事件副本（按类型排序）
+func (s *Store) Match(pattern string) []*core.Event {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.events) == 0 {
+		return nil
+	}
+	var out []*core.Event
+	s.each(pattern, func(evt *core.Event) {
+		out = append(out, clone(evt))
+	})
+	sort.Slice(out, func(i, j int) bool { return out[i].Type < out[j].Type })
+	return out
+}
+
+// Clear 清除匹配 pattern 的保留事件，返回清除数量
+func (s *Store) Clear(pattern string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var types []string
+	s.each(pattern, func(evt *core.Event) {
+		types = append(types, evt.Type)
+	})
+	for _, t := range types {
+		s.bytes -= int64(len(s.events[t].Data))
+		delete(s.events, t)
+	}
+	return len(types)
+}
+
+// Stats 返回保留事件数与 Data 总字节数
+func (s *Store) Stats() (count, bytes int64) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return int64(len(s.events)), s.bytes
+}
+
+// each 对类型匹配 pattern 的保留事件调用 fn（调用方持锁）
+// 精确 pattern 直接查表；通配符 pattern 编译后逐类型匹配（语法无效时不匹配任何事件）。
+func (s *Store) each(pattern string, fn func(*core.Event)) {
+	if !s.syntax.HasWildcard(pattern) {
+		if evt, ok := s.events[pattern]; ok {
+			fn(evt)
+		}
+		return
+	}
+	p, err := s.syntax.Compile(pattern)
+	if err != nil {
+		return
+	}
+	for typ, evt := range s.events {
+		if p.Match(typ) {
+			fn(evt)
+		}
+	}
+}
+
+// clone 复制事件的可导出字段（Data/Metadata 深拷贝，不含 context、完成句柄与回复通道）
+func clone(evt *core.Event) *core.Event {
+	c := &core.Event{
+		Type:      evt.Type,
+		ID:        evt.ID,
+		Key:       evt.Key,
+		Source:    evt.Source,
+		Timestamp: evt.Timestamp,
+	}
+	if evt.Data != nil {
+		c.Data = append([]byte(nil), evt.Data...)
+	}
+	if evt.Metadata != nil {
+		c.Metadata = make(map[string]string, len(evt.Metadata))
+		for k, v := range evt.Metadata {
+			c.Metadata[k] = v
+		}
+	}
+	return c
+}
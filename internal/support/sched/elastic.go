@@ -0,0 +1,205 @@
+package sched
+
+import (
+	"time"
+
+	"github.com/uniyakcom/beat/core"
+)
+
+const (
+	defaultScaleInterval = 100 * time.Millisecond
+
+	// 滞回: 连续满足条件的采样次数（扩容快、缩容慢）
+	growAfter   = 2
+	shrinkAfter = 10
+
+	// 泊车时间占比阈值: 低于 busyIdle 视为 worker 已跟不上，高于 slackIdle 视为有富余
+	busyIdle  = 0.1
+	slackIdle = 0.5
+)
+
+// EnableElastic 启用弹性 worker（必须在 Start 之前调用）
+// worker 数在 [min, 槽位数] 间调整，初始为槽位数（可在 Start 前用 Resize 设定）；
+// interval 为自动伸缩采样间隔（0=100ms，<0 不自动伸缩，仅 Resize 手动调整），observer 接收每次调整（可为 nil）。
+func (ss *ShardedScheduler[T]) EnableElastic(min int, interval time.Duration, observer core.ScaleObserver) {
+	if min < 1 {
+		min = 1
+	}
+	if min > ss.workers {
+		min = ss.workers
+	}
+	if interval == 0 {
+		interval = defaultScaleInterval
+	}
+	ss.elastic = true
+	ss.minW = min
+	ss.interval = interval
+	ss.observer = observer
+	ss.clocks = make([]parkClock, ss.workers)
+}
+
+// Workers 返回当前运行的 worker 数
+func (ss *ShardedScheduler[T]) Workers() int {
+	return int(ss.active.Load())
+}
+
+// Resize 将 worker 数调整为 n（按 [min, 槽位数] 截断），返回调整后的 worker 数
+// 未启用弹性 worker 时不调整；调度器已停止时返回当前值。
+func (ss *ShardedScheduler[T]) Resize(n int) int {
+	return ss.resize(n, core.ScaleEvent{Reason: "manual"})
+}
+
+// resize 调整 worker 数并通知 observer（ev 的 From / To 由本函数填写）
+func (ss *ShardedScheduler[T]) resize(n int, ev core.ScaleEvent) int {
+	ss.resizeMu.Lock()
+	from := int(ss.active.Load())
+	if !ss.elastic || ss.stop.Load() {
+		ss.resizeMu.Unlock()
+		return from
+	}
+	if n < ss.minW {
+		n = ss.minW
+	}
+	if n > ss.workers {
+		n = ss.workers
+	}
+	if n == from {
+		ss.resizeMu.Unlock()
+		return n
+	}
+	ss.active.Store(int32(n))
+	started := ss.loop != nil
+	if started {
+		for id := from; id < n; id++ {
+			ss.spawn(id)
+		}
+		// 全部 worker 重新计算归属；缩容时多出的 worker 醒来后退出
+		for w := range ss.sems {
+			ss.wake(w)
+		}
+	}
+	obs := ss.observer
+	ss.resizeMu.Unlock()
+
+	if started && obs != nil {
+		ev.From, ev.To = from, n
+		obs(ev)
+	}
+	return n
+}
+
+// sample 统计全部 ring（含溢出队列、keyed ring 与共享 ring）的积压总数，
+// 并报告 active=n 时是否有 worker 拥有至少两个积压达一批的 ring（此时加 worker 能分摊；
+// 单个 ring 的积压只能由其属主消费，工作窃取模式除外）。load 为按槽位计数的临时切片。
+func (ss *ShardedScheduler[T]) sample(n int, load []int) (backlog int64, spread bool) {
+	clear(load)
+	count := func(idx int, depth int64) {
+		backlog += depth
+		if depth >= stealBatch {
+			w := idx % n
+			if load[w]++; load[w] > 1 {
+				spread = true
+			}
+		}
+	}
+	for i, r := range ss.rings {
+		depth := int64(r.Len())
+		if ss.spills != nil {
+			depth += ss.spills[i].n.Load()
+		}
+		count(i, depth)
+	}
+	lockedDepth := func(kr *lockedRing[T]) int64 {
+		depth := int64(kr.ring.Len())
+		if kr.sp != nil {
+			depth += kr.sp.n.Load()
+		}
+		return depth
+	}
+	for k, kr := range ss.keyed {
+		count(k, lockedDepth(kr))
+	}
+	if sh := ss.shared.Load(); sh != nil {
+		for s, kr := range *sh {
+			count(s, lockedDepth(kr))
+		}
+	}
+	return backlog, spread
+}
+
+// autoscale 自动伸缩采样循环（Start 时启动，Stop 时退出）
+//   - 扩容: 泊车占比低于 busyIdle，且有 worker 拥有多个积压 ring（工作窃取模式下为总积压超过每 worker 一批），
+//     连续 growAfter 次后加一个 worker
+//   - 缩容: 无积压、无 ring 写满，且泊车占比高于 slackIdle，连续 shrinkAfter 次后减一个 worker
+//   - 任一次调整（含手动 Resize）后计数清零
+func (ss *ShardedScheduler[T]) autoscale() {
+	defer ss.wg.Done()
+	ticker := time.NewTicker(ss.interval)
+	defer ticker.Stop()
+
+	parked := make([]int64, ss.workers) // 各槽位截至上次采样的泊车累计时长
+	load := make([]int, ss.workers)
+	last := time.Now()
+	lastFull := ss.full.Load()
+	lastActive := ss.Workers()
+	hot, cold := 0, 0
+	for {
+		select {
+		case <-ss.done:
+			return
+		case <-ticker.C:
+		}
+
+		now := time.Now()
+		elapsed := now.Sub(last).Nanoseconds()
+		last = now
+		active := ss.Workers()
+		var idleNs int64
+		for id := range parked {
+			t := ss.clocks[id].total.Load()
+			if since := ss.clocks[id].since.Load(); since != 0 {
+				t += now.UnixNano() - since
+			}
+			if d := t - parked[id]; id < active && d > 0 {
+				idleNs += d
+			}
+			parked[id] = t
+		}
+		idle := float64(idleNs) / float64(elapsed*int64(active))
+		if idle > 1 {
+			idle = 1
+		}
+		full := ss.full.Load()
+		fullDelta := full - lastFull
+		lastFull = full
+		backlog, spread := ss.sample(active, load)
+		if ss.steal {
+			spread = backlog > int64(stealBatch*active)
+		}
+
+		if active != lastActive { // 期间有手动 Resize
+			hot, cold = 0, 0
+			lastActive = active
+		}
+		switch {
+		case spread && idle < busyIdle:
+			hot, cold = hot+1, 0
+		case backlog == 0 && fullDelta == 0 && idle > slackIdle:
+			hot, cold = 0, cold+1
+		default:
+			hot, cold = 0, 0
+		}
+
+		ev := core.ScaleEvent{Backlog: backlog, Idle: idle}
+		switch {
+		case hot >= growAfter && active < ss.workers:
+			ev.Reason = "backlog"
+			lastActive = ss.resize(active+1, ev)
+			hot, cold = 0, 0
+		case cold >= shrinkAfter && active > ss.minW:
+			ev.Reason = "idle"
+			lastActive = ss.resize(active-1, ev)
+			hot, cold = 0, 0
+		}
+	}
+}
@@ -10,17 +10,58 @@
 //   - worker[i] 拥有 rings {i, i+workers, i+2*workers, ...}
 //   - workers = NumCPU/2（物理核数），rings = GOMAXPROCS（逻辑核数）
 //   - 例: 6C/12T → 12 rings, 6 workers, 每 worker 2 rings
+//
+// GOMAXPROCS 在创建后调大时：
+//   - ring 数在创建时按当时的 GOMAXPROCS 确定，之后 P 编号可能超出 ring 数
+//   - 超出范围的 P 不再按位掩码折叠到已有 ring（那会让两个 P 共写一个 ring，破坏 SPSC 单写者），
+//     而是经互斥锁写入按 worker 分配的共享 ring（首次出现时惰性创建），由对应 worker 消费
+//   - 范围内的 P 仍走零 CAS 快速路径，判断只多一次比较
+//
+// 工作窃取（EnableStealing 后可用）：
+//   - 每个 ring 的消费端由一个租约（progress.lease）保护：持有者独占 Dequeue，满足 SPSC 单读者
+//   - 属主取出一批（最多 32 个）后立即释放租约再处理；自身 ring 全空的 worker 选积压最多的 ring 抢租约取走一批
+//   - 生产者在 ring 积压达到一批时额外唤醒一个非属主 worker，属主被慢 handler 卡住时积压仍能被消费
+//   - 顺序：同一 ring 的元素按入队顺序出队、同一批内按顺序处理，但不同批次可能由不同 worker 并发处理，
+//     因此不再保证同一 ring 内的处理顺序；keyed ring 与共享 ring 不参与窃取，按键有序不受影响
+//
+// 弹性 worker（EnableElastic 后可用）：
+//   - worker 槽位数固定为创建时的 workers（上限），运行中的 worker 数 active 在 [min, workers] 间调整
+//   - ring 归属随 active 变化（第 r 个 ring 属于 r % active 号 worker），keyed / 共享 ring 的数量与槽位数一致，
+//     提交端的选择不受 active 影响，同一 key 始终落到同一 ring
+//   - 交接安全: 弹性模式下 worker 持 ring 的消费租约处理整批，新属主在旧属主处理完本批前跳过该 ring，
+//     任一时刻每个 ring 至多一个消费者，ring 内顺序与按键有序均不受伸缩影响
+//   - 缩容时多出的 worker 退出，扩容时为空槽位启动 worker；Resize 后唤醒全部 worker 重新计算归属
+//   - 自动伸缩: 采样 goroutine 按 ring 积压、ring 写满次数与 worker 泊车时间占比决定加减，带滞回
+//
+// 按键有序（EnableKeyed 后可用）：
+//   - 额外为每个 worker 建一个 keyed ring，SubmitKeyed 按 key 哈希选择 ring
+//   - 同一 key 固定落到同一 ring → 同一 worker 串行消费 → per-key FIFO
+//   - keyed ring 由多个生产者共享，写入侧用 per-ring mutex 保证单写者
+//
+// 溢出策略（SetOverflow，默认 OverflowBlock）：
+//   - ring 满时按 core.OverflowPolicy 阻塞、超时失败、丢弃或写入溢出队列
+//   - 溢出队列非空期间，新元素一律追加到溢出队列，worker 在 ring 取空后再消费，保持 ring 内 FIFO
+//
+// Barrier：
+//   - 每个 ring（含 keyed ring）维护累计完成数 done，worker 每批消费后累加
+//   - Barrier 快照各 ring 的累计入队数（ring tail + 溢出队列累计入队数），等待 done 追上
 package sched
 
 import (
+	"context"
 	"runtime"
 	"sync"
 	"sync/atomic"
-	_ "unsafe"
+	"time"
+	"unsafe"
 
+	"github.com/uniyakcom/beat/core"
 	sl "github.com/uniyakcom/beat/internal/support/spsc"
 )
 
+// defaultOverflowTimeout OverflowBlockTimeout 未指定超时时的默认值
+const defaultOverflowTimeout = 100 * time.Millisecond
+
 //go:linkname runtime_procPin runtime.procPin
 func runtime_procPin() int
 
@@ -30,19 +71,75 @@ func runtime_procUnpin()
 //go:linkname runtime_procyield runtime.procyield
 func runtime_procyield(cycles uint32)
 
+// progress 单个 ring 的消费端状态（独占缓存行，避免与相邻 ring 伪共享）
+type progress struct {
+	done  atomic.Uint64 // 累计完成数
+	lease atomic.Bool   // 消费租约（仅工作窃取模式使用）
+	_     [64 - unsafe.Sizeof(atomic.Uint64{}) - unsafe.Sizeof(atomic.Bool{})]byte
+}
+
+// stealBatch 工作窃取模式下每次取出的最大批量（亦为唤醒非属主 worker 的积压阈值）
+const stealBatch = 32
+
+// parkClock 单个 worker 槽位的泊车计时（弹性模式下采样空闲占比，独占缓存行）
+type parkClock struct {
+	since atomic.Int64 // 当前泊车开始时间（UnixNano，0=未泊车）
+	total atomic.Int64 // 已结束的泊车累计时长（ns）
+	_     [64 - 2*unsafe.Sizeof(atomic.Int64{})]byte
+}
+
 // ShardedScheduler SPSC 分片调度器
 // 每个 P 有独立的 SPSC ring（零 CAS），worker 按静态亲和性消费
 type ShardedScheduler[T any] struct {
 	rings    []*sl.SPSCRing[T]
+	ringSize uint64
 	numRings int
-	ringMask int // numRings-1（2 的幂），用于 pid&mask 替代 pid%numRings
-	workers  int
+	workers  int // worker 槽位数（弹性模式下为上限）
+	active   atomic.Int32
+	alive    []atomic.Bool // 槽位上是否有 worker goroutine 在运行
 	wg       sync.WaitGroup
 	stop     atomic.Bool
 	done     chan struct{}
 	OnPanic  func(any)
+	OnDrop   func(T) // 元素被溢出策略丢弃时回调（可为 nil，Start 前设置）
 	parked   atomic.Int32
-	sem      chan struct{}
+	sems     []chan struct{}  // 每个 worker 一个唤醒信号（容量 1），只唤醒 ring 的属主
+	keyed    []*lockedRing[T] // worker[i] 独占 keyed[i]（nil=未启用按键有序）
+	progress []progress       // 与 rings 对齐的累计完成数
+
+	// 超出 ring 数的 P 使用的共享 ring（worker[i] 消费 shared[i]；GOMAXPROCS 未调大时为 nil）
+	shared   atomic.Pointer[[]*lockedRing[T]]
+	sharedMu sync.Mutex // 串行化 shared 的惰性创建
+
+	// 工作窃取（Start 前设置，运行期只读）
+	steal    bool
+	stolen   atomic.Int64  // 被非属主 worker 取走的元素数
+	wakeNext atomic.Uint32 // 轮流唤醒的非属主 worker
+
+	// 弹性 worker（EnableElastic 后可用）
+	elastic  bool
+	minW     int
+	interval time.Duration
+	observer core.ScaleObserver
+	clocks   []parkClock  // 与槽位对齐
+	full     atomic.Int64 // 提交时 ring 已满的次数（扩容信号）
+	resizeMu sync.Mutex   // 串行化 Resize / Stop
+	loop     func(T)      // Start 传入，扩容时启动新 worker 使用
+
+	// 溢出策略（Start 前设置，运行期只读）
+	policy  core.OverflowPolicy
+	timeout time.Duration
+	spills  []*spill[T] // 与 rings 对齐（仅 Spill/DropOldest 策略分配）
+	dropped atomic.Int64
+}
+
+// lockedRing 多生产者 ring：生产者经 mu 串行化后满足 SPSC 单写者
+// 用于按键有序（keyed）与超出 ring 数的 P（shared）。
+type lockedRing[T any] struct {
+	mu   sync.Mutex
+	ring *sl.SPSCRing[T]
+	sp   *spill[T] // 溢出队列（仅 Spill/DropOldest 策略分配）
+	prog progress
 }
 
 // NewShardedScheduler 创建 SPSC 分片调度器
@@ -73,11 +170,17 @@ func NewShardedScheduler[T any](ringSize uint64, workers int) *ShardedScheduler[
 
 	ss := &ShardedScheduler[T]{
 		rings:    make([]*sl.SPSCRing[T], numRings),
+		progress: make([]progress, numRings),
+		ringSize: ringSize,
 		numRings: numRings,
-		ringMask: numRings - 1,
 		workers:  workers,
 		done:     make(chan struct{}),
-		sem:      make(chan struct{}, workers),
+		sems:     make([]chan struct{}, workers),
+		alive:    make([]atomic.Bool, workers),
+	}
+	ss.active.Store(int32(workers))
+	for i := range ss.sems {
+		ss.sems[i] = make(chan struct{}, 1)
 	}
 	for i := 0; i < numRings; i++ {
 		ss.rings[i] = sl.NewSPSCRing[T](ringSize)
@@ -85,91 +188,437 @@ func NewShardedScheduler[T any](ringSize uint64, workers int) *ShardedScheduler[
 	return ss
 }
 
+// SetOverflow 设置 ring 满时的处理策略（必须在 Start 之前调用）
+// timeout 仅 OverflowBlockTimeout 使用（<=0 时为 100ms）。
+func (ss *ShardedScheduler[T]) SetOverflow(policy core.OverflowPolicy, timeout time.Duration) {
+	if timeout <= 0 {
+		timeout = defaultOverflowTimeout
+	}
+	ss.policy = policy
+	ss.timeout = timeout
+}
+
+// Dropped 返回因溢出策略被丢弃（或超时未能入队）的元素数
+func (ss *ShardedScheduler[T]) Dropped() int64 {
+	return ss.dropped.Load()
+}
+
+// newSpill 按策略创建溢出队列（不需要时返回 nil）
+func (ss *ShardedScheduler[T]) newSpill() *spill[T] {
+	switch ss.policy {
+	case core.OverflowSpill:
+		return &spill[T]{}
+	case core.OverflowDropOldest:
+		return &spill[T]{max: int(ss.ringSize)}
+	}
+	return nil
+}
+
 // Submit producer 入队 — procPin 保证 SPSC 单写者
 // 快速路径: procPin → SPSC Enqueue (零 CAS) → procUnpin
-func (ss *ShardedScheduler[T]) Submit(v T) {
+// 仅 OverflowBlockTimeout 超时时返回 core.ErrQueueFull。
+func (ss *ShardedScheduler[T]) Submit(v T) error {
+	return ss.SubmitCtx(nil, v)
+}
+
+// SubmitCtx 同 Submit，ring 满需要等待时 ctx 结束即放弃入队并返回 ctx.Err()
+// ctx 为 nil 表示不可取消。
+func (ss *ShardedScheduler[T]) SubmitCtx(ctx context.Context, v T) error {
 	// 快速路径：pin 住当前 P，选择对应 ring，写入
 	// procPin 必须覆盖 Enqueue 全程以保证 SPSC 单写者
 	pid := runtime_procPin()
-	ring := ss.rings[pid&ss.ringMask]
-	ok := ring.Enqueue(v)
+	if pid >= ss.numRings {
+		// GOMAXPROCS 在创建后调大: 该 P 没有独占 ring，改走互斥的共享 ring
+		runtime_procUnpin()
+		w := pid % ss.workers
+		return ss.submitLocked(ctx, w, ss.sharedRing(w), v)
+	}
+	idx := pid
+	// 溢出队列有积压时不得绕过它直接写 ring（保持 FIFO）
+	ok := (ss.spills == nil || ss.spills[idx].n.Load() == 0) && ss.rings[idx].Enqueue(v)
 	runtime_procUnpin()
 
 	if !ok {
-		// 慢路径：ring 满，背压重试（极少触发）
-		ss.submitSlow(v)
+		// 慢路径：ring 满，按溢出策略处理（极少触发）
+		if err := ss.submitSlow(ctx, idx, v); err != nil {
+			return err
+		}
 	}
 
-	// 唤醒泊车 worker（仅在有 worker 泊车时）
+	// 唤醒泊车的 ring 属主 worker（仅在有 worker 泊车时）
+	ss.wakeRing(idx)
+	return nil
+}
+
+// wakeRing 唤醒第 idx 个 ring 的属主 worker（无泊车时零开销）
+// 必须唤醒属主：其他 worker 不消费该 ring，唤醒它们会让元素滞留到下一次提交。
+func (ss *ShardedScheduler[T]) wakeRing(idx int) {
 	if ss.parked.Load() > 0 {
-		select {
-		case ss.sem <- struct{}{}:
-		default:
+		n := int(ss.active.Load())
+		owner := idx % n
+		ss.wake(owner)
+		// 工作窃取: 积压达到一批时再唤醒一个其他 worker（属主可能正被慢 handler 占住）
+		if ss.steal && n > 1 && ss.rings[idx].Len() >= stealBatch {
+			w := int(ss.wakeNext.Add(1) % uint32(n))
+			if w == owner {
+				w = (w + 1) % n
+			}
+			ss.wake(w)
 		}
 	}
 }
 
-// submitSlow ring 满时的背压重试
-func (ss *ShardedScheduler[T]) submitSlow(v T) {
+// wake 向第 w 个 worker 发送唤醒信号（已有未消费信号时跳过）
+func (ss *ShardedScheduler[T]) wake(w int) {
+	select {
+	case ss.sems[w] <- struct{}{}:
+	default:
+	}
+}
+
+// submitSlow ring 满时按溢出策略处理
+func (ss *ShardedScheduler[T]) submitSlow(ctx context.Context, idx int, v T) error {
+	ss.full.Add(1)
+	switch ss.policy {
+	case core.OverflowDropNewest:
+		ss.drop(v)
+		return nil
+	case core.OverflowSpill, core.OverflowDropOldest:
+		if old, dropped := ss.spills[idx].push(v); dropped {
+			ss.progress[idx].done.Add(1) // 被挤出的元素视为已完成
+			ss.drop(old)
+		}
+		return nil
+	}
+
+	// OverflowBlock / OverflowBlockTimeout: 背压重试
+	var deadline time.Time
+	if ss.policy == core.OverflowBlockTimeout {
+		deadline = time.Now().Add(ss.timeout)
+	}
 	for {
 		runtime.Gosched() // 让出 CPU 让 consumer 消费
 		pid := runtime_procPin()
-		ring := ss.rings[pid&ss.ringMask]
-		ok := ring.Enqueue(v)
+		if pid >= ss.numRings {
+			// 让出后迁移到了超出 ring 数的 P: 余下的等待交给共享 ring
+			runtime_procUnpin()
+			w := pid % ss.workers
+			return ss.submitLocked(ctx, w, ss.sharedRing(w), v)
+		}
+		retry := pid
+		ok := ss.rings[retry].Enqueue(v)
 		runtime_procUnpin()
 		if ok {
-			return
+			ss.wakeRing(retry) // 重试可能落在其他 P 的 ring
+			return nil
+		}
+		if ctx != nil {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			ss.dropped.Add(1)
+			return core.ErrQueueFull
 		}
 	}
 }
 
+// drop 记录一次溢出丢弃并通知 OnDrop
+func (ss *ShardedScheduler[T]) drop(v T) {
+	ss.dropped.Add(1)
+	if ss.OnDrop != nil {
+		ss.OnDrop(v)
+	}
+}
+
+// EnableStealing 启用工作窃取（必须在 Start 之前调用）
+// 空闲 worker 从积压最多的 ring 取走批次处理；同一 ring 内的处理顺序不再保证（见包文档）。
+func (ss *ShardedScheduler[T]) EnableStealing() {
+	ss.steal = true
+}
+
+// Stolen 返回被非属主 worker 取走处理的元素数
+func (ss *ShardedScheduler[T]) Stolen() int64 {
+	return ss.stolen.Load()
+}
+
+// EnableKeyed 启用按键有序投递（为每个 worker 分配一个 keyed ring）
+// 必须在 Start 之前调用。
+func (ss *ShardedScheduler[T]) EnableKeyed() {
+	if ss.keyed != nil {
+		return
+	}
+	ss.keyed = make([]*lockedRing[T], ss.workers)
+	for i := range ss.keyed {
+		ss.keyed[i] = &lockedRing[T]{ring: sl.NewSPSCRing[T](ss.ringSize)}
+	}
+}
+
+// SubmitKeyed 按 key 哈希入队到固定 ring（需先 EnableKeyed）
+// 同一 key 的元素按提交顺序由同一 worker 依次处理；ring 满时持锁按溢出策略处理以保持顺序。
+func (ss *ShardedScheduler[T]) SubmitKeyed(key string, v T) error {
+	return ss.SubmitKeyedCtx(nil, key, v)
+}
+
+// SubmitKeyedCtx 同 SubmitKeyed，ring 满需要等待时 ctx 结束即放弃入队并返回 ctx.Err()
+func (ss *ShardedScheduler[T]) SubmitKeyedCtx(ctx context.Context, key string, v T) error {
+	w := int(keyHash(key) % uint64(len(ss.keyed)))
+	return ss.submitLocked(ctx, w, ss.keyed[w], v)
+}
+
+// submitLocked 持 kr.mu 写入第 w 个多生产者 ring，并唤醒其属主 worker
+func (ss *ShardedScheduler[T]) submitLocked(ctx context.Context, w int, kr *lockedRing[T], v T) error {
+	var err error
+	kr.mu.Lock()
+	if (kr.sp != nil && kr.sp.n.Load() > 0) || !kr.ring.Enqueue(v) {
+		err = ss.submitLockedSlow(ctx, kr, v)
+	}
+	kr.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if ss.parked.Load() > 0 {
+		ss.wake(w % int(ss.active.Load()))
+	}
+	return nil
+}
+
+// sharedRing 返回超出 ring 数的 P 写入第 w 个 worker 时使用的共享 ring（首次调用时创建全部 worker 的共享 ring）
+func (ss *ShardedScheduler[T]) sharedRing(w int) *lockedRing[T] {
+	if p := ss.shared.Load(); p != nil {
+		return (*p)[w]
+	}
+	ss.sharedMu.Lock()
+	defer ss.sharedMu.Unlock()
+	if p := ss.shared.Load(); p != nil {
+		return (*p)[w]
+	}
+	rings := make([]*lockedRing[T], ss.workers)
+	for i := range rings {
+		rings[i] = &lockedRing[T]{ring: sl.NewSPSCRing[T](ss.ringSize), sp: ss.newSpill()}
+	}
+	ss.shared.Store(&rings)
+	return rings[w]
+}
+
+// submitLockedSlow 多生产者 ring 满时按溢出策略处理（调用方持有 kr.mu）
+func (ss *ShardedScheduler[T]) submitLockedSlow(ctx context.Context, kr *lockedRing[T], v T) error {
+	ss.full.Add(1)
+	switch ss.policy {
+	case core.OverflowDropNewest:
+		ss.drop(v)
+		return nil
+	case core.OverflowSpill, core.OverflowDropOldest:
+		if old, dropped := kr.sp.push(v); dropped {
+			kr.prog.done.Add(1)
+			ss.drop(old)
+		}
+		return nil
+	}
+
+	var deadline time.Time
+	if ss.policy == core.OverflowBlockTimeout {
+		deadline = time.Now().Add(ss.timeout)
+	}
+	for !kr.ring.Enqueue(v) {
+		if ctx != nil {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			ss.dropped.Add(1)
+			return core.ErrQueueFull
+		}
+		runtime.Gosched() // 让出 CPU 让 consumer 消费
+	}
+	return nil
+}
+
+// keyHash FNV-1a 64 位哈希（零分配）
+func keyHash(key string) uint64 {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(key); i++ {
+		h ^= uint64(key[i])
+		h *= 1099511628211
+	}
+	return h
+}
+
 // Start 启动 workers
 func (ss *ShardedScheduler[T]) Start(loop func(T)) {
-	for i := 0; i < ss.workers; i++ {
+	// 溢出队列按最终策略一次性分配（Start 后只读）
+	if ss.policy == core.OverflowSpill || ss.policy == core.OverflowDropOldest {
+		ss.spills = make([]*spill[T], ss.numRings)
+		for i := range ss.spills {
+			ss.spills[i] = ss.newSpill()
+		}
+		for _, kr := range ss.keyed {
+			kr.sp = ss.newSpill()
+		}
+	}
+	ss.resizeMu.Lock()
+	defer ss.resizeMu.Unlock()
+	ss.loop = loop
+	for i := 0; i < int(ss.active.Load()); i++ {
+		ss.spawn(i)
+	}
+	if ss.elastic && ss.interval > 0 {
 		ss.wg.Add(1)
-		go ss.worker(i, loop)
+		go ss.autoscale()
+	}
+}
+
+// spawn 在空槽位 id 上启动 worker（调用方持有 resizeMu）
+func (ss *ShardedScheduler[T]) spawn(id int) {
+	if ss.alive[id].CompareAndSwap(false, true) {
+		ss.wg.Add(1)
+		go ss.worker(id, ss.loop)
 	}
 }
 
 func (ss *ShardedScheduler[T]) worker(id int, loop func(T)) {
 	defer ss.wg.Done()
 
-	// 计算该 worker 拥有的 rings（静态分配，不可偷取）
-	owned := make([]int, 0, (ss.numRings+ss.workers-1)/ss.workers)
-	for r := id; r < ss.numRings; r += ss.workers {
-		owned = append(owned, r)
+	var buf []T
+	if ss.spills != nil || ss.steal {
+		buf = make([]T, stealBatch)
 	}
 
-	for !ss.stop.Load() {
-		ss.workerLoop(owned, loop)
+	for {
+		for !ss.stop.Load() && id < int(ss.active.Load()) {
+			ss.workerLoop(id, buf, loop)
+		}
+		// 缩容退出: 若退出前又被扩容纳入且槽位尚未被新 goroutine 占用，则继续运行
+		ss.alive[id].Store(false)
+		if ss.stop.Load() || id >= int(ss.active.Load()) || !ss.alive[id].CompareAndSwap(false, true) {
+			return
+		}
 	}
 }
 
-func (ss *ShardedScheduler[T]) workerLoop(owned []int, loop func(T)) {
+// assignment 第 id 个 worker 在 active=n 时拥有的 ring（静态分配；弹性模式下随 n 重新计算）
+type assignment[T any] struct {
+	n     int
+	owned []int            // Per-P ring 下标
+	keyed []*lockedRing[T] // keyed ring（按键有序，仅本 worker 消费）
+}
+
+func (ss *ShardedScheduler[T]) assign(id, n int) assignment[T] {
+	a := assignment[T]{n: n, owned: make([]int, 0, (ss.numRings+n-1)/n)}
+	for r := id; r < ss.numRings; r += n {
+		a.owned = append(a.owned, r)
+	}
+	for k := id; k < len(ss.keyed); k += n {
+		a.keyed = append(a.keyed, ss.keyed[k])
+	}
+	return a
+}
+
+// drain 从 ring 批量消费最多 32 个元素；ring 取空后再消费溢出队列（保持 FIFO）
+// 本批处理完成后一次性累加 prog.done（loop 自身需保证不 panic，否则本批不计入）。
+func drain[T any](ring *sl.SPSCRing[T], sp *spill[T], prog *progress, buf []T, loop func(T)) bool {
+	var done uint64
+	for i := 0; i < 32; i++ {
+		t, ok := ring.Dequeue()
+		if !ok {
+			if sp != nil && sp.n.Load() > 0 {
+				n := sp.popBatch(buf)
+				for j := 0; j < n; j++ {
+					loop(buf[j])
+					var zero T
+					buf[j] = zero
+				}
+				done += uint64(n)
+			}
+			break
+		}
+		loop(t)
+		done++
+	}
+	if done == 0 {
+		return false
+	}
+	prog.done.Add(done)
+	return true
+}
+
+// drainOwned 消费本 worker 拥有的 ring；弹性模式下持消费租约处理整批（归属交接期间新旧属主不会同时消费）
+func (ss *ShardedScheduler[T]) drainOwned(ring *sl.SPSCRing[T], sp *spill[T], prog *progress, buf []T, loop func(T)) bool {
+	if !ss.elastic {
+		return drain(ring, sp, prog, buf, loop)
+	}
+	if !prog.lease.CompareAndSwap(false, true) {
+		return false
+	}
+	defer prog.lease.Store(false)
+	return drain(ring, sp, prog, buf, loop)
+}
+
+func (ss *ShardedScheduler[T]) workerLoop(id int, buf []T, loop func(T)) {
 	defer func() {
 		if r := recover(); r != nil && ss.OnPanic != nil {
 			ss.OnPanic(r)
 		}
 	}()
 
+	a := ss.assign(id, int(ss.active.Load()))
+	sem := ss.sems[id]
 	idle := 0
 	for !ss.stop.Load() {
+		if int(ss.active.Load()) != a.n {
+			return // worker 数已调整: 由 worker 重新计算归属（或退出）
+		}
 		consumed := false
 
-		// 轮询拥有的 rings（SPSC Dequeue = 零 CAS）
-		for _, ringIdx := range owned {
-			ring := ss.rings[ringIdx]
-			// 每个 ring 批量消费最多 32 个事件
-			for i := 0; i < 32; i++ {
-				t, ok := ring.Dequeue()
-				if !ok {
-					break
+		// 轮询拥有的 rings（SPSC Dequeue = 零 CAS，每个 ring 批量消费最多 32 个事件）
+		for _, ringIdx := range a.owned {
+			if ss.steal {
+				// 工作窃取: 持租约取批，释放后再处理
+				if n := ss.take(ringIdx, buf); n > 0 {
+					ss.run(ringIdx, buf[:n], loop)
+					consumed = true
 				}
-				loop(t)
+				continue
+			}
+			var sp *spill[T]
+			if ss.spills != nil {
+				sp = ss.spills[ringIdx]
+			}
+			if ss.drainOwned(ss.rings[ringIdx], sp, &ss.progress[ringIdx], buf, loop) {
+				consumed = true
+			}
+		}
+
+		// keyed ring（按键有序，仅本 worker 消费）
+		for _, kr := range a.keyed {
+			if ss.drainOwned(kr.ring, kr.sp, &kr.prog, buf, loop) {
 				consumed = true
 			}
 		}
 
+		// 共享 ring（超出 ring 数的 P 写入，仅在 GOMAXPROCS 调大后存在）
+		if sh := ss.shared.Load(); sh != nil {
+			for s := id; s < len(*sh); s += a.n {
+				if r := (*sh)[s]; ss.drainOwned(r.ring, r.sp, &r.prog, buf, loop) {
+					consumed = true
+				}
+			}
+		}
+
+		// 自身无事可做: 从积压最多的 ring 窃取一批
+		if !consumed && ss.steal {
+			if victim := ss.victim(); victim >= 0 {
+				if n := ss.take(victim, buf); n > 0 {
+					ss.run(victim, buf[:n], loop)
+					ss.stolen.Add(int64(n))
+					consumed = true
+				}
+			}
+		}
+
 		if consumed {
 			idle = 0
 			continue
@@ -194,20 +643,233 @@ func (ss *ShardedScheduler[T]) workerLoop(owned []int, loop func(T)) {
 
 		// Level 2: 泊车等待唤醒
 		ss.parked.Add(1)
+		// 泊车前复查: 生产者在上次轮询之后、parked 计数之前入队时看不到泊车者，不会发出唤醒
+		if ss.pending(id, &a) {
+			ss.parked.Add(-1)
+			idle = 0
+			continue
+		}
+		var clock *parkClock
+		if ss.clocks != nil {
+			clock = &ss.clocks[id]
+			clock.since.Store(time.Now().UnixNano())
+		}
 		select {
-		case <-ss.sem:
+		case <-sem:
 			ss.parked.Add(-1)
 			idle = 0
 		case <-ss.done:
 			ss.parked.Add(-1)
 			return
 		}
+		if clock != nil {
+			clock.total.Add(time.Now().UnixNano() - clock.since.Swap(0))
+		}
+	}
+}
+
+// take 持第 idx 个 ring 的消费租约取出最多 len(buf) 个元素（租约被占用时返回 0）
+// ring 取空后再取溢出队列（保持 FIFO）。取出后即释放租约，处理在租约之外进行。
+func (ss *ShardedScheduler[T]) take(idx int, buf []T) int {
+	prog := &ss.progress[idx]
+	if !prog.lease.CompareAndSwap(false, true) {
+		return 0
+	}
+	ring := ss.rings[idx]
+	n := 0
+	for n < len(buf) {
+		t, ok := ring.Dequeue()
+		if !ok {
+			if ss.spills != nil && ss.spills[idx].n.Load() > 0 {
+				n += ss.spills[idx].popBatch(buf[n:])
+			}
+			break
+		}
+		buf[n] = t
+		n++
+	}
+	prog.lease.Store(false)
+	return n
+}
+
+// run 依次处理 take 取出的一批元素，完成后累加第 idx 个 ring 的完成数
+func (ss *ShardedScheduler[T]) run(idx int, batch []T, loop func(T)) {
+	var zero T
+	for i := range batch {
+		loop(batch[i])
+		batch[i] = zero
+	}
+	ss.progress[idx].done.Add(uint64(len(batch)))
+}
+
+// victim 返回积压最多的 ring（均为空时返回 -1）
+func (ss *ShardedScheduler[T]) victim() int {
+	best, most := -1, uint64(0)
+	for i, r := range ss.rings {
+		n := r.Len()
+		if ss.spills != nil {
+			n += uint64(ss.spills[i].n.Load())
+		}
+		if n > most {
+			best, most = i, n
+		}
+	}
+	return best
+}
+
+// pending 报告第 id 个 worker 拥有的 ring / 溢出队列 / keyed ring / 共享 ring 是否有积压
+func (ss *ShardedScheduler[T]) pending(id int, a *assignment[T]) bool {
+	if int(ss.active.Load()) != a.n {
+		return true // worker 数已调整: 回到循环顶部重新计算归属
+	}
+	for _, i := range a.owned {
+		if ss.rings[i].Len() > 0 || (ss.spills != nil && ss.spills[i].n.Load() > 0) {
+			return true
+		}
+	}
+	if sh := ss.shared.Load(); sh != nil {
+		for s := id; s < len(*sh); s += a.n {
+			if (*sh)[s].backlog() {
+				return true
+			}
+		}
+	}
+	if ss.steal && ss.victim() >= 0 {
+		return true // 工作窃取: 任一 ring 有积压都不泊车
+	}
+	for _, kr := range a.keyed {
+		if kr.backlog() {
+			return true
+		}
+	}
+	return false
+}
+
+// backlog 报告 ring 或溢出队列是否有积压
+func (kr *lockedRing[T]) backlog() bool {
+	return kr.ring.Len() > 0 || (kr.sp != nil && kr.sp.n.Load() > 0)
+}
+
+// Depth 返回全部 ring（含溢出队列、keyed ring 与共享 ring）的当前积压总数（近似值）
+func (ss *ShardedScheduler[T]) Depth() int64 {
+	var depth int64
+	for i, r := range ss.rings {
+		depth += int64(r.Len())
+		if ss.spills != nil {
+			depth += ss.spills[i].n.Load()
+		}
+	}
+	locked := ss.keyed
+	if sh := ss.shared.Load(); sh != nil {
+		locked = append(locked[:len(locked):len(locked)], *sh...)
+	}
+	for _, kr := range locked {
+		depth += int64(kr.ring.Len())
+		if kr.sp != nil {
+			depth += kr.sp.n.Load()
+		}
+	}
+	return depth
+}
+
+// QueueStats 返回各 ring 的积压、容量、最早采样元素的等待时长与入队→出队延迟分位数
+// 顺序: Per-P ring、keyed ring、共享 ring（各自按下标）。
+func (ss *ShardedScheduler[T]) QueueStats() []core.QueueStat {
+	stat := func(kind string, i int, ring *sl.SPSCRing[T], sp *spill[T]) core.QueueStat {
+		q := core.QueueStat{
+			Kind:      kind,
+			Index:     i,
+			Depth:     int64(ring.Len()),
+			Capacity:  int64(ring.Cap()),
+			OldestAge: ring.OldestAge(),
+		}
+		lat := ring.Latency()
+		q.Samples, q.LatencyP50, q.LatencyP99, q.LatencyMax = lat.Count, lat.Percentile(50), lat.Percentile(99), lat.Max
+		if sp != nil {
+			q.Depth += sp.n.Load()
+		}
+		return q
+	}
+	stats := make([]core.QueueStat, 0, len(ss.rings)+len(ss.keyed))
+	for i, r := range ss.rings {
+		var sp *spill[T]
+		if ss.spills != nil {
+			sp = ss.spills[i]
+		}
+		stats = append(stats, stat("ring", i, r, sp))
+	}
+	for k, kr := range ss.keyed {
+		stats = append(stats, stat("keyed", k, kr.ring, kr.sp))
+	}
+	if sh := ss.shared.Load(); sh != nil {
+		for s, kr := range *sh {
+			stats = append(stats, stat("shared", s, kr.ring, kr.sp))
+		}
+	}
+	return stats
+}
+
+// Barrier 等待调用前已提交的元素全部处理完毕（或被溢出策略丢弃）
+// ctx 结束时返回 ctx.Err()；调度器已停止时返回 core.ErrClosed。
+// ctx 为 nil 表示不可取消。
+func (ss *ShardedScheduler[T]) Barrier(ctx context.Context) error {
+	// 快照各 ring 的目标完成数（ring tail + 溢出队列累计入队数）
+	locked := ss.keyed
+	if sh := ss.shared.Load(); sh != nil {
+		locked = append(locked[:len(locked):len(locked)], *sh...)
+	}
+	targets := make([]uint64, len(ss.rings)+len(locked))
+	progs := make([]*progress, 0, len(targets))
+	for i, r := range ss.rings {
+		targets[i] = r.Enqueued()
+		if ss.spills != nil {
+			targets[i] += ss.spills[i].pushed.Load()
+		}
+		progs = append(progs, &ss.progress[i])
+	}
+	for i, kr := range locked {
+		kr.mu.Lock() // 与 submitLocked 串行，保证读到完整的入队计数
+		t := kr.ring.Enqueued()
+		if kr.sp != nil {
+			t += kr.sp.pushed.Load()
+		}
+		kr.mu.Unlock()
+		targets[len(ss.rings)+i] = t
+		progs = append(progs, &kr.prog)
+	}
+
+	// 逐 ring 等待：先协作让出，再指数退避睡眠（上限 1ms）
+	backoff := time.Duration(0)
+	for i, p := range progs {
+		for p.done.Load() < targets[i] {
+			if ss.stop.Load() {
+				return core.ErrClosed
+			}
+			if ctx != nil {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+			switch {
+			case backoff < 16*time.Microsecond:
+				runtime.Gosched()
+				backoff += time.Microsecond
+			case backoff < time.Millisecond:
+				backoff *= 2
+				time.Sleep(backoff)
+			default:
+				time.Sleep(time.Millisecond)
+			}
+		}
 	}
+	return nil
 }
 
 // Stop 停止所有 workers
 func (ss *ShardedScheduler[T]) Stop() {
+	ss.resizeMu.Lock() // 与 Resize 串行: Stop 之后不再启动新 worker
 	ss.stop.Store(true)
 	close(ss.done) // 通知所有 parked workers 退出
+	ss.resizeMu.Unlock()
 	ss.wg.Wait()
 }
@@ -0,0 +1,67 @@
+package sched
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// spill ring 满时的溢出队列（mutex 保护的 FIFO，仅慢路径使用）
+//   - OverflowSpill: 无界（max=0）
+//   - OverflowDropOldest: 有界（max=ringSize），满时丢弃队首（最旧）元素
+//
+// n 供生产者/消费者无锁探测是否有积压：n>0 时生产者必须继续写入溢出队列，
+// 保证同一 ring 内先入队者先处理。
+type spill[T any] struct {
+	mu   sync.Mutex
+	q    []T
+	head int
+	max  int
+	n    atomic.Int64
+
+	pushed atomic.Uint64 // 累计入队数（Barrier 目标计算）
+}
+
+// push 追加到队尾，队列满时丢弃并返回队首元素
+func (s *spill[T]) push(v T) (old T, dropped bool) {
+	s.mu.Lock()
+	if s.max > 0 && len(s.q)-s.head >= s.max {
+		var zero T
+		old = s.q[s.head]
+		s.q[s.head] = zero
+		s.head++
+		dropped = true
+	}
+	// 已消费前缀过半时前移，避免底层数组只增不减
+	if s.head > 0 && s.head >= len(s.q)/2 {
+		n := copy(s.q, s.q[s.head:])
+		var zero T
+		for i := n; i < len(s.q); i++ {
+			s.q[i] = zero
+		}
+		s.q = s.q[:n]
+		s.head = 0
+	}
+	s.q = append(s.q, v)
+	s.n.Store(int64(len(s.q) - s.head))
+	s.pushed.Add(1)
+	s.mu.Unlock()
+	return old, dropped
+}
+
+// popBatch 从队首取出最多 len(dst) 个元素
+func (s *spill[T]) popBatch(dst []T) int {
+	s.mu.Lock()
+	n := copy(dst, s.q[s.head:])
+	var zero T
+	for i := s.head; i < s.head+n; i++ {
+		s.q[i] = zero // help GC
+	}
+	s.head += n
+	if s.head == len(s.q) {
+		s.q = s.q[:0]
+		s.head = 0
+	}
+	s.n.Store(int64(len(s.q) - s.head))
+	s.mu.Unlock()
+	return n
+}
@@ -0,0 +1,330 @@
+// Package wheel 提供延迟事件的分层时间轮（三种 Bus 实现共用）
+//
+// 6 层 × 64 槽，刻度 1ms，覆盖约 2000 年：
+//   - 定时器按距到期的刻度数放入对应层：第 L 层每槽跨 64^L 个刻度
+//   - 指针走到高层槽的起点时，将该槽定时器降级重新放置（自高层向低层级联）
+//   - 第 0 层当前槽内的定时器即为本刻度到期
+//
+// 添加、取消均为 O(1)，每个未到期事件只占一个链表节点；驱动 goroutine 在首个事件加入时
+// 启动，没有未到期事件时停止计时等待唤醒。
+//
+// 除延迟事件外也可调度回调（AddFunc，供所属 Bus 的订阅算子与限流合并投递使用），回调不计入 Len、不参与 Drain。
+package wheel
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/uniyakcom/beat/core"
+)
+
+// Tick 时间轮刻度（延迟发布的精度）
+const Tick = time.Millisecond
+
+const (
+	slotBits = 6
+	slots    = 1 << slotBits
+	slotMask = slots - 1
+	levels   = 6
+	maxSpan  = int64(1)<<(slotBits*levels) - 1 // 最大可放置的刻度跨度
+)
+
+// timer 未到期事件或回调（双向链表节点）
+type timer struct {
+	evt        *core.Event
+	fn         func() // 非 nil 时到期调用 fn 而非发布 evt
+	expire     int64  // 到期刻度（相对 start）
+	seq        uint64 // 加入顺序（同刻度到期的事件按此发布）
+	prev, next *timer
+	bucket     **timer // 所在槽的链表头（nil=已到期或已取消）
+}
+
+// Wheel 分层时间轮
+type Wheel struct {
+	fire   func(*core.Event) error // 到期发布（Bus.Emit）
+	policy core.DelayPolicy
+	start  time.Time
+
+	mu      sync.Mutex
+	buckets *[levels][slots]*timer // 首次 Add 时分配
+	now     int64                  // 已推进到的刻度
+	seq     uint64
+	running bool // 驱动 goroutine 已启动
+
+	pending atomic.Int64 // 未到期项数（事件与回调）
+	funcs   atomic.Int64 // 其中的回调数
+	closed  atomic.Bool
+	wake    chan struct{} // 空闲 → 有事件（容量 1）
+	done    chan struct{}
+}
+
+// New 创建时间轮；fire 在驱动 goroutine 中发布到期事件，policy 决定 Drain 时未到期事件的去向
+func New(fire func(*core.Event) error, policy core.DelayPolicy) *Wheel {
+	return &Wheel{
+		fire:   fire,
+		policy: policy,
+		start:  time.Now(),
+		wake:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+}
+
+// Add 在 at 时刻发布 evt，返回取消函数；时间轮已关闭时不发布，取消函数返回 false
+func (w *Wheel) Add(at time.Time, evt *core.Event) core.CancelFunc {
+	return w.add(at, &timer{evt: evt})
+}
+
+// AddFunc 在 at 时刻于驱动 goroutine 中调用 fn，返回取消函数（语义同 Add）
+// fn 应尽快返回：同一时间轮上的其他到期项在其返回前不会执行。
+func (w *Wheel) AddFunc(at time.Time, fn func()) core.CancelFunc {
+	return w.add(at, &timer{fn: fn})
+}
+
+func (w *Wheel) add(at time.Time, t *timer) core.CancelFunc {
+	expire := int64((at.Sub(w.start) + Tick - 1) / Tick)
+
+	w.mu.Lock()
+	if w.closed.Load() {
+		w.mu.Unlock()
+		return func() bool { return false }
+	}
+	if w.buckets == nil {
+		w.buckets = new([levels][slots]*timer)
+	}
+	idle := w.pending.Load() == 0
+	if idle {
+		// 空闲期间指针未推进，先对齐当前时间（轮上无事件，无需逐刻度推进）
+		w.now = w.elapsed()
+	}
+	if expire <= w.now {
+		expire = w.now + 1
+	}
+	w.seq++
+	t.expire, t.seq = expire, w.seq
+	w.place(t)
+	w.pending.Add(1)
+	if t.fn != nil {
+		w.funcs.Add(1)
+	}
+	if !w.running {
+		w.running = true
+		go w.run()
+	} else if idle {
+		select {
+		case w.wake <- struct{}{}:
+		default:
+		}
+	}
+	w.mu.Unlock()
+
+	return func() bool {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		if t.bucket == nil {
+			return false
+		}
+		w.unlink(t)
+		w.pending.Add(-1)
+		if t.fn != nil {
+			w.funcs.Add(-1)
+		}
+		return true
+	}
+}
+
+// Len 返回未到期事件数（不含 AddFunc 回调）
+func (w *Wheel) Len() int64 {
+	return w.pending.Load() - w.funcs.Load()
+}
+
+// Close 停止时间轮，丢弃全部未到期事件
+func (w *Wheel) Close() {
+	w.stop()
+}
+
+// Drain 停止时间轮并按 DelayPolicy 处理未到期事件
+//   - DelayFire: 按到期顺序在调用方 goroutine 中依次发布，返回 nil
+//   - DelayReport: 不发布，返回 *core.DelayedPendingError（无未到期事件时返回 nil）
+func (w *Wheel) Drain() error {
+	evts := w.stop()
+	if len(evts) == 0 {
+		return nil
+	}
+	if w.policy == core.DelayReport {
+		return &core.DelayedPendingError{Events: evts}
+	}
+	for _, evt := range evts {
+		_ = w.fire(evt)
+	}
+	return nil
+}
+
+// stop 关闭时间轮并取出全部未到期事件（按到期刻度、加入顺序排序；回调直接丢弃）
+// 不等待驱动 goroutine 退出：到期 handler 内调用 Close/Drain 不会死锁。
+func (w *Wheel) stop() []*core.Event {
+	w.mu.Lock()
+	if w.closed.Load() {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed.Store(true)
+	var ts []*timer
+	if w.buckets != nil {
+		for l := range w.buckets {
+			for s := range w.buckets[l] {
+				for t := w.buckets[l][s]; t != nil; t = t.next {
+					t.bucket = nil
+					if t.fn == nil {
+						ts = append(ts, t)
+					}
+				}
+				w.buckets[l][s] = nil
+			}
+		}
+	}
+	w.pending.Store(0)
+	w.funcs.Store(0)
+	w.mu.Unlock()
+	close(w.done)
+
+	sort.Slice(ts, func(i, j int) bool {
+		if ts[i].expire != ts[j].expire {
+			return ts[i].expire < ts[j].expire
+		}
+		return ts[i].seq < ts[j].seq
+	})
+	evts := make([]*core.Event, len(ts))
+	for i, t := range ts {
+		evts[i] = t.evt
+	}
+	return evts
+}
+
+// run 驱动 goroutine：每个刻度推进指针并发布到期事件，无未到期事件时停表等待唤醒
+func (w *Wheel) run() {
+	tk := time.NewTicker(Tick)
+	defer tk.Stop()
+	var due []*timer
+	for {
+		select {
+		case <-tk.C:
+		case <-w.done:
+			return
+		}
+
+		w.mu.Lock()
+		due = w.advance(w.elapsed(), due[:0])
+		idle := w.pending.Load() == 0
+		w.mu.Unlock()
+
+		for i, t := range due {
+			if w.closed.Load() {
+				break
+			}
+			if t.fn != nil {
+				t.fn()
+			} else {
+				_ = w.fire(t.evt)
+			}
+			due[i] = nil
+		}
+
+		if idle {
+			tk.Stop()
+			select {
+			case <-w.wake:
+				tk.Reset(Tick)
+			case <-w.done:
+				return
+			}
+		}
+	}
+}
+
+// advance 将指针逐刻度推进到 target，返回到期的定时器（调用方持锁）
+func (w *Wheel) advance(target int64, due []*timer) []*timer {
+	for w.now < target && w.pending.Load() > 0 {
+		w.now++
+		w.cascade()
+		slot := &w.buckets[0][w.now&slotMask]
+		n := len(due)
+		for t := *slot; t != nil; t = t.next {
+			t.bucket = nil
+			if t.fn != nil {
+				w.funcs.Add(-1)
+			}
+			due = append(due, t)
+		}
+		*slot = nil
+		w.pending.Add(-int64(len(due) - n))
+		// 槽内为头插，同刻度按加入顺序发布
+		if len(due)-n > 1 {
+			batch := due[n:]
+			sort.Slice(batch, func(i, j int) bool { return batch[i].seq < batch[j].seq })
+		}
+	}
+	if w.now < target {
+		w.now = target // 轮上已无事件，直接对齐
+	}
+	return due
+}
+
+// cascade 指针走到高层槽起点时，自最高对齐层向下将该槽定时器重新放置
+// 必须自高层向低层：高层降级的定时器可能落入本刻度即将级联的低层槽。
+func (w *Wheel) cascade() {
+	top := 0
+	for l := 1; l < levels && w.now&(int64(1)<<(slotBits*l)-1) == 0; l++ {
+		top = l
+	}
+	for l := top; l >= 1; l-- {
+		slot := &w.buckets[l][(w.now>>(slotBits*l))&slotMask]
+		t := *slot
+		*slot = nil
+		for t != nil {
+			next := t.next
+			t.prev, t.next, t.bucket = nil, nil, nil
+			w.place(t)
+			t = next
+		}
+	}
+}
+
+// place 按距到期的刻度数将定时器放入对应层的槽（头插）
+func (w *Wheel) place(t *timer) {
+	expire := t.expire
+	if expire-w.now > maxSpan {
+		expire = w.now + maxSpan // 超出覆盖范围：先放最高层，级联时按真实到期刻度重新放置
+	}
+	delta := expire - w.now
+	l := 0
+	for l < levels-1 && delta >= int64(1)<<(slotBits*(l+1)) {
+		l++
+	}
+	b := &w.buckets[l][(expire>>(slotBits*l))&slotMask]
+	t.bucket = b
+	t.next = *b
+	if *b != nil {
+		(*b).prev = t
+	}
+	*b = t
+}
+
+// unlink 将定时器从所在槽移除（调用方持锁）
+func (w *Wheel) unlink(t *timer) {
+	if t.prev != nil {
+		t.prev.next = t.next
+	} else {
+		*t.bucket = t.next
+	}
+	if t.next != nil {
+		t.next.prev = t.prev
+	}
+	t.prev, t.next, t.bucket = nil, nil, nil
+}
+
+// elapsed 返回自创建以来经过的刻度数
+func (w *Wheel) elapsed() int64 {
+	return int64(time.Since(w.start) / Tick)
+}
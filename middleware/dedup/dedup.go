@@ -0,0 +1,116 @@
+// Package dedup 提供按消息 UUID 去重的中间件。
+//
+// 上游按 at-least-once 语义重投时，同一消息在 TTL 窗口内只交给 handler 处理一次：
+// 重复消息直接返回成功（Router 照常 Ack），不再调用 handler。
+// handler 返回 error 时撤销记录，重投的消息仍会被处理。
+//
+// 默认使用进程内存储（分片、容量有界）；多实例部署可实现 Store 接入 Redis 等共享存储。
+//
+//	r.Use(dedup.New(dedup.Config{TTL: 10 * time.Minute}))
+//	r.Use(dedup.New(dedup.Config{Store: redisStore}))
+package dedup
+
+import (
+	"context"
+	"time"
+
+	seen "github.com/uniyakcom/beat/internal/support/dedup"
+	"github.com/uniyakcom/beat/message"
+	"github.com/uniyakcom/beat/router"
+)
+
+// Store 已处理消息的去重存储
+type Store interface {
+	// Seen 原子地检查并记录 key：ttl 内已记录过返回 true，否则记录并返回 false
+	Seen(ctx context.Context, key string, ttl time.Duration) (bool, error)
+	// Forget 删除 key 的记录（handler 失败后让重投的消息重新处理）
+	Forget(ctx context.Context, key string) error
+}
+
+// Config 去重中间件配置
+type Config struct {
+	// TTL 去重窗口。默认 10m。
+	TTL time.Duration
+
+	// Store 去重存储。为 nil 时使用 NewMemoryStore(0)。
+	Store Store
+
+	// KeyFunc 提取去重键。为 nil 时使用 msg.UUID；返回空字符串的消息不去重。
+	KeyFunc func(msg *message.Message) string
+
+	// OnDuplicate 丢弃重复消息时回调（可选，用于计数/日志）。
+	OnDuplicate func(msg *message.Message)
+}
+
+func (c *Config) defaults() {
+	if c.TTL <= 0 {
+		c.TTL = 10 * time.Minute
+	}
+	if c.Store == nil {
+		c.Store = NewMemoryStore(0)
+	}
+	if c.KeyFunc == nil {
+		c.KeyFunc = func(msg *message.Message) string { return msg.UUID }
+	}
+}
+
+// New 创建去重中间件。
+//
+// Store 返回 error 时不调用 handler，直接返回该 error（消息 Nack 后由上游重投）。
+func New(cfg Config) router.Middleware {
+	cfg.defaults()
+
+	return func(h router.HandlerFunc) router.HandlerFunc {
+		return func(msg *message.Message) ([]*message.Message, error) {
+			key := cfg.KeyFunc(msg)
+			if key == "" {
+				return h(msg)
+			}
+
+			dup, err := cfg.Store.Seen(msg.Context(), key, cfg.TTL)
+			if err != nil {
+				return nil, err
+			}
+			if dup {
+				if cfg.OnDuplicate != nil {
+					cfg.OnDuplicate(msg)
+				}
+				return nil, nil
+			}
+
+			produced, err := h(msg)
+			if err != nil {
+				// 处理失败: 撤销记录，允许重投后重新处理（撤销失败不覆盖 handler error）
+				_ = cfg.Store.Forget(msg.Context(), key)
+			}
+			return produced, err
+		}
+	}
+}
+
+// MemoryStore 进程内去重存储
+// 按 key 哈希分片加锁；容量有界，超出时淘汰最早记录的 key。
+type MemoryStore struct {
+	set *seen.Set
+}
+
+// NewMemoryStore 创建进程内去重存储，最多记录 maxEntries 个 key（<=0 时 65536）
+func NewMemoryStore(maxEntries int) *MemoryStore {
+	return &MemoryStore{set: seen.New(0, maxEntries)}
+}
+
+// Seen 实现 Store
+func (s *MemoryStore) Seen(_ context.Context, key string, ttl time.Duration) (bool, error) {
+	return s.set.Seen(key, ttl), nil
+}
+
+// Forget 实现 Store
+func (s *MemoryStore) Forget(_ context.Context, key string) error {
+	s.set.Forget(key)
+	return nil
+}
+
+// Len 返回当前记录的 key 数（含尚未清理的过期记录）
+func (s *MemoryStore) Len() int {
+	return s.set.Len()
+}
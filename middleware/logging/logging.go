@@ -1,6 +1,7 @@
 // Package logging 提供消息处理日志中间件。
 //
 // 记录每条消息的处理耗时、产出数量和错误信息。使用 log/slog 零外部依赖。
+// 耗时可同时写入 util.PerCPUHistogram，用于查询分位数。
 //
 //	r.Use(logging.New(slog.Default()))
 package logging
@@ -11,10 +12,21 @@ import (
 
 	"github.com/uniyakcom/beat/message"
 	"github.com/uniyakcom/beat/router"
+	"github.com/uniyakcom/beat/util"
 )
 
 // New 创建日志中间件。
 func New(logger *slog.Logger) router.Middleware {
+	return NewWithLatency(logger, nil)
+}
+
+// NewWithLatency 创建日志中间件，并将每条消息的处理耗时写入 hist（nil 时仅记录日志）。
+// 同一 hist 可在多个 Handler 间共享，得到整体耗时分布。
+//
+//	lat := util.NewPerCPUHistogram()
+//	r.Use(logging.NewWithLatency(slog.Default(), lat))
+//	p99 := lat.Snapshot().Percentile(99)
+func NewWithLatency(logger *slog.Logger, hist *util.PerCPUHistogram) router.Middleware {
 	if logger == nil {
 		logger = slog.Default()
 	}
@@ -26,6 +38,9 @@ func New(logger *slog.Logger) router.Middleware {
 			produced, err := h(msg)
 
 			duration := time.Since(start)
+			if hist != nil {
+				hist.Record(duration)
+			}
 			attrs := []any{
 				"uuid", msg.UUID,
 				"duration", duration,
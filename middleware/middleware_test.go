@@ -2,15 +2,20 @@ package middleware_test
 
 import (
 	"errors"
+	"io"
+	"log/slog"
 	"testing"
 	"time"
 
 	"github.com/uniyakcom/beat/message"
 	"github.com/uniyakcom/beat/middleware/correlation"
+	"github.com/uniyakcom/beat/middleware/dedup"
+	"github.com/uniyakcom/beat/middleware/logging"
 	"github.com/uniyakcom/beat/middleware/recoverer"
 	"github.com/uniyakcom/beat/middleware/retry"
 	"github.com/uniyakcom/beat/middleware/timeout"
 	"github.com/uniyakcom/beat/router"
+	"github.com/uniyakcom/beat/util"
 )
 
 func TestRetryMiddleware(t *testing.T) {
@@ -176,6 +181,54 @@ func TestCorrelationMiddleware(t *testing.T) {
 	})
 }
 
+func TestDedupMiddleware(t *testing.T) {
+	var calls, dups int
+	fail := true
+	mw := dedup.New(dedup.Config{
+		TTL:         time.Minute,
+		OnDuplicate: func(*message.Message) { dups++ },
+	})
+	handler := mw(func(msg *message.Message) ([]*message.Message, error) {
+		calls++
+		if fail {
+			return nil, errors.New("transient error")
+		}
+		return nil, nil
+	})
+
+	// 处理失败: 撤销记录，重投的消息仍被处理
+	if _, err := handler(message.New("order-1", nil)); err == nil {
+		t.Fatal("expected handler error")
+	}
+	fail = false
+	if _, err := handler(message.New("order-1", nil)); err != nil {
+		t.Fatal(err)
+	}
+	// 已成功处理: 重投在窗口内被丢弃
+	if _, err := handler(message.New("order-1", nil)); err != nil {
+		t.Fatal(err)
+	}
+	_, _ = handler(message.New("order-2", nil))
+
+	if calls != 3 || dups != 1 {
+		t.Errorf("calls = %d dups = %d, want 3 and 1", calls, dups)
+	}
+
+	t.Run("window expiry", func(t *testing.T) {
+		store := dedup.NewMemoryStore(0)
+		h := dedup.New(dedup.Config{TTL: 20 * time.Millisecond, Store: store})(
+			func(*message.Message) ([]*message.Message, error) { calls++; return nil, nil })
+		calls = 0
+		_, _ = h(message.New("m", nil))
+		_, _ = h(message.New("m", nil))
+		time.Sleep(30 * time.Millisecond)
+		_, _ = h(message.New("m", nil))
+		if calls != 2 {
+			t.Errorf("calls = %d, want 2 (duplicate dropped, processed again after TTL)", calls)
+		}
+	})
+}
+
 func TestMiddlewareChaining(t *testing.T) {
 	var order []string
 
@@ -218,3 +271,24 @@ func TestMiddlewareChaining(t *testing.T) {
 		}
 	}
 }
+
+func TestLoggingLatency(t *testing.T) {
+	lat := util.NewPerCPUHistogram()
+	mw := logging.NewWithLatency(slog.New(slog.NewTextHandler(io.Discard, nil)), lat)
+
+	handler := mw(func(msg *message.Message) ([]*message.Message, error) {
+		time.Sleep(time.Millisecond)
+		return nil, errors.New("fail")
+	})
+	for i := 0; i < 3; i++ {
+		_, _ = handler(message.New("", nil))
+	}
+
+	s := lat.Snapshot()
+	if s.Count != 3 {
+		t.Fatalf("recorded %d durations, want 3", s.Count)
+	}
+	if p := s.Percentile(50); p < time.Millisecond {
+		t.Errorf("p50 = %v, want >= 1ms", p)
+	}
+}
@@ -0,0 +1,97 @@
+package operator
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/uniyakcom/beat/core"
+)
+
+// batchKey 批次事件的 context key
+type batchKey struct{}
+
+// Batch 返回 Buffer 投递的批次；evt 不是批次事件时返回 []*core.Event{evt}
+func Batch(evt *core.Event) []*core.Event {
+	if events, ok := evt.Context().Value(batchKey{}).([]*core.Event); ok {
+		return events
+	}
+	return []*core.Event{evt}
+}
+
+// buffer 缓冲算子
+type buffer struct {
+	n int
+	d time.Duration
+}
+
+// Buffer 缓冲: 攒满 n 个事件或首个事件到达 d 后，将整批作为一个批次事件投递
+// 批次事件的 Type 为批内首个事件的类型，handler 用 Batch(evt) 取出整批（按到达顺序）。
+// 攒满时在发布路径上同步投递（handler error 照常返回），超时时在时间轮上投递。
+// n<=0 时只按时间切分，d<=0 时只按数量切分（均 <=0 时每个事件单独成批）。
+func Buffer(n int, d time.Duration) core.Operator {
+	if n <= 0 && d <= 0 {
+		n = 1
+	}
+	return buffer{n: n, d: d}
+}
+
+// Build 实现 core.Operator
+func (o buffer) Build(next func(core.OperatorRuntime) core.Handler, rt core.OperatorRuntime) core.Handler {
+	s := &buffering{n: o.n, d: o.d, next: next(rt), rt: rt}
+	return s.handle
+}
+
+type buffering struct {
+	n    int
+	d    time.Duration
+	next core.Handler
+	rt   core.OperatorRuntime
+
+	mu  sync.Mutex
+	buf []*core.Event
+	gen uint64 // 批次序号（超时定时器只冲刷自己所属的批次）
+}
+
+func (s *buffering) handle(evt *core.Event) error {
+	s.mu.Lock()
+	s.buf = append(s.buf, evt)
+	if s.n > 0 && len(s.buf) >= s.n {
+		batch := s.take()
+		s.mu.Unlock()
+		return s.next(batched(batch))
+	}
+	if len(s.buf) == 1 && s.d > 0 {
+		gen := s.gen
+		s.rt.After(s.d, func() { s.expire(gen) })
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+// expire 批次超时: 仍是同一批次时投递
+func (s *buffering) expire(gen uint64) {
+	s.mu.Lock()
+	if gen != s.gen || len(s.buf) == 0 {
+		s.mu.Unlock()
+		return
+	}
+	batch := s.take()
+	s.mu.Unlock()
+	s.rt.Deliver(s.next, batched(batch))
+}
+
+// take 取出当前批次并开启下一批（调用方持锁）
+func (s *buffering) take() []*core.Event {
+	batch := s.buf
+	s.buf = nil
+	s.gen++
+	return batch
+}
+
+// batched 构造批次事件
+func batched(batch []*core.Event) *core.Event {
+	evt := &core.Event{Type: batch[0].Type, Timestamp: time.Now()}
+	evt.SetContext(context.WithValue(context.Background(), batchKey{}, batch))
+	return evt
+}
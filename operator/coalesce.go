@@ -0,0 +1,104 @@
+package operator
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/uniyakcom/beat/core"
+)
+
+// keyIdle 按 key 分区的空闲回收时间: 超过该时长未出现且下游没有未到期定时器的 key 释放其下游算子实例
+const keyIdle = time.Minute
+
+// coalesceBy 按 key 分区算子
+type coalesceBy func(*core.Event) string
+
+// CoalesceBy 按 key 分区: 之后的算子对每个 key 独立生效
+// 例如 CoalesceBy(key) + Debounce(d) 为每个 key 投递其静默 d 后的最新事件，
+// CoalesceBy(key) + Sample(d) 每隔 d 为每个有新事件的 key 投递一次最新值。
+// 单独使用（之后没有算子）时不改变投递行为。
+// 超过 1 分钟未出现的 key 在下游没有未到期的定时器时释放其下游实例（窗口超过 1 分钟的算子不会被提前回收）。
+func CoalesceBy(key func(*core.Event) string) core.Operator {
+	return coalesceBy(key)
+}
+
+// Build 实现 core.Operator
+func (o coalesceBy) Build(next func(core.OperatorRuntime) core.Handler, rt core.OperatorRuntime) core.Handler {
+	s := &coalescer{key: o, next: next, rt: rt, parts: make(map[string]*part)}
+	return s.handle
+}
+
+type coalescer struct {
+	key  func(*core.Event) string
+	next func(core.OperatorRuntime) core.Handler
+	rt   core.OperatorRuntime
+
+	mu       sync.Mutex
+	parts    map[string]*part
+	sweeping bool // 回收定时器已启动
+}
+
+// part 单个 key 的下游实例，同时是该实例的运行环境（统计未到期的定时器，有定时器时不回收）
+type part struct {
+	s       *coalescer
+	h       core.Handler
+	seen    time.Time    // 受 coalescer.mu 保护
+	pending atomic.Int64 // 下游未到期的定时器数
+}
+
+// After 实现 core.OperatorRuntime
+func (p *part) After(d time.Duration, fn func()) core.CancelFunc {
+	p.pending.Add(1)
+	cancel := p.s.rt.After(d, func() {
+		fn() // fn 内重新调度的定时器先计入，计数不会在两次定时之间归零
+		p.pending.Add(-1)
+	})
+	return func() bool {
+		if !cancel() {
+			return false
+		}
+		p.pending.Add(-1)
+		return true
+	}
+}
+
+// Deliver 实现 core.OperatorRuntime
+func (p *part) Deliver(h core.Handler, evt *core.Event) {
+	p.s.rt.Deliver(h, evt)
+}
+
+func (s *coalescer) handle(evt *core.Event) error {
+	k := s.key(evt)
+	s.mu.Lock()
+	p := s.parts[k]
+	if p == nil {
+		p = &part{s: s}
+		p.h = s.next(p)
+		s.parts[k] = p
+		if !s.sweeping {
+			s.sweeping = true
+			s.rt.After(keyIdle, s.sweep)
+		}
+	}
+	p.seen = time.Now()
+	s.mu.Unlock()
+	return p.h(evt)
+}
+
+// sweep 回收空闲且没有未到期定时器的 key（定时器到期前回收会让同一 key 出现两个下游实例）；仍有 key 时继续定时回收
+func (s *coalescer) sweep() {
+	cutoff := time.Now().Add(-keyIdle)
+	s.mu.Lock()
+	for k, p := range s.parts {
+		if p.seen.Before(cutoff) && p.pending.Load() == 0 {
+			delete(s.parts, k)
+		}
+	}
+	if len(s.parts) > 0 {
+		s.rt.After(keyIdle, s.sweep)
+	} else {
+		s.sweeping = false
+	}
+	s.mu.Unlock()
+}
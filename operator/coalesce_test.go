@@ -0,0 +1,93 @@
+package operator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/uniyakcom/beat/core"
+)
+
+// manualRuntime 手动触发定时器的运行环境，Deliver 直接调用 handler
+type manualRuntime struct {
+	timers []*manualTimer
+}
+
+type manualTimer struct {
+	d    time.Duration
+	fn   func()
+	done bool
+}
+
+func (r *manualRuntime) After(d time.Duration, fn func()) core.CancelFunc {
+	t := &manualTimer{d: d, fn: fn}
+	r.timers = append(r.timers, t)
+	return func() bool {
+		if t.done {
+			return false
+		}
+		t.done = true
+		return true
+	}
+}
+
+func (r *manualRuntime) Deliver(h core.Handler, evt *core.Event) {
+	_ = h(evt)
+}
+
+// fire 触发第一个未完成且时长为 d 的定时器
+func (r *manualRuntime) fire(t *testing.T, d time.Duration) {
+	t.Helper()
+	for _, tm := range r.timers {
+		if !tm.done && tm.d == d {
+			tm.done = true
+			tm.fn()
+			return
+		}
+	}
+	t.Fatalf("no pending %v timer", d)
+}
+
+// TestCoalesceSweepKeepsPendingParts 下游定时器未到期的 key 即使空闲超过 keyIdle 也不回收，
+// 否则同一 key 的新事件会建出第二个下游实例，旧实例到期后重复投递
+func TestCoalesceSweepKeepsPendingParts(t *testing.T) {
+	const window = 2 * keyIdle
+	rt := &manualRuntime{}
+	var got []string
+	final := func(evt *core.Event) error {
+		got = append(got, string(evt.Data))
+		return nil
+	}
+	s := &coalescer{
+		key: func(evt *core.Event) string { return evt.Key },
+		next: func(rt core.OperatorRuntime) core.Handler {
+			return Sample(window).Build(func(core.OperatorRuntime) core.Handler { return final }, rt)
+		},
+		rt:    rt,
+		parts: make(map[string]*part),
+	}
+
+	_ = s.handle(&core.Event{Key: "a", Data: []byte("1")})
+	s.parts["a"].seen = time.Now().Add(-window)
+	rt.fire(t, keyIdle) // 回收: 采样定时器未到期，保留
+	if _, ok := s.parts["a"]; !ok {
+		t.Fatal("part with a pending timer was swept")
+	}
+
+	_ = s.handle(&core.Event{Key: "a", Data: []byte("2")})
+	rt.fire(t, window) // 投递最新事件并开启下一个周期
+	if len(got) != 1 || got[0] != "2" {
+		t.Fatalf("delivered %q, want [2]", got)
+	}
+	s.parts["a"].seen = time.Now().Add(-window)
+	rt.fire(t, keyIdle)
+	if _, ok := s.parts["a"]; !ok {
+		t.Fatal("part swept while its next sample period is pending")
+	}
+	rt.fire(t, window) // 周期内没有新事件: 不再调度
+
+	s.parts["a"].seen = time.Now().Add(-window)
+	rt.fire(t, keyIdle) // 回收: 定时器已到期，释放
+	if n := len(s.parts); n != 0 {
+		t.Fatalf("%d parts after sweep, want 0", n)
+	}
+}
@@ -0,0 +1,36 @@
+// Package operator 提供订阅算子：防抖、节流、采样、按 key 合并、缓冲与过滤。
+//
+// 算子实现 core.Operator，通过 WithOperators 挂到订阅上，按参数顺序组合（前者在外层）：
+//
+//	bus.(core.OptionSubscriber).OnWith("cache.invalidate.*", invalidate,
+//	    beat.WithOperators(
+//	        operator.Filter(func(e *beat.Event) bool { return e.Key != "" }),
+//	        operator.CoalesceBy(func(e *beat.Event) string { return e.Key }), // 之后的算子按 key 独立生效
+//	        operator.Debounce(200*time.Millisecond),                          // 每个 key 静默 200ms 后投递最新事件
+//	    ))
+//
+// 定时器挂在所属 Bus 的时间轮上（精度 1ms），不为每个订阅常驻 goroutine；
+// 到期后的投递在订阅自己的投递 goroutine 中按顺序执行，耗时 handler 不影响其他订阅的定时投递。
+// Off 取消订阅时未到期的定时器一并取消，尚未投递的事件丢弃。
+package operator
+
+import "github.com/uniyakcom/beat/core"
+
+// filter 过滤算子
+type filter func(*core.Event) bool
+
+// Filter 只让 pred 返回 true 的事件通过
+func Filter(pred func(*core.Event) bool) core.Operator {
+	return filter(pred)
+}
+
+// Build 实现 core.Operator
+func (f filter) Build(next func(core.OperatorRuntime) core.Handler, rt core.OperatorRuntime) core.Handler {
+	h := next(rt)
+	return func(evt *core.Event) error {
+		if f(evt) {
+			return h(evt)
+		}
+		return nil
+	}
+}
@@ -0,0 +1,163 @@
+package operator
+
+import (
+	"sync"
+	"time"
+
+	"github.com/uniyakcom/beat/core"
+)
+
+// debounce 防抖算子
+type debounce time.Duration
+
+// Debounce 防抖: 事件停止到达 d 后投递最后一个事件
+// 持续到达的事件不断顺延投递时间，期间只保留最新一个。
+func Debounce(d time.Duration) core.Operator {
+	return debounce(d)
+}
+
+// Build 实现 core.Operator
+func (o debounce) Build(next func(core.OperatorRuntime) core.Handler, rt core.OperatorRuntime) core.Handler {
+	s := &debouncer{d: time.Duration(o), next: next(rt), rt: rt}
+	return s.handle
+}
+
+type debouncer struct {
+	d    time.Duration
+	next core.Handler
+	rt   core.OperatorRuntime
+
+	mu       sync.Mutex
+	latest   *core.Event
+	deadline time.Time
+	armed    bool // 定时器已启动
+}
+
+func (s *debouncer) handle(evt *core.Event) error {
+	s.mu.Lock()
+	s.latest = evt
+	s.deadline = time.Now().Add(s.d)
+	if !s.armed {
+		s.armed = true
+		s.rt.After(s.d, s.fire)
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+// fire 到期时若期间有新事件则顺延（每个订阅同一时刻只有一个定时器，不随事件数增长）
+func (s *debouncer) fire() {
+	s.mu.Lock()
+	if wait := time.Until(s.deadline); wait > 0 {
+		s.rt.After(wait, s.fire)
+		s.mu.Unlock()
+		return
+	}
+	evt := s.latest
+	s.latest = nil
+	s.armed = false
+	s.mu.Unlock()
+	s.rt.Deliver(s.next, evt)
+}
+
+// throttle 节流算子
+type throttle time.Duration
+
+// Throttle 节流: 窗口外的事件立即投递并开启 d 的窗口，窗口内只保留最新事件，
+// 窗口结束时投递它并开启下一个窗口（首尾均投递，相邻投递间隔不小于 d）
+func Throttle(d time.Duration) core.Operator {
+	return throttle(d)
+}
+
+// Build 实现 core.Operator
+func (o throttle) Build(next func(core.OperatorRuntime) core.Handler, rt core.OperatorRuntime) core.Handler {
+	s := &throttler{d: time.Duration(o), next: next(rt), rt: rt}
+	return s.handle
+}
+
+type throttler struct {
+	d    time.Duration
+	next core.Handler
+	rt   core.OperatorRuntime
+
+	mu     sync.Mutex
+	latest *core.Event
+	active bool // 处于窗口内
+}
+
+func (s *throttler) handle(evt *core.Event) error {
+	s.mu.Lock()
+	if s.active {
+		s.latest = evt
+		s.mu.Unlock()
+		return nil
+	}
+	s.active = true
+	s.rt.After(s.d, s.fire)
+	s.mu.Unlock()
+	return s.next(evt)
+}
+
+func (s *throttler) fire() {
+	s.mu.Lock()
+	evt := s.latest
+	s.latest = nil
+	if evt == nil {
+		s.active = false
+		s.mu.Unlock()
+		return
+	}
+	s.rt.After(s.d, s.fire) // 尾部投递开启下一个窗口
+	s.mu.Unlock()
+	s.rt.Deliver(s.next, evt)
+}
+
+// sample 采样算子
+type sample time.Duration
+
+// Sample 采样: 每隔 d 投递这段时间内的最新事件（没有新事件的周期不投递）
+// 周期从空闲后的首个事件开始计时，不立即投递。
+func Sample(d time.Duration) core.Operator {
+	return sample(d)
+}
+
+// Build 实现 core.Operator
+func (o sample) Build(next func(core.OperatorRuntime) core.Handler, rt core.OperatorRuntime) core.Handler {
+	s := &sampler{d: time.Duration(o), next: next(rt), rt: rt}
+	return s.handle
+}
+
+type sampler struct {
+	d    time.Duration
+	next core.Handler
+	rt   core.OperatorRuntime
+
+	mu     sync.Mutex
+	latest *core.Event
+	armed  bool
+}
+
+func (s *sampler) handle(evt *core.Event) error {
+	s.mu.Lock()
+	s.latest = evt
+	if !s.armed {
+		s.armed = true
+		s.rt.After(s.d, s.fire)
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *sampler) fire() {
+	s.mu.Lock()
+	evt := s.latest
+	s.latest = nil
+	if evt == nil {
+		s.armed = false
+		s.mu.Unlock()
+		return
+	}
+	s.rt.After(s.d, s.fire)
+	s.mu.Unlock()
+	s.rt.Deliver(s.next, evt)
+}
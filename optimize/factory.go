@@ -22,6 +22,12 @@ func Build(advised *Advised) (core.Bus, error) {
 	}
 	pool.SetEnableArena(enableArena)
 
+	if p := advised.Profile; p != nil {
+		if err := validatePatterns(p); err != nil {
+			return nil, err
+		}
+	}
+
 	switch impl {
 	case "sync":
 		return buildSync(advised, enableArena)
@@ -34,6 +40,37 @@ func Build(advised *Advised) (core.Bus, error) {
 	}
 }
 
+// validatePatterns 按 Profile 的 pattern 语法校验保留与限流规则中的 pattern
+func validatePatterns(p *Profile) error {
+	for _, pattern := range p.Retain {
+		if _, err := p.PatternSyntax.Compile(pattern); err != nil {
+			return err
+		}
+	}
+	for _, rl := range p.RateLimits {
+		if _, err := p.PatternSyntax.Compile(rl.Pattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// overflowOf 解析溢出策略：未显式指定时由 Auto.Backpressure 决定是否限时阻塞
+func overflowOf(p *Profile) (core.OverflowPolicy, time.Duration) {
+	if p.Overflow == core.OverflowBlock && p.Auto.Enabled && p.Auto.Backpressure {
+		return core.OverflowBlockTimeout, p.OverflowTimeout
+	}
+	return p.Overflow, p.OverflowTimeout
+}
+
+// scaleOf 解析弹性 worker 配置：未显式指定时由 Auto.Degradation 决定是否在 [1, workers] 间伸缩
+func scaleOf(p *Profile, workers int) core.ScalePolicy {
+	if p.Scale.Max == 0 && p.Auto.Enabled && p.Auto.Degradation {
+		return core.ScalePolicy{Min: 1, Max: workers}
+	}
+	return p.Scale
+}
+
 // buildSync 构建同步 Bus（用于sync场景）
 func buildSync(advised *Advised, enableArena bool) (core.Bus, error) {
 	cfg := &implsync.Config{
@@ -41,6 +78,19 @@ func buildSync(advised *Advised, enableArena bool) (core.Bus, error) {
 		PoolSize:    0,
 		EnableArena: enableArena,
 	}
+	if p := advised.Profile; p != nil {
+		cfg.PanicHandler = p.PanicHandler
+		cfg.ErrorHandler = p.ErrorHandler
+		cfg.ErrorMode = p.ErrorMode
+		cfg.Retain = p.Retain
+		cfg.DelayPolicy = p.DelayPolicy
+		cfg.DedupWindow, cfg.DedupMaxEntries = p.DedupWindow, p.DedupMaxEntries
+		cfg.RateLimits = p.RateLimits
+		cfg.PatternSyntax = p.PatternSyntax
+		cfg.MatchCacheSize = p.MatchCacheSize
+		cfg.HandlerLatency = p.HandlerLatency
+		cfg.Overflow, cfg.OverflowTimeout = overflowOf(p)
+	}
 
 	// 处理推荐参数
 	if prewarm, ok := advised.Params["prewarm"]; ok && prewarm.(bool) {
@@ -68,6 +118,21 @@ func buildAsync(advised *Advised) (core.Bus, error) {
 	if v, ok := advised.Params["ringSize"]; ok {
 		cfg.RingSize = v.(uint64)
 	}
+	if p := advised.Profile; p != nil {
+		cfg.PanicHandler = p.PanicHandler
+		cfg.ErrorHandler = p.ErrorHandler
+		cfg.Ordered = p.Ordered
+		cfg.WorkStealing = p.WorkStealing
+		cfg.Scale = scaleOf(p, cfg.Workers)
+		cfg.Retain = p.Retain
+		cfg.DelayPolicy = p.DelayPolicy
+		cfg.DedupWindow, cfg.DedupMaxEntries = p.DedupWindow, p.DedupMaxEntries
+		cfg.RateLimits = p.RateLimits
+		cfg.PatternSyntax = p.PatternSyntax
+		cfg.MatchCacheSize = p.MatchCacheSize
+		cfg.HandlerLatency = p.HandlerLatency
+		cfg.Overflow, cfg.OverflowTimeout = overflowOf(p)
+	}
 
 	return implasync.New(cfg), nil
 }
@@ -84,13 +149,24 @@ func buildFlow(advised *Advised, enableArena bool) (core.Bus, error) {
 		timeout = v.(time.Duration)
 	}
 
-	// 创建简单的透传Stage
-	stages := []flow.Stage{
-		func(events []*core.Event) error {
-			return nil
-		},
+	cfg := &flow.Config{
+		BatchSize:    batchsz,
+		BatchTimeout: timeout,
+	}
+	if p := advised.Profile; p != nil {
+		cfg.Stages = p.Stages
+		cfg.StageErrorPolicy = p.StageErrorPolicy
+		cfg.StageErrorHandler = p.StageErrorHandler
+		cfg.PanicHandler = p.PanicHandler
+		cfg.ErrorHandler = p.ErrorHandler
+		cfg.Retain = p.Retain
+		cfg.DelayPolicy = p.DelayPolicy
+		cfg.DedupWindow, cfg.DedupMaxEntries = p.DedupWindow, p.DedupMaxEntries
+		cfg.RateLimits = p.RateLimits
+		cfg.PatternSyntax = p.PatternSyntax
+		cfg.MatchCacheSize = p.MatchCacheSize
+		cfg.HandlerLatency = p.HandlerLatency
 	}
 
-	bus := flow.New(stages, batchsz, timeout)
-	return bus, nil
+	return flow.NewWithConfig(cfg), nil
 }
@@ -4,14 +4,16 @@ package optimize
 import (
 	"runtime"
 	"time"
+
+	"github.com/uniyakcom/beat/core"
 )
 
 // Auto 自动配置结构
 type Auto struct {
 	Enabled      bool // 总开关（默认true）
 	Batch        bool // 批处理自适应
-	Backpressure bool // 背压控制
-	Degradation  bool // 自动降级
+	Backpressure bool // 背压控制（未显式指定溢出策略时按 OverflowBlockTimeout 处理）
+	Degradation  bool // 自动降级（Async: 未显式配置 Scale 时开启弹性 worker，空闲时缩减至 1 个）
 }
 
 // Profile 优化场景Profile
@@ -26,7 +28,62 @@ type Profile struct {
 	Impl         string        // "sync"/"async"/"flow"
 	EnableArena  bool          // 是否启用 Arena（0分配数据分配）
 	BatchTimeout time.Duration // Flow 批处理超时（0=默认100ms）
+	Ordered      bool          // 按 Event.Key 保序投递（仅 Async 实现生效）
+	WorkStealing bool          // worker 间工作窃取（仅 Async 实现生效）
 	Auto         Auto          // 自动配置
+
+	// 溢出策略（Async / Sync 异步模式生效）
+	// Overflow 为默认 OverflowBlock 且 Auto.Backpressure 开启时，按 OverflowBlockTimeout 处理。
+	Overflow        core.OverflowPolicy // ring 满时的处理策略
+	OverflowTimeout time.Duration       // OverflowBlockTimeout 的等待上限（0=100ms）
+
+	// 弹性 worker（仅 Async 实现生效）: Scale.Max>0 时开启；未设置且 Auto.Degradation 开启时在 [1, workers] 间伸缩
+	Scale core.ScalePolicy
+
+	// 回调（三种实现均生效）
+	PanicHandler core.PanicInfoHandler // handler panic 回调（含事件与订阅信息；WithPanicHandler 经 core.PanicInfo 适配）
+	ErrorHandler core.ErrorHandler     // handler error 回调（含事件与订阅信息）
+	ErrorMode    core.ErrorMode        // 同步 Emit 的 handler 失败处理方式（仅 Sync 同步模式生效）
+
+	// 保留事件（三种实现均生效）: 类型匹配这些 pattern 的事件发布时保留最新副本
+	Retain []string
+
+	// 延迟发布（三种实现均生效）: Drain 时未到期延迟事件的处理方式
+	DelayPolicy core.DelayPolicy
+
+	// 按 Event.ID 去重（三种实现均生效）: DedupWindow>0 时开启
+	DedupWindow     time.Duration // 去重窗口（窗口内重复 ID 的事件在匹配前丢弃）
+	DedupMaxEntries int           // 最多记录的 ID 数（0=65536，超出时淘汰最早记录）
+
+	// 发布侧限流（三种实现均生效）: 按事件类型 pattern 的令牌桶规则
+	RateLimits []core.RateLimit
+
+	// pattern 语法（三种实现均生效）: 分隔符与通配符记号（零值=DotSyntax）
+	PatternSyntax core.PatternSyntax
+
+	// 匹配结果缓存容量（三种实现均生效）: 按事件类型缓存通配符匹配结果的条目上限（0=4096，<0 不缓存）
+	MatchCacheSize int
+
+	// handler 耗时直方图（三种实现均生效）: 按订阅 pattern 记录每次 handler 执行耗时（经 core.LatencyStatter 查询）
+	HandlerLatency bool
+
+	// Flow Pipeline（仅 Flow 实现生效）
+	Stages            []core.Stage           // 用户自定义阶段（过滤/转换/富化/丢弃）
+	StageErrorPolicy  core.StageErrorPolicy  // Stage 错误策略（默认 StageSkipBatch）
+	StageErrorHandler core.StageErrorHandler // StageRouteError 策略下的错误回调
+}
+
+// Opt Profile 修改函数（ForXxx 可选参数，按顺序应用）
+type Opt func(*Profile)
+
+// Apply 依次应用 opts 到 p 并返回 p
+func (p *Profile) Apply(opts ...Opt) *Profile {
+	for _, o := range opts {
+		if o != nil {
+			o(p)
+		}
+	}
+	return p
 }
 
 // ═══════════════════════════════════════════════════════════════════
@@ -129,7 +186,27 @@ func Preset(name string) *Profile {
 			Impl:         p.Impl,
 			EnableArena:  p.EnableArena,
 			BatchTimeout: p.BatchTimeout,
+			Ordered:      p.Ordered,
+			WorkStealing: p.WorkStealing,
 			Auto:         p.Auto,
+
+			Overflow:          p.Overflow,
+			OverflowTimeout:   p.OverflowTimeout,
+			Scale:             p.Scale,
+			PanicHandler:      p.PanicHandler,
+			ErrorHandler:      p.ErrorHandler,
+			ErrorMode:         p.ErrorMode,
+			Retain:            p.Retain,
+			DelayPolicy:       p.DelayPolicy,
+			DedupWindow:       p.DedupWindow,
+			DedupMaxEntries:   p.DedupMaxEntries,
+			RateLimits:        p.RateLimits,
+			PatternSyntax:     p.PatternSyntax,
+			MatchCacheSize:    p.MatchCacheSize,
+			HandlerLatency:    p.HandlerLatency,
+			Stages:            p.Stages,
+			StageErrorPolicy:  p.StageErrorPolicy,
+			StageErrorHandler: p.StageErrorHandler,
 		}
 	}
 	return Sync() // 默认使用sync场景
@@ -0,0 +1,392 @@
+package beat
+
+import (
+	"time"
+
+	"github.com/uniyakcom/beat/core"
+	"github.com/uniyakcom/beat/optimize"
+	"github.com/uniyakcom/beat/where"
+)
+
+// 溢出策略（导出 core 常量）
+const (
+	OverflowBlock        = core.OverflowBlock
+	OverflowBlockTimeout = core.OverflowBlockTimeout
+	OverflowDropNewest   = core.OverflowDropNewest
+	OverflowDropOldest   = core.OverflowDropOldest
+	OverflowSpill        = core.OverflowSpill
+)
+
+// ErrQueueFull 队列满且在超时内未能入队（OverflowBlockTimeout 策略）
+var ErrQueueFull = core.ErrQueueFull
+
+// ErrClosed Bus 已关闭（Barrier / EmitAwait 返回）
+var ErrClosed = core.ErrClosed
+
+// ErrNoResponders 请求的匹配 handler 均已执行完毕但没有回复
+var ErrNoResponders = core.ErrNoResponders
+
+// ErrNotRequest 对非 Request 发布的事件调用 Reply
+var ErrNotRequest = core.ErrNotRequest
+
+// ErrStopPropagation handler 返回它以结束事件的后续分发且不上报错误（Sync Bus）
+var ErrStopPropagation = core.ErrStopPropagation
+
+// ErrRateLimited 发布被限流规则拒绝（RateReject 策略）
+var ErrRateLimited = core.ErrRateLimited
+
+// ErrBadPattern pattern 语法无效（CompilePattern 与 New 等构造函数返回的错误包装它）
+var ErrBadPattern = core.ErrBadPattern
+
+// Stage 错误策略（导出 core 常量）
+const (
+	StageSkipBatch  = core.StageSkipBatch
+	StageRouteError = core.StageRouteError
+	StageContinue   = core.StageContinue
+)
+
+// WithStages 追加 Flow Pipeline 阶段（按参数顺序执行）
+// 阶段可过滤、转换、富化或丢弃批次中的事件，订阅者仅看到最后一个阶段的输出。
+//
+// 用法:
+//
+//	dropEmpty := func(batch []*beat.Event) ([]*beat.Event, error) {
+//	    out := batch[:0]
+//	    for _, e := range batch {
+//	        if len(e.Data) > 0 {
+//	            out = append(out, e)
+//	        }
+//	    }
+//	    return out, nil
+//	}
+//	bus, _ := beat.ForFlow(beat.WithStages(dropEmpty))
+func WithStages(stages ...Stage) Opt {
+	return func(p *optimize.Profile) {
+		p.Stages = append(p.Stages, stages...)
+	}
+}
+
+// WithStageErrorPolicy 设置 Stage 返回 error 时的批次处理策略
+// handler 仅在 StageRouteError 策略下调用，可为 nil。
+func WithStageErrorPolicy(policy core.StageErrorPolicy, handler core.StageErrorHandler) Opt {
+	return func(p *optimize.Profile) {
+		p.StageErrorPolicy = policy
+		p.StageErrorHandler = handler
+	}
+}
+
+// WithOrdered 启用按键有序投递（仅 Async 实现生效）
+// Event.Key 非空的事件按 key 哈希固定到同一 ring/worker，同一 key 按发布顺序处理，
+// 不同 key 仍并行；Key 为空的事件照常走 Per-P ring，不保证顺序。
+//
+// 用法:
+//
+//	bus, _ := beat.ForAsync(beat.WithOrdered())
+//	bus.Emit(&beat.Event{Type: "order.updated", Key: orderID, Data: payload})
+func WithOrdered() Opt {
+	return func(p *optimize.Profile) {
+		p.Ordered = true
+	}
+}
+
+// WithWorkStealing 启用 worker 间工作窃取（仅 Async 实现生效）
+// 空闲 worker 从积压最多的 Per-P ring 取走批次处理，单个慢 handler 不再让其 ring 的积压独自堆积。
+// 同一 ring 的事件按批出队，不同批次可能由不同 worker 并发处理，因此 Key 为空的事件不再有任何顺序；
+// 与 WithOrdered 同用时 Key 非空的事件不参与窃取，同一 key 仍按发布顺序处理。
+//
+// 用法:
+//
+//	bus, _ := beat.ForAsync(beat.WithWorkStealing())
+func WithWorkStealing() Opt {
+	return func(p *optimize.Profile) {
+		p.WorkStealing = true
+	}
+}
+
+// WithElasticWorkers 开启弹性 worker（仅 Async 实现生效）
+// worker 数在 [policy.Min, policy.Max] 间按 ring 积压与 worker 泊车时间自动加减（带滞回），
+// 也可经 Resizer.Resize 手动调整；每次调整交给 policy.Observer。初始 worker 数为预设值按 [Min, Max] 截断。
+// 伸缩期间 ring 归属经消费租约交接，ring 内顺序与 WithOrdered 的按键有序不受影响。
+//
+// 用法:
+//
+//	bus, _ := beat.ForAsync(beat.WithElasticWorkers(beat.ScalePolicy{
+//	    Min: 1, Max: 16,
+//	    Observer: func(ev beat.ScaleEvent) { log.Printf("workers %d→%d (%s)", ev.From, ev.To, ev.Reason) },
+//	}))
+//	bus.(beat.Resizer).Resize(8)
+func WithElasticWorkers(policy ScalePolicy) Opt {
+	return func(p *optimize.Profile) {
+		p.Scale = policy
+	}
+}
+
+// WithOverflow 设置 ring 满时的处理策略（Async / Sync 异步模式生效）
+// timeout 仅 OverflowBlockTimeout 使用（0=100ms）；丢弃与超时的事件计入 Stats().Dropped。
+//
+// 用法:
+//
+//	bus, _ := beat.ForAsync(beat.WithOverflow(beat.OverflowBlockTimeout, 50*time.Millisecond))
+//	if err := bus.Emit(evt); errors.Is(err, beat.ErrQueueFull) {
+//	    // 消费者卡住：降级处理
+//	}
+func WithOverflow(policy core.OverflowPolicy, timeout time.Duration) Opt {
+	return func(p *optimize.Profile) {
+		p.Overflow = policy
+		p.OverflowTimeout = timeout
+	}
+}
+
+// WithPanicHandler 注册 handler panic 回调（Sync / Async / Flow 均生效）
+// 回调收到 recover 值与触发 panic 的事件；Stats().Panics 照常计数。
+// 需要订阅 ID/模式时使用 WithPanicInfoHandler（二者共用一个回调，后设置的生效）。
+func WithPanicHandler(h PanicHandler) Opt {
+	return func(p *optimize.Profile) {
+		p.PanicHandler = core.PanicInfo(h)
+	}
+}
+
+// WithPanicInfoHandler 注册带订阅信息的 handler panic 回调（Sync / Async / Flow 均生效）
+// 回调收到 recover 值、触发 panic 的事件及订阅 ID/模式；Stats().Panics 照常计数。
+//
+// 用法:
+//
+//	bus, _ := beat.ForAsync(beat.WithPanicInfoHandler(func(r interface{}, evt *beat.Event, sub beat.SubInfo) {
+//	    alert.Send("handler %d (%s) panic on %s: %v", sub.ID, sub.Pattern, evt.Type, r)
+//	}))
+func WithPanicInfoHandler(h PanicInfoHandler) Opt {
+	return func(p *optimize.Profile) {
+		p.PanicHandler = h
+	}
+}
+
+// WithErrorHandler 注册 handler error 回调（Sync / Async / Flow 均生效）
+// 每个返回 error 的 handler 触发一次回调，收到 error、事件及订阅 ID/模式；
+// Stats().Errors / ErrorsByPattern 照常计数。panic 不经过此回调（见 WithPanicInfoHandler）。
+// On / OnWith 的 pattern 语法无效时也回调一次（err 包装 ErrBadPattern，evt 为 nil）。
+//
+// 用法:
+//
+//	bus, _ := beat.ForAsync(beat.WithErrorHandler(func(err error, evt *beat.Event, sub beat.SubInfo) {
+//	    log.Printf("handler %d (%s) failed on %s: %v", sub.ID, sub.Pattern, evt.Type, err)
+//	}))
+func WithErrorHandler(h ErrorHandler) Opt {
+	return func(p *optimize.Profile) {
+		p.ErrorHandler = h
+	}
+}
+
+// 同步 Emit 的 handler 失败处理方式
+const (
+	ErrorFailFast = core.ErrorFailFast // 第一个失败即停止，返回该 error（默认）
+	ErrorRunAll   = core.ErrorRunAll   // 调用全部 handler，以 *EmitError 汇总所有失败
+)
+
+// WithErrorMode 设置同步 Emit 的 handler 失败处理方式（仅 Sync 同步模式生效）
+// ErrorRunAll 下 Emit/EmitMatch/EmitBatch 调用全部匹配 handler（panic 后也继续），
+// 结束后返回 *EmitError，逐条记录订阅 ID、模式、handler error 及是否为 panic。
+//
+// 用法:
+//
+//	bus, _ := beat.ForSync(beat.WithErrorMode(beat.ErrorRunAll))
+//	var ee *beat.EmitError
+//	if errors.As(bus.Emit(evt), &ee) {
+//	    for _, f := range ee.Failures {
+//	        report(f.Sub.Pattern, f.Err)
+//	    }
+//	}
+func WithErrorMode(mode core.ErrorMode) Opt {
+	return func(p *optimize.Profile) {
+		p.ErrorMode = mode
+	}
+}
+
+// WithRetain 自动保留类型匹配 patterns（支持通配符）的事件（Sync / Async / Flow 均生效）
+// 每个事件类型保留最新一个副本，之后注册的匹配订阅立即收到；读取与清除见 beat.Retainer。
+//
+// 用法:
+//
+//	bus, _ := beat.ForAsync(beat.WithRetain("config.*", "leader.elected"))
+//	bus.Emit(&beat.Event{Type: "config.updated", Data: cfg})
+//	bus.On("config.**", reload) // 启动较晚的服务立即收到 config.updated
+func WithRetain(patterns ...string) Opt {
+	return func(p *optimize.Profile) {
+		p.Retain = append(p.Retain, patterns...)
+	}
+}
+
+// Drain 时未到期延迟事件（EmitAfter / EmitAt）的处理方式
+const (
+	DelayFire   = core.DelayFire   // 按到期顺序立即发布（默认）
+	DelayReport = core.DelayReport // 不发布，Drain 以 *DelayedPendingError 返回
+)
+
+// WithDelayPolicy 设置 Drain 时未到期延迟事件的处理方式（Sync / Async / Flow 均生效）
+//
+// 用法:
+//
+//	bus, _ := beat.ForAsync(beat.WithDelayPolicy(beat.DelayReport))
+//	var pe *beat.DelayedPendingError
+//	if errors.As(bus.Drain(5*time.Second), &pe) {
+//	    persist(pe.Events) // 重启后重新 EmitAt
+//	}
+func WithDelayPolicy(policy core.DelayPolicy) Opt {
+	return func(p *optimize.Profile) {
+		p.DelayPolicy = policy
+	}
+}
+
+// WithDedup 按 Event.ID 去重（Sync / Async / Flow 均生效）
+// window 内 ID 相同的事件只发布第一个，其余在匹配订阅前丢弃并计入 Stats().Duplicates；
+// ID 为空的事件不参与去重。已见 ID 分片记录，最多 maxEntries 个（<=0 时 65536），超出时淘汰最早记录。
+// Sync 的 Unsafe* 与 Async 的 UnsafeEmitMatch 为零保护路径，不做去重。
+//
+// 用法:
+//
+//	bus, _ := beat.ForAsync(beat.WithDedup(5*time.Minute, 1<<20))
+//	bus.Emit(&beat.Event{Type: "payment.settled", ID: msgID}) // 上游重投的同一 ID 被丢弃
+func WithDedup(window time.Duration, maxEntries int) Opt {
+	return func(p *optimize.Profile) {
+		p.DedupWindow = window
+		p.DedupMaxEntries = maxEntries
+	}
+}
+
+// 发布侧限流命中时的处理方式
+const (
+	RateReject = core.RateReject // Emit 返回 ErrRateLimited（默认）
+	RateDelay  = core.RateDelay  // 阻塞等待令牌后发布（EmitCtx 的 ctx 结束时返回 ctx.Err()）
+	RateDrop   = core.RateDrop   // 静默丢弃，Emit 返回 nil
+)
+
+// WithEmitRateLimit 按事件类型 pattern 限制发布速率（令牌桶；Sync / Async / Flow 均生效）
+// 类型匹配 pattern 的全部事件共享一个每秒 rate 个令牌、容量 burst 的令牌桶，
+// 令牌不足时按 policy 拒绝、等待或丢弃，命中次数计入 Stats().RateLimited。
+// 可多次调用配置多条规则，同一事件匹配多条规则时须全部通过（被拒绝时归还其他规则已预定的令牌）。
+// 批量发布中被拒绝的事件从批次中移除，其余照常发布。
+// Sync 的 Unsafe* 与 Async 的 UnsafeEmitMatch 为零保护路径，不做限流。
+//
+// 用法:
+//
+//	bus, _ := beat.ForAsync(
+//	    beat.WithEmitRateLimit("metric.**", 1000, 100, beat.RateDrop), // 指标洪峰不挤占 worker
+//	    beat.WithEmitRateLimit("mail.send", 10, 10, beat.RateDelay),
+//	)
+func WithEmitRateLimit(pattern string, rate float64, burst int, policy RatePolicy) Opt {
+	return func(p *optimize.Profile) {
+		p.RateLimits = append(p.RateLimits, core.RateLimit{
+			Pattern: pattern,
+			Rate:    rate,
+			Burst:   burst,
+			Policy:  policy,
+		})
+	}
+}
+
+// pattern 语法
+var (
+	DotSyntax  = core.DotSyntax  // user.created、user.*、user.**（默认）
+	MQTTSyntax = core.MQTTSyntax // sensor/+/temp、sensor/#
+)
+
+// WithPatternSyntax 设置 pattern 的段分隔符与通配符记号（Sync / Async / Flow 均生效）
+// 订阅、EmitMatch、WithRetain 与 WithEmitRateLimit 的 pattern 均按该语法解析；
+// 保留或限流规则中的 pattern 语法无效时构造函数返回包装 ErrBadPattern 的错误。
+//
+// 用法:
+//
+//	bus, _ := beat.ForAsync(beat.WithPatternSyntax(beat.MQTTSyntax))
+//	bus.On("sensor/+/temp", onTemp)
+//	bus.On("sensor/#", onAny) // 也匹配 sensor 本身
+func WithPatternSyntax(s PatternSyntax) Opt {
+	return func(p *optimize.Profile) {
+		p.PatternSyntax = s
+	}
+}
+
+// WithMatchCacheSize 设置通配符匹配结果缓存的容量（条目数；Sync / Async / Flow 均生效）
+// EmitMatch 按事件类型缓存匹配到的 pattern，每个不同的事件类型占一条；
+// 缓存满时按 CLOCK 淘汰最近未命中的条目，内存不随事件类型数增长。
+// n 为 0 时使用默认容量 4096，为负时不缓存。命中、未命中与淘汰次数见 Stats()。
+//
+// 用法:
+//
+//	// 事件类型含租户 ID（tenant.<id>.order.created），活跃租户约 2 万
+//	bus, _ := beat.ForAsync(beat.WithMatchCacheSize(32768))
+func WithMatchCacheSize(n int) Opt {
+	return func(p *optimize.Profile) {
+		p.MatchCacheSize = n
+	}
+}
+
+// WithHandlerLatency 按订阅 pattern 记录 handler 执行耗时（Sync / Async / Flow 均生效）
+// 订阅时为 handler 包一层计时，耗时写入该 pattern 的 per-CPU 对数分桶直方图（同一 pattern 的订阅共用）；
+// 经 LatencyStatter 查询分位数或清零。未开启时订阅与分发路径不增加任何开销。
+// 计时只覆盖 handler 本身（不含订阅算子、限流与谓词），panic 的调用不计入。
+//
+// 用法:
+//
+//	bus, _ := beat.ForAsync(beat.WithHandlerLatency())
+//	for pattern, h := range bus.(beat.LatencyStatter).HandlerLatency() {
+//	    log.Printf("%s p99=%v max=%v", pattern, h.Percentile(99), h.Max)
+//	}
+func WithHandlerLatency() Opt {
+	return func(p *optimize.Profile) {
+		p.HandlerLatency = true
+	}
+}
+
+// WithErrorSink 订阅级错误回调（用于 OnWith，先于 Bus 级 ErrorHandler 调用）
+func WithErrorSink(h ErrorHandler) SubOption {
+	return core.WithErrorSink(h)
+}
+
+// 订阅组均衡策略
+const (
+	GroupRoundRobin = core.GroupRoundRobin // 组内轮询（默认）
+	GroupByKey      = core.GroupByKey      // 按 Event.Key 哈希固定成员（Key 为空时轮询）
+)
+
+// WithPriority 订阅调用优先级（用于 OnWith，仅 Sync Bus：越大越先调用，默认 0）
+func WithPriority(p int) SubOption {
+	return core.WithPriority(p)
+}
+
+// WithGroup 以订阅组（竞争消费者）订阅（用于 OnWith，等价于 OnGroup）
+func WithGroup(name string) SubOption {
+	return core.WithGroup(name)
+}
+
+// WithGroupBalance 订阅组均衡策略（用于 OnGroup/OnWith，以组内最早加入成员的设置为准）
+func WithGroupBalance(b GroupBalance) SubOption {
+	return core.WithGroupBalance(b)
+}
+
+// 订阅侧限流命中时的处理方式
+const (
+	SubRateDrop     = core.SubRateDrop     // 丢弃本次事件（默认）
+	SubRateCoalesce = core.SubRateCoalesce // 只保留最新事件，令牌可用时投递
+)
+
+// WithRateLimit 限制订阅 handler 的调用频率（用于 OnWith；每秒 rate 次，突发 burst 次）
+// 令牌不足时按 mode 丢弃或合并，命中次数计入 Stats().HandlerRateLimited。
+func WithRateLimit(rate float64, burst int, mode SubRateMode) SubOption {
+	return core.WithRateLimit(rate, burst, mode)
+}
+
+// WithOperators 为订阅挂载算子（用于 OnWith；operator 包的 Debounce / Throttle / Sample 等，前者在外层）
+// 定时器挂在 Bus 的时间轮上，Off 时随订阅取消。
+func WithOperators(ops ...Operator) SubOption {
+	return core.WithOperators(ops...)
+}
+
+// WithWhere 为订阅挂载负载谓词（用于 OnWith；where.Compile 编译，对 Event.Data 的 JSON 字段求值）
+// 求值为 false 的事件不交给 handler，计入 Stats().Filtered。
+//
+// 用法:
+//
+//	bus.(core.OptionSubscriber).OnWith("order.*", handle,
+//	    beat.WithWhere(where.MustCompile(`amount > 100 && region == "eu"`)))
+func WithWhere(x *where.Expr) SubOption {
+	return core.WithWhere(x)
+}
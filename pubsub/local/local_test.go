@@ -33,6 +33,7 @@ func TestPublishSubscribe(t *testing.T) {
 	payload := []byte(`{"key":"value"}`)
 	msg := message.New("", payload)
 	msg.Metadata.Set("foo", "bar")
+	msg.Key = "order-42"
 
 	if err := pub.Publish(context.Background(), "test.topic", msg); err != nil {
 		t.Fatal(err)
@@ -50,6 +51,9 @@ func TestPublishSubscribe(t *testing.T) {
 		if received.Metadata.Get("_topic") != "test.topic" {
 			t.Errorf("topic metadata: got %s, want test.topic", received.Metadata.Get("_topic"))
 		}
+		if received.Key != "order-42" {
+			t.Errorf("key mismatch: got %q, want order-42", received.Key)
+		}
 	case <-time.After(time.Second):
 		t.Fatal("timeout waiting for message")
 	}
@@ -199,3 +203,96 @@ func BenchmarkLocalPublishSubscribe(b *testing.B) {
 	}
 	b.StopTimer()
 }
+
+type traceKey struct{}
+
+func TestPublishContextFlowsToMessage(t *testing.T) {
+	bus, err := beat.ForAsync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bus.Close()
+
+	pub := local.NewPublisher(bus)
+	sub := local.NewSubscriber(bus)
+	defer sub.Close()
+
+	msgCh, err := sub.Subscribe(context.Background(), "ctx.topic")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.WithValue(context.Background(), traceKey{}, "trace-7")
+	if err := pub.Publish(ctx, "ctx.topic", message.New("", []byte("x"))); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case received := <-msgCh:
+		if got, _ := received.Context().Value(traceKey{}).(string); got != "trace-7" {
+			t.Errorf("message context value = %q, want trace-7", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for message")
+	}
+}
+
+func TestGroupSubscriberWorkQueue(t *testing.T) {
+	bus, err := beat.ForSync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bus.Close()
+
+	pub := local.NewPublisher(bus)
+	sub := local.NewGroupSubscriber(bus, "workers")
+	defer sub.Close()
+	fanout := local.NewSubscriber(bus)
+	defer fanout.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var chs []<-chan *message.Message
+	for i := 0; i < 3; i++ {
+		ch, err := sub.Subscribe(ctx, "jobs")
+		if err != nil {
+			t.Fatal(err)
+		}
+		chs = append(chs, ch)
+	}
+	all, err := fanout.Subscribe(ctx, "jobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const count = 30
+	for i := 0; i < count; i++ {
+		if err := pub.Publish(context.Background(), "jobs", message.New("", []byte{byte(i)})); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 组内每条消息只投递一次，普通订阅者收到全部消息
+	seen := make(map[byte]int)
+	for i, ch := range chs {
+		n := len(ch)
+		if n == 0 {
+			t.Errorf("group member %d received nothing", i)
+		}
+		for j := 0; j < n; j++ {
+			seen[(<-ch).Payload[0]]++
+		}
+	}
+	if len(seen) != count {
+		t.Errorf("group received %d distinct messages, want %d", len(seen), count)
+	}
+	for id, n := range seen {
+		if n != 1 {
+			t.Errorf("message %d delivered %d times to group", id, n)
+		}
+	}
+	if len(all) != count {
+		t.Errorf("plain subscriber received %d, want %d", len(all), count)
+	}
+}
@@ -40,19 +40,29 @@ func NewPublisher(bus core.Bus) *Publisher {
 //   - topic → Event.Type
 //   - msg.Payload → Event.Data
 //   - msg.UUID → Event.ID
+//   - msg.Key → Event.Key（Async 有序模式下同 Key 消息按发布顺序处理）
 //   - msg.Metadata → Event.Metadata
-func (p *Publisher) Publish(_ context.Context, topic string, messages ...*message.Message) error {
+//   - ctx → Event.Context()（Bus 实现 core.ContextEmitter 时，经 EmitCtx 发布）
+func (p *Publisher) Publish(ctx context.Context, topic string, messages ...*message.Message) error {
+	ce, _ := p.bus.(core.ContextEmitter)
 	for _, msg := range messages {
 		evt := &core.Event{
 			Type:     topic,
 			Data:     msg.Payload,
 			ID:       msg.UUID,
+			Key:      msg.Key,
 			Metadata: make(map[string]string, len(msg.Metadata)),
 		}
 		for k, v := range msg.Metadata {
 			evt.Metadata[k] = v
 		}
-		if err := p.bus.Emit(evt); err != nil {
+		var err error
+		if ce != nil && ctx != nil {
+			err = ce.EmitCtx(ctx, evt)
+		} else {
+			err = p.bus.Emit(evt)
+		}
+		if err != nil {
 			return err
 		}
 	}
@@ -2,12 +2,16 @@ package local
 
 import (
 	"context"
+	"errors"
 	"sync"
 
 	"github.com/uniyakcom/beat/core"
 	"github.com/uniyakcom/beat/message"
 )
 
+// ErrGroupUnsupported Bus 未实现 core.GroupSubscriber，无法按订阅组订阅
+var ErrGroupUnsupported = errors.New("local: bus does not support subscription groups")
+
 // Subscriber 基于 beat Bus 的本地订阅者
 type Subscriber struct {
 	bus    core.Bus
@@ -15,6 +19,10 @@ type Subscriber struct {
 	once   sync.Once
 	mu     sync.Mutex
 	subIDs []uint64 // 跟踪订阅 ID，Close 时清理
+
+	// 竞争消费者订阅组（空=每次 Subscribe 都收到主题的全部消息）
+	group     string
+	groupOpts []core.SubOption
 }
 
 // NewSubscriber 创建本地订阅者。
@@ -25,15 +33,43 @@ func NewSubscriber(bus core.Bus) *Subscriber {
 	}
 }
 
+// NewGroupSubscriber 创建按订阅组消费的本地订阅者（工作队列语义）。
+//
+// 每次 Subscribe 都以 group 加入该主题的竞争消费者组：同一主题的每条消息只交给组内一个订阅，
+// 因此多个 router handler 共用此 Subscriber 订阅同一主题时，消息在它们之间分摊而非重复处理。
+// opts 可指定组内均衡策略（如 core.WithGroupBalance(core.GroupByKey) 按 Message.Key 固定成员）。
+//
+// 用法：
+//
+//	sub := local.NewGroupSubscriber(bus, "order-workers")
+//	for i := 0; i < 4; i++ {
+//	    r.On(fmt.Sprintf("worker-%d", i), "order.created", sub, handleOrder)
+//	}
+func NewGroupSubscriber(bus core.Bus, group string, opts ...core.SubOption) *Subscriber {
+	s := NewSubscriber(bus)
+	s.group = group
+	s.groupOpts = opts
+	return s
+}
+
 // Subscribe 订阅主题，返回消息通道。
 //
 // 内部注册 beat On handler，将 core.Event 转换为 message.Message 后写入通道。
 // 通道在 ctx 取消或 Subscriber.Close() 时停止接收新消息。
 func (s *Subscriber) Subscribe(ctx context.Context, topic string) (<-chan *message.Message, error) {
+	var gs core.GroupSubscriber
+	if s.group != "" {
+		var ok bool
+		if gs, ok = s.bus.(core.GroupSubscriber); !ok {
+			return nil, ErrGroupUnsupported
+		}
+	}
 	output := make(chan *message.Message, 256)
 
-	id := s.bus.On(topic, func(e *core.Event) error {
+	handler := func(e *core.Event) error {
 		msg := message.New(e.ID, e.Data)
+		msg.Key = e.Key
+		msg.SetContext(e.Context())
 		msg.Metadata.Set("_topic", e.Type)
 		msg.Metadata.Set("_source", e.Source)
 		// 复制事件元数据
@@ -47,7 +83,13 @@ func (s *Subscriber) Subscribe(ctx context.Context, topic string) (<-chan *messa
 		case <-s.done:
 		}
 		return nil
-	})
+	}
+	var id uint64
+	if gs != nil {
+		id = gs.OnGroup(topic, s.group, handler, s.groupOpts...)
+	} else {
+		id = s.bus.On(topic, handler)
+	}
 
 	// 跟踪订阅 ID，在 Close 时清理
 	s.mu.Lock()
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/uniyakcom/beat/message"
+	"github.com/uniyakcom/beat/util"
 )
 
 // HandlerFunc 消息处理函数（单条）
@@ -59,6 +60,9 @@ type Handler struct {
 
 	// 流控
 	maxInFlight int // 最大在途消息（0 = 无限）
+
+	// 耗时
+	latency *util.PerCPUHistogram // 处理函数（含中间件）每次执行的耗时（未开启 Config.HandlerLatency 时为 nil）
 }
 
 // AddMiddleware 添加 Handler 专属中间件。
@@ -112,6 +116,28 @@ func (h *Handler) InFlight(n int) *Handler {
 	return h
 }
 
+// Name 返回 Handler 名称。
+func (h *Handler) Name() string {
+	return h.name
+}
+
+// Latency 返回处理函数（含中间件）的执行耗时分布。
+// 单条模式每次执行记录一次（路由器级重试的每次尝试分别记录），批量模式每批记录一次；panic 的执行不计入。
+// 需以 Config{HandlerLatency: true} 创建路由器，否则返回空分布。
+func (h *Handler) Latency() util.HistogramSnapshot {
+	if h.latency == nil {
+		return util.HistogramSnapshot{}
+	}
+	return h.latency.Snapshot()
+}
+
+// ResetLatency 清零执行耗时分布（如按统计周期上报后调用）。
+func (h *Handler) ResetLatency() {
+	if h.latency != nil {
+		h.latency.Reset()
+	}
+}
+
 // resolveTopic 解析产出消息的目标 topic。
 func (h *Handler) resolveTopic(msg *message.Message) string {
 	if h.topicFunc != nil {
@@ -18,6 +18,7 @@ import (
 	"time"
 
 	"github.com/uniyakcom/beat/message"
+	"github.com/uniyakcom/beat/util"
 )
 
 // Router 消息路由器
@@ -26,6 +27,7 @@ type Router struct {
 	middlewares []Middleware
 	plugins     []Plugin
 	logger      *slog.Logger
+	latency     bool // 是否记录 Handler 执行耗时
 
 	running     chan struct{}
 	runningOnce sync.Once
@@ -39,23 +41,37 @@ type Router struct {
 type Config struct {
 	// Logger 自定义日志。为 nil 时使用 slog.Default()。
 	Logger *slog.Logger
+	// HandlerLatency 为 true 时记录每个 Handler 处理函数（含中间件）的执行耗时，见 Handler.Latency。
+	// 默认关闭：不读时钟、不分配直方图。
+	HandlerLatency bool
 }
 
 // NewRouter 创建路由器。
 func NewRouter(cfg ...Config) *Router {
-	var logger *slog.Logger
-	if len(cfg) > 0 && cfg[0].Logger != nil {
-		logger = cfg[0].Logger
-	} else {
+	var c Config
+	if len(cfg) > 0 {
+		c = cfg[0]
+	}
+	logger := c.Logger
+	if logger == nil {
 		logger = slog.Default()
 	}
 	return &Router{
 		logger:  logger,
+		latency: c.HandlerLatency,
 		running: make(chan struct{}),
 		closed:  make(chan struct{}),
 	}
 }
 
+// newLatency 按 Config.HandlerLatency 为新 Handler 创建耗时直方图（关闭时为 nil）
+func (r *Router) newLatency() *util.PerCPUHistogram {
+	if !r.latency {
+		return nil
+	}
+	return util.NewPerCPUHistogram()
+}
+
 // Use 添加全局中间件（对所有 Handler 生效）。
 func (r *Router) Use(m ...Middleware) {
 	r.middlewares = append(r.middlewares, m...)
@@ -93,6 +109,7 @@ func (r *Router) Handle(
 		publisher:      publisher,
 		handlerFunc:    handlerFunc,
 		logger:         r.logger.With("handler", name),
+		latency:        r.newLatency(),
 	}
 	r.handlers = append(r.handlers, h)
 	return h
@@ -142,6 +159,7 @@ func (r *Router) HandleBatch(
 		batchSize:      batchSize,
 		batchTimeout:   batchTimeout,
 		logger:         r.logger.With("handler", name),
+		latency:        r.newLatency(),
 	}
 	r.handlers = append(r.handlers, h)
 	return h
@@ -406,7 +424,14 @@ func (r *Router) processMessage(ctx context.Context, h *Handler, fn HandlerFunc,
 
 	var lastErr error
 	for attempt := 0; attempt < maxAttempts; attempt++ {
+		var start time.Time
+		if h.latency != nil {
+			start = time.Now()
+		}
 		producedMsgs, err := fn(msg)
+		if h.latency != nil {
+			h.latency.Record(time.Since(start))
+		}
 		if err != nil {
 			lastErr = err
 			if attempt < maxAttempts-1 {
@@ -445,7 +470,14 @@ func (r *Router) processBatch(ctx context.Context, h *Handler, fn BatchFunc, msg
 		}
 	}()
 
+	var start time.Time
+	if h.latency != nil {
+		start = time.Now()
+	}
 	producedMsgs, err := fn(msgs)
+	if h.latency != nil {
+		h.latency.Record(time.Since(start))
+	}
 	if err != nil {
 		h.logger.Error("batch handler error", "error", err, "count", len(msgs))
 		for _, m := range msgs {
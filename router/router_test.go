@@ -294,3 +294,95 @@ func TestRouterHandlers(t *testing.T) {
 		t.Errorf("handlers count = %d, want 2", got)
 	}
 }
+
+func TestRouterHandlerLatency(t *testing.T) {
+	bus, err := beat.ForSync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bus.Close()
+
+	sub := local.NewSubscriber(bus)
+	pub := local.NewPublisher(bus)
+
+	r := router.NewRouter(router.Config{HandlerLatency: true})
+	var received atomic.Int64
+	h := r.On("slow", "order.created", sub, func(msg *message.Message) error {
+		time.Sleep(time.Millisecond)
+		received.Add(1)
+		return nil
+	})
+	if h.Name() != "slow" {
+		t.Errorf("Name() = %q, want %q", h.Name(), "slow")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		_ = r.Run(ctx)
+	}()
+	<-r.Running()
+
+	for i := 0; i < 5; i++ {
+		_ = pub.Publish(context.Background(), "order.created", message.New("", nil))
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for received.Load() < 5 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-r.Closed()
+
+	s := h.Latency()
+	if s.Count != 5 {
+		t.Fatalf("Latency().Count = %d, want 5", s.Count)
+	}
+	if p := s.Percentile(50); p < time.Millisecond {
+		t.Errorf("p50 = %v, want >= 1ms", p)
+	}
+	h.ResetLatency()
+	if c := h.Latency().Count; c != 0 {
+		t.Errorf("after ResetLatency: %d records", c)
+	}
+}
+
+func TestRouterHandlerLatencyDisabled(t *testing.T) {
+	bus, err := beat.ForSync()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bus.Close()
+
+	sub := local.NewSubscriber(bus)
+	pub := local.NewPublisher(bus)
+
+	r := router.NewRouter()
+	var received atomic.Int64
+	h := r.On("plain", "order.created", sub, func(msg *message.Message) error {
+		received.Add(1)
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		_ = r.Run(ctx)
+	}()
+	<-r.Running()
+
+	for i := 0; i < 3; i++ {
+		_ = pub.Publish(context.Background(), "order.created", message.New("", nil))
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for received.Load() < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-r.Closed()
+
+	if received.Load() != 3 {
+		t.Fatalf("received %d messages, want 3", received.Load())
+	}
+	if c := h.Latency().Count; c != 0 {
+		t.Errorf("Latency().Count without HandlerLatency = %d, want 0", c)
+	}
+	h.ResetLatency()
+}
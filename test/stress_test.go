@@ -1,7 +1,10 @@
 package beat_test
 
 import (
+	"context"
+	"encoding/binary"
 	"runtime"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -419,3 +422,93 @@ func TestStressBatchEmit(t *testing.T) {
 		t.Errorf("expected %d events, got %d", expected, actual)
 	}
 }
+
+// TestStressGOMAXPROCSChange 生产者运行期间反复调整 GOMAXPROCS
+// Async 的 ring 数按创建时的 GOMAXPROCS 确定；之后新增的 P 不得与已有 P 共写一个 SPSC ring，
+// 否则事件会丢失或重复。这里校验每个事件恰好被处理一次。
+func TestStressGOMAXPROCSChange(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping stress test in short mode")
+	}
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(1)) // 以 1 个 P 创建 Bus（1 个 ring）
+
+	for name, opts := range map[string][]beat.Opt{
+		"async":   nil,
+		"ordered": {beat.WithOrdered()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			runtime.GOMAXPROCS(1)
+			bus, err := beat.ForAsync(opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer bus.Close()
+
+			const producers, perProducer = 8, 20000
+			seen := make([]atomic.Int32, producers*perProducer)
+			bus.On("stress.procs", func(e *beat.Event) error {
+				seen[binary.LittleEndian.Uint32(e.Data)].Add(1)
+				return nil
+			})
+
+			stop := make(chan struct{})
+			changed := make(chan struct{})
+			go func() {
+				defer close(changed)
+				for i := 0; ; i++ {
+					select {
+					case <-stop:
+						return
+					default:
+					}
+					runtime.GOMAXPROCS([]int{8, 2, 16, 1, 4}[i%5])
+					time.Sleep(200 * time.Microsecond)
+				}
+			}()
+
+			var wg sync.WaitGroup
+			for p := 0; p < producers; p++ {
+				wg.Add(1)
+				go func(p int) {
+					defer wg.Done()
+					for i := 0; i < perProducer; i++ {
+						data := make([]byte, 4)
+						binary.LittleEndian.PutUint32(data, uint32(p*perProducer+i))
+						evt := &beat.Event{Type: "stress.procs", Data: data}
+						if i%3 == 0 {
+							evt.Key = strconv.Itoa(i % 7)
+						}
+						if err := bus.Emit(evt); err != nil {
+							t.Errorf("Emit: %v", err)
+							return
+						}
+					}
+				}(p)
+			}
+			wg.Wait()
+			close(stop)
+			<-changed
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := bus.(beat.Awaiter).Barrier(ctx); err != nil {
+				t.Fatalf("Barrier: %v", err)
+			}
+			var lost, dup int
+			for i := range seen {
+				switch n := seen[i].Load(); {
+				case n == 0:
+					lost++
+				case n > 1:
+					dup++
+				}
+			}
+			if lost+dup > 0 {
+				t.Errorf("%d events lost, %d processed more than once (of %d)", lost, dup, len(seen))
+			}
+			if s := bus.Stats(); s.Processed != int64(len(seen)) {
+				t.Errorf("Stats().Processed = %d, want %d", s.Processed, len(seen))
+			}
+		})
+	}
+}
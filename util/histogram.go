@@ -0,0 +1,237 @@
+package util
+
+import (
+	"math/bits"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+// 分桶: 小于 16ns 的值各占一桶；之后每个 2 的幂区间线性细分为 16 个子桶（相对误差 ≤ 1/16）
+const (
+	histSubBits = 4
+	histSub     = 1 << histSubBits
+	histMaxExp  = 36 // 上限 2^36ns ≈ 68.7s，超出的值计入最后一个桶（Max 仍精确）
+	histBuckets = (histMaxExp - histSubBits + 1) * histSub
+)
+
+// Histogram 无锁对数分桶（HDR 风格）时长直方图
+// Record 为 2~3 次 atomic 操作、零分配；零值可用，可并发 Record 与 Snapshot。
+// 写入集中在单个 goroutine（如 ring 消费者）时使用；多 goroutine 并发写入用 PerCPUHistogram。
+type Histogram struct {
+	counts [histBuckets]atomic.Uint64
+	sum    atomic.Int64
+	max    atomic.Int64
+}
+
+// histIndex 值 v（ns）所在的桶
+func histIndex(v uint64) int {
+	if v < histSub {
+		return int(v)
+	}
+	if v >= 1<<histMaxExp {
+		v = 1<<histMaxExp - 1
+	}
+	e := bits.Len64(v) - 1
+	return (e-histSubBits+1)*histSub + int(v>>(e-histSubBits)&(histSub-1))
+}
+
+// histUpper 第 i 个桶的上界（ns，含）
+func histUpper(i int) int64 {
+	if i < histSub {
+		return int64(i)
+	}
+	shift := i/histSub - 1
+	return int64(histSub+i%histSub+1)<<shift - 1
+}
+
+// Record 记录一次时长（负值按 0 计）
+func (h *Histogram) Record(d time.Duration) {
+	v := int64(d)
+	if v < 0 {
+		v = 0
+	}
+	h.counts[histIndex(uint64(v))].Add(1)
+	h.sum.Add(v)
+	for {
+		m := h.max.Load()
+		if v <= m || h.max.CompareAndSwap(m, v) {
+			return
+		}
+	}
+}
+
+// Snapshot 返回当前分布的副本（与并发 Record 之间不保证原子一致，计数可能相差正在进行的几次记录）
+func (h *Histogram) Snapshot() HistogramSnapshot {
+	var s HistogramSnapshot
+	h.snapshotInto(&s)
+	return s
+}
+
+// Reset 清零全部计数（与并发 Record 之间不保证原子，正在进行的记录可能部分保留）
+func (h *Histogram) Reset() {
+	for i := range h.counts {
+		h.counts[i].Store(0)
+	}
+	h.sum.Store(0)
+	h.max.Store(0)
+}
+
+// snapshotInto 将计数累加到 s（Merge 语义）
+func (h *Histogram) snapshotInto(s *HistogramSnapshot) {
+	for i := range h.counts {
+		n := h.counts[i].Load()
+		s.counts[i] += n
+		s.Count += n
+	}
+	s.Sum += time.Duration(h.sum.Load())
+	if m := time.Duration(h.max.Load()); m > s.Max {
+		s.Max = m
+	}
+}
+
+// HistogramSnapshot 直方图快照（只读值，可自由复制）
+type HistogramSnapshot struct {
+	Count uint64        // 记录次数
+	Sum   time.Duration // 时长总和
+	Max   time.Duration // 最大值（精确）
+
+	counts [histBuckets]uint64
+}
+
+// Merge 将 o 的分布合并到 s（如汇总多个 pattern / 多个实例）
+func (s *HistogramSnapshot) Merge(o *HistogramSnapshot) {
+	for i, n := range o.counts {
+		s.counts[i] += n
+	}
+	s.Count += o.Count
+	s.Sum += o.Sum
+	if o.Max > s.Max {
+		s.Max = o.Max
+	}
+}
+
+// Mean 平均时长（无记录时为 0）
+func (s *HistogramSnapshot) Mean() time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	return s.Sum / time.Duration(s.Count)
+}
+
+// Percentile 返回第 p 百分位（0~100，如 99.9）所在桶的上界（不超过 Max；无记录时为 0）
+func (s *HistogramSnapshot) Percentile(p float64) time.Duration {
+	if s.Count == 0 {
+		return 0
+	}
+	rank := uint64(p / 100 * float64(s.Count))
+	if float64(rank) < p/100*float64(s.Count) {
+		rank++ // 向上取整
+	}
+	if rank < 1 {
+		rank = 1
+	}
+	var seen uint64
+	for i, n := range s.counts {
+		if seen += n; seen >= rank {
+			// 最后一个桶无上界（超出 2^36ns 的值），直接取 Max
+			if v := time.Duration(histUpper(i)); v < s.Max && i < histBuckets-1 {
+				return v
+			}
+			return s.Max
+		}
+	}
+	return s.Max
+}
+
+// maxHistSlots PerCPUHistogram 最大 stripe 数（每个 stripe 约 4KB）
+const maxHistSlots = 64
+
+// PerCPUHistogram per-CPU 无锁时长直方图（多 goroutine 并发写入时避免 atomic 竞争）
+// 与 PerCPUCounter 相同，按 goroutine 栈地址哈希分散到不同 stripe；stripe 在首次写入时惰性分配。
+// Snapshot 合并全部 stripe。
+type PerCPUHistogram struct {
+	stripes [maxHistSlots]atomic.Pointer[Histogram]
+	mask    int
+}
+
+// NewPerCPUHistogram 创建 per-CPU 直方图（stripe 数 = GOMAXPROCS 向上取 2 的幂，范围 [8, 64]）
+func NewPerCPUHistogram() *PerCPUHistogram {
+	n := runtime.GOMAXPROCS(0)
+	sz := 8
+	for sz < n && sz < maxHistSlots {
+		sz *= 2
+	}
+	return &PerCPUHistogram{mask: sz - 1}
+}
+
+// Record 记录一次时长（负值按 0 计）
+func (h *PerCPUHistogram) Record(d time.Duration) {
+	var x uintptr
+	id := int(uintptr(unsafe.Pointer(&x))>>13) & h.mask // 同 PerCPUCounter.Add
+	s := h.stripes[id].Load()
+	if s == nil {
+		h.stripes[id].CompareAndSwap(nil, new(Histogram))
+		s = h.stripes[id].Load()
+	}
+	s.Record(d)
+}
+
+// Snapshot 合并全部 stripe 返回当前分布
+func (h *PerCPUHistogram) Snapshot() HistogramSnapshot {
+	var s HistogramSnapshot
+	for i := 0; i <= h.mask; i++ {
+		if st := h.stripes[i].Load(); st != nil {
+			st.snapshotInto(&s)
+		}
+	}
+	return s
+}
+
+// Reset 清零全部 stripe
+func (h *PerCPUHistogram) Reset() {
+	for i := 0; i <= h.mask; i++ {
+		if st := h.stripes[i].Load(); st != nil {
+			st.Reset()
+		}
+	}
+}
+
+// KeyedHistogram 按 key 分组的 per-CPU 直方图（sync.Map + *PerCPUHistogram）
+// 适用于 key 数量有界（如订阅模式）的场景；热路径应先用 Get 取出直方图再反复 Record。
+// 零值可用。
+type KeyedHistogram struct {
+	m sync.Map // key → *PerCPUHistogram
+}
+
+// Get 返回 key 对应的直方图（首次访问时惰性创建）
+func (k *KeyedHistogram) Get(key string) *PerCPUHistogram {
+	v, ok := k.m.Load(key)
+	if !ok {
+		v, _ = k.m.LoadOrStore(key, NewPerCPUHistogram())
+	}
+	return v.(*PerCPUHistogram)
+}
+
+// Snapshot 返回各 key 当前分布的副本（无数据时返回 nil）
+func (k *KeyedHistogram) Snapshot() map[string]HistogramSnapshot {
+	var out map[string]HistogramSnapshot
+	k.m.Range(func(key, v any) bool {
+		if out == nil {
+			out = make(map[string]HistogramSnapshot)
+		}
+		out[key.(string)] = v.(*PerCPUHistogram).Snapshot()
+		return true
+	})
+	return out
+}
+
+// Reset 清零全部 key 的分布（key 保留）
+func (k *KeyedHistogram) Reset() {
+	k.m.Range(func(_, v any) bool {
+		v.(*PerCPUHistogram).Reset()
+		return true
+	})
+}
@@ -0,0 +1,200 @@
+package util
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestHistogramBuckets 每个值落在 (上一桶上界, 本桶上界] 内，相对误差 ≤ 1/16；超限值计入最后一个桶
+func TestHistogramBuckets(t *testing.T) {
+	for i := 1; i < histBuckets; i++ {
+		if histUpper(i) <= histUpper(i-1) {
+			t.Fatalf("histUpper(%d) = %d, not above histUpper(%d) = %d", i, histUpper(i), i-1, histUpper(i-1))
+		}
+	}
+	if got, want := histUpper(histBuckets-1), int64(1)<<histMaxExp-1; got != want {
+		t.Errorf("last bucket upper = %d, want %d", got, want)
+	}
+
+	vals := []uint64{0, 1, 15, 16, 17, 31, 32, 33, 100, 1000, 1023, 1024, 12345, 1 << 20, 1<<20 + 1, 999_999_999, 1<<histMaxExp - 1}
+	for v := uint64(0); v < 4096; v++ {
+		vals = append(vals, v)
+	}
+	for _, v := range vals {
+		i := histIndex(v)
+		up := histUpper(i)
+		if int64(v) > up || (i > 0 && int64(v) <= histUpper(i-1)) {
+			t.Fatalf("value %d in bucket %d, bounds (%d, %d]", v, i, histUpper(i-1), up)
+		}
+		if err := uint64(up) - v; err > v/histSub {
+			t.Errorf("value %d: bucket upper %d, error %d > v/%d", v, up, err, histSub)
+		}
+	}
+	for _, v := range []uint64{1 << histMaxExp, 1<<histMaxExp + 1, 1 << 62} {
+		if i := histIndex(v); i != histBuckets-1 {
+			t.Errorf("histIndex(%d) = %d, want last bucket %d", v, i, histBuckets-1)
+		}
+	}
+}
+
+// TestHistogramRecordPercentile Count / Sum / Max / Mean 精确，百分位为所在桶上界且不超过 Max
+func TestHistogramRecordPercentile(t *testing.T) {
+	var h Histogram
+	s := h.Snapshot()
+	if s.Count != 0 || s.Percentile(99) != 0 || s.Mean() != 0 {
+		t.Fatalf("empty snapshot = %+v, p99 %v", s, s.Percentile(99))
+	}
+
+	for i := 1; i <= 100; i++ {
+		h.Record(time.Duration(i) * time.Microsecond)
+	}
+	h.Record(-time.Second) // 按 0 计
+	s = h.Snapshot()
+	if s.Count != 101 {
+		t.Errorf("Count = %d, want 101", s.Count)
+	}
+	if want := 5050 * time.Microsecond; s.Sum != want {
+		t.Errorf("Sum = %v, want %v", s.Sum, want)
+	}
+	if s.Max != 100*time.Microsecond {
+		t.Errorf("Max = %v, want 100µs", s.Max)
+	}
+	if want := 5050 * time.Microsecond / 101; s.Mean() != want {
+		t.Errorf("Mean = %v, want %v", s.Mean(), want)
+	}
+
+	for _, c := range []struct {
+		p    float64
+		want time.Duration // 第 p 百分位的真实值（0 与 1..100µs 共 101 个）
+	}{
+		{0, 0}, {1, time.Microsecond}, {50, 50 * time.Microsecond}, {90, 90 * time.Microsecond}, {99, 99 * time.Microsecond}, {100, 100 * time.Microsecond},
+	} {
+		got := s.Percentile(c.p)
+		if got < c.want || got > c.want+c.want/histSub || got > s.Max {
+			t.Errorf("Percentile(%v) = %v, want in [%v, %v]", c.p, got, c.want, c.want+c.want/histSub)
+		}
+	}
+
+	// 超出上限的值计入最后一个桶，Max 与 p100 仍精确
+	h.Record(100 * time.Second)
+	s = h.Snapshot()
+	if s.Max != 100*time.Second || s.Percentile(100) != 100*time.Second {
+		t.Errorf("over-range: Max = %v, p100 = %v, want 100s", s.Max, s.Percentile(100))
+	}
+	if p := s.Percentile(50); p > 100*time.Microsecond {
+		t.Errorf("over-range: p50 = %v, want ≤ 100µs", p)
+	}
+}
+
+// TestHistogramMergeReset Merge 等价于在同一直方图中记录；Reset 清零全部计数
+func TestHistogramMergeReset(t *testing.T) {
+	var a, b, all Histogram
+	for i := 1; i <= 200; i++ {
+		d := time.Duration(i*i) * time.Nanosecond
+		all.Record(d)
+		if i%3 == 0 {
+			a.Record(d)
+		} else {
+			b.Record(d)
+		}
+	}
+	m := a.Snapshot()
+	bs := b.Snapshot()
+	m.Merge(&bs)
+	want := all.Snapshot()
+	if m != want {
+		t.Errorf("merged = {Count %d Sum %v Max %v}, want {Count %d Sum %v Max %v}", m.Count, m.Sum, m.Max, want.Count, want.Sum, want.Max)
+	}
+	for _, p := range []float64{10, 50, 99, 99.9} {
+		if m.Percentile(p) != want.Percentile(p) {
+			t.Errorf("merged Percentile(%v) = %v, want %v", p, m.Percentile(p), want.Percentile(p))
+		}
+	}
+
+	all.Reset()
+	if s := all.Snapshot(); s != (HistogramSnapshot{}) {
+		t.Errorf("after Reset: Count %d Sum %v Max %v, want zero", s.Count, s.Sum, s.Max)
+	}
+	all.Record(time.Millisecond)
+	if s := all.Snapshot(); s.Count != 1 || s.Max != time.Millisecond || s.Percentile(50) != time.Millisecond {
+		t.Errorf("record after Reset: Count %d Max %v p50 %v", s.Count, s.Max, s.Percentile(50))
+	}
+}
+
+// TestPerCPUHistogramSnapshot 并发写入后 Snapshot 合并全部 stripe；Reset 清零
+func TestPerCPUHistogramSnapshot(t *testing.T) {
+	h := NewPerCPUHistogram()
+	const workers, per = 8, 1000
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < per; i++ {
+				h.Record(time.Duration(w+1) * time.Microsecond)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	s := h.Snapshot()
+	if s.Count != workers*per {
+		t.Errorf("Count = %d, want %d", s.Count, workers*per)
+	}
+	if want := per * 36 * time.Microsecond; s.Sum != want {
+		t.Errorf("Sum = %v, want %v", s.Sum, want)
+	}
+	if s.Max != workers*time.Microsecond || s.Percentile(100) != s.Max {
+		t.Errorf("Max = %v, p100 = %v, want %v", s.Max, s.Percentile(100), workers*time.Microsecond)
+	}
+
+	h.Reset()
+	if s := h.Snapshot(); s != (HistogramSnapshot{}) {
+		t.Errorf("after Reset: Count %d Sum %v Max %v, want zero", s.Count, s.Sum, s.Max)
+	}
+}
+
+// TestKeyedHistogramSnapshot 按 key 独立统计；零值无数据时 Snapshot 为 nil；Reset 保留 key
+func TestKeyedHistogramSnapshot(t *testing.T) {
+	var k KeyedHistogram
+	if s := k.Snapshot(); s != nil {
+		t.Fatalf("zero value Snapshot = %v, want nil", s)
+	}
+
+	if k.Get("a") != k.Get("a") {
+		t.Fatal("Get returned different histograms for the same key")
+	}
+	for i := 0; i < 3; i++ {
+		k.Get("a").Record(time.Millisecond)
+	}
+	k.Get("b").Record(2 * time.Millisecond)
+
+	s := k.Snapshot()
+	if len(s) != 2 {
+		t.Fatalf("Snapshot has %d keys, want 2", len(s))
+	}
+	if a := s["a"]; a.Count != 3 || a.Max != time.Millisecond {
+		t.Errorf("a: Count %d Max %v, want 3, 1ms", a.Count, a.Max)
+	}
+	if b := s["b"]; b.Count != 1 || b.Max != 2*time.Millisecond {
+		t.Errorf("b: Count %d Max %v, want 1, 2ms", b.Count, b.Max)
+	}
+
+	// 快照是副本：之后的记录不影响
+	k.Get("a").Record(time.Second)
+	if a := s["a"]; a.Count != 3 {
+		t.Errorf("snapshot changed after Record: Count %d", a.Count)
+	}
+
+	k.Reset()
+	s = k.Snapshot()
+	if len(s) != 2 {
+		t.Fatalf("after Reset Snapshot has %d keys, want 2", len(s))
+	}
+	for key, h := range s {
+		if h.Count != 0 || h.Max != 0 {
+			t.Errorf("after Reset %q: Count %d Max %v, want zero", key, h.Count, h.Max)
+		}
+	}
+}
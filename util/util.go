@@ -3,6 +3,7 @@ package util
 
 import (
 	"runtime"
+	"sync"
 	"sync/atomic"
 	"unsafe"
 )
@@ -64,3 +65,32 @@ func (c *PerCPUCounter) Read() int64 {
 	}
 	return sum
 }
+
+// KeyedCounter 按 key 分组的计数器（sync.Map + atomic.Int64）
+// 适用于 key 数量有界（如订阅模式）、写入集中在慢路径的统计场景。
+// 零值可用。
+type KeyedCounter struct {
+	m sync.Map // key → *atomic.Int64
+}
+
+// Add 为 key 累加 delta（首次写入时惰性创建计数槽）
+func (c *KeyedCounter) Add(key string, delta int64) {
+	v, ok := c.m.Load(key)
+	if !ok {
+		v, _ = c.m.LoadOrStore(key, new(atomic.Int64))
+	}
+	v.(*atomic.Int64).Add(delta)
+}
+
+// Snapshot 返回各 key 当前计数的副本（无数据时返回 nil）
+func (c *KeyedCounter) Snapshot() map[string]int64 {
+	var out map[string]int64
+	c.m.Range(func(k, v any) bool {
+		if out == nil {
+			out = make(map[string]int64)
+		}
+		out[k.(string)] = v.(*atomic.Int64).Load()
+		return true
+	})
+	return out
+}
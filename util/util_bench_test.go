@@ -2,6 +2,7 @@ package util
 
 import (
 	"testing"
+	"time"
 )
 
 // BenchmarkPerCPUCounterAdd 测量 PerCPUCounter.Add 的性能
@@ -37,3 +38,25 @@ func BenchmarkPerCPUCounterParallel(b *testing.B) {
 		}
 	})
 }
+
+// BenchmarkHistogramRecord 测量 Histogram.Record 的性能
+func BenchmarkHistogramRecord(b *testing.B) {
+	var h Histogram
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		h.Record(time.Duration(i & 0xfffff))
+	}
+}
+
+// BenchmarkPerCPUHistogramParallel 并发测试 PerCPUHistogram.Record
+func BenchmarkPerCPUHistogramParallel(b *testing.B) {
+	h := NewPerCPUHistogram()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		var d time.Duration
+		for pb.Next() {
+			h.Record(d & 0xfffff)
+			d++
+		}
+	})
+}
@@ -0,0 +1,75 @@
+package where
+
+import "github.com/uniyakcom/beat/json"
+
+// fieldsInline Fields 内联缓存的路径数（超出时追加到堆上）
+const fieldsInline = 4
+
+// Fields 同一份 JSON 负载上的字段查找缓存（零值可用，使用前 Reset）
+// 多个 Expr 依次在同一个 Fields 上求值时，相同路径只调用一次 json.GetBytes。
+// Fields 不是并发安全的。
+type Fields struct {
+	data   []byte
+	n      int
+	inline [fieldsInline]field
+	more   []field
+}
+
+// field 已查找的路径
+type field struct {
+	path string
+	res  json.Res
+	val  value
+}
+
+// Reset 以新的负载 data 清空缓存
+func (f *Fields) Reset(data []byte) {
+	f.data = data
+	f.n = 0
+	f.more = f.more[:0]
+}
+
+// Get 返回 path 对应的值（首次访问时查找并缓存）
+func (f *Fields) Get(path string) json.Res {
+	return f.lookup(path).res
+}
+
+// lookup 返回 path 的缓存项，未命中时查找并记录
+func (f *Fields) lookup(path string) *field {
+	for i := 0; i < f.n; i++ {
+		if f.inline[i].path == path {
+			return &f.inline[i]
+		}
+	}
+	for i := range f.more {
+		if f.more[i].path == path {
+			return &f.more[i]
+		}
+	}
+	var fd *field
+	if f.n < fieldsInline {
+		fd = &f.inline[f.n]
+		f.n++
+	} else {
+		f.more = append(f.more, field{})
+		fd = &f.more[len(f.more)-1]
+	}
+	res := json.GetBytes(f.data, path)
+	fd.path, fd.res, fd.val = path, res, toValue(res)
+	return fd
+}
+
+// toValue 将查找结果转为比较值（不存在视为 null）
+func toValue(r json.Res) value {
+	switch r.Type() {
+	case json.TypeNumber:
+		return value{typ: json.TypeNumber, num: r.Float64()}
+	case json.TypeString:
+		return value{typ: json.TypeString, str: r.String()}
+	case json.TypeBool:
+		return value{typ: json.TypeBool, b: r.Bool()}
+	case json.TypeArray, json.TypeObject:
+		return value{typ: r.Type(), str: r.Raw()}
+	}
+	return value{typ: json.TypeNull}
+}
@@ -0,0 +1,252 @@
+package where
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/uniyakcom/beat/json"
+)
+
+// maxDepth 表达式最大嵌套深度（括号与 ! 的层数，防止恶意输入耗尽栈）
+const maxDepth = 64
+
+// parser 递归下降解析器
+//
+//	or      = and { "||" and }
+//	and     = unary { "&&" unary }
+//	unary   = "!" unary | primary
+//	primary = "(" or ")" | operand [ cmp operand ]
+type parser struct {
+	src   string
+	pos   int
+	depth int
+	paths []string
+}
+
+// parse 解析整个表达式
+func (p *parser) parse() (*node, error) {
+	n, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	p.skip()
+	if p.pos < len(p.src) {
+		return nil, p.errorf("unexpected %q", p.src[p.pos:p.pos+1])
+	}
+	return n, nil
+}
+
+func (p *parser) or() (*node, error) {
+	l, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("||") {
+		r, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		l = &node{op: opOr, l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *parser) and() (*node, error) {
+	l, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept("&&") {
+		r, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		l = &node{op: opAnd, l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *parser) unary() (*node, error) {
+	if p.depth++; p.depth > maxDepth {
+		return nil, p.errorf("expression nested too deeply")
+	}
+	defer func() { p.depth-- }()
+	p.skip()
+	if p.pos < len(p.src) && p.src[p.pos] == '!' && !strings.HasPrefix(p.src[p.pos:], "!=") {
+		p.pos++
+		n, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &node{op: opNot, l: n}, nil
+	}
+	return p.primary()
+}
+
+func (p *parser) primary() (*node, error) {
+	if p.accept("(") {
+		n, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(")") {
+			return nil, p.errorf("missing )")
+		}
+		return n, nil
+	}
+	a, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	o, ok := p.cmp()
+	if !ok {
+		return &node{op: opTruth, a: a}, nil
+	}
+	b, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	return &node{op: o, a: a, b: b}, nil
+}
+
+// cmp 读取比较运算符（长的优先）
+func (p *parser) cmp() (op, bool) {
+	for _, c := range [...]struct {
+		tok string
+		op  op
+	}{{"==", opEq}, {"!=", opNe}, {">=", opGe}, {"<=", opLe}, {">", opGt}, {"<", opLt}} {
+		if p.accept(c.tok) {
+			return c.op, true
+		}
+	}
+	return 0, false
+}
+
+// operand 读取字段路径或字面量
+func (p *parser) operand() (operand, error) {
+	p.skip()
+	if p.pos >= len(p.src) {
+		return operand{}, p.errorf("unexpected end of expression")
+	}
+	start := p.pos
+	switch c := p.src[p.pos]; {
+	case c == '"' || c == '\'':
+		s, err := p.str(c)
+		if err != nil {
+			return operand{}, err
+		}
+		return operand{lit: value{typ: json.TypeString, str: s}}, nil
+	case c == '-' || c == '+' || isDigit(c):
+		p.pos++
+		for p.pos < len(p.src) && isNumChar(p.src[p.pos]) {
+			p.pos++
+		}
+		text := p.src[start:p.pos]
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			p.pos = start
+			return operand{}, p.errorf("invalid number %q", text)
+		}
+		return operand{lit: value{typ: json.TypeNumber, num: f}}, nil
+	case isIdentStart(c):
+		for p.pos < len(p.src) && isPathChar(p.src[p.pos]) {
+			p.pos++
+		}
+		word := p.src[start:p.pos]
+		switch word {
+		case "true", "false":
+			return operand{lit: value{typ: json.TypeBool, b: word == "true"}}, nil
+		case "null":
+			return operand{lit: value{typ: json.TypeNull}}, nil
+		}
+		if strings.HasSuffix(word, ".") || strings.Contains(word, "..") {
+			p.pos = start
+			return operand{}, p.errorf("invalid path %q", word)
+		}
+		p.addPath(word)
+		return operand{path: word}, nil
+	}
+	return operand{}, p.errorf("unexpected %q", p.src[p.pos:p.pos+1])
+}
+
+// str 读取以 q 为引号的字符串字面量（支持 \\ \" \' \n \t 转义）
+func (p *parser) str(q byte) (string, error) {
+	start := p.pos
+	p.pos++
+	var b strings.Builder
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		p.pos++
+		switch c {
+		case q:
+			return b.String(), nil
+		case '\\':
+			if p.pos >= len(p.src) {
+				break
+			}
+			e := p.src[p.pos]
+			p.pos++
+			switch e {
+			case 'n':
+				e = '\n'
+			case 't':
+				e = '\t'
+			case '\\', '"', '\'':
+			default:
+				p.pos -= 2
+				return "", p.errorf("invalid escape \\%c", e)
+			}
+			b.WriteByte(e)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	p.pos = start
+	return "", p.errorf("unterminated string")
+}
+
+// addPath 记录读取的字段路径（去重）
+func (p *parser) addPath(path string) {
+	for _, s := range p.paths {
+		if s == path {
+			return
+		}
+	}
+	p.paths = append(p.paths, path)
+}
+
+// accept 跳过空白后若下一个记号为 tok 则消费并返回 true
+func (p *parser) accept(tok string) bool {
+	p.skip()
+	if strings.HasPrefix(p.src[p.pos:], tok) {
+		p.pos += len(tok)
+		return true
+	}
+	return false
+}
+
+// skip 跳过空白
+func (p *parser) skip() {
+	for p.pos < len(p.src) && (p.src[p.pos] == ' ' || p.src[p.pos] == '\t' || p.src[p.pos] == '\n' || p.src[p.pos] == '\r') {
+		p.pos++
+	}
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return &Error{Expr: p.src, Offset: p.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isNumChar(c byte) bool {
+	return isDigit(c) || c == '.' || c == 'e' || c == 'E' || c == '-' || c == '+'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isPathChar(c byte) bool {
+	return isIdentStart(c) || isDigit(c) || c == '.' || c == '-'
+}
@@ -0,0 +1,196 @@
+// Package where 提供基于 JSON 负载字段的谓词表达式（订阅内容过滤）。
+//
+// 表达式在订阅时编译一次，求值时经 json.GetBytes 零分配按路径取值：
+//
+//	x := where.MustCompile(`amount > 100 && region == "eu"`)
+//	bus.(core.OptionSubscriber).OnWith("order.*", handle, beat.WithWhere(x))
+//
+// 语法:
+//   - 字段: json.Get 路径（点分隔键名/数组下标），如 user.id、items.0.sku
+//   - 字面量: 数字、"字符串"（或单引号）、true、false、null
+//   - 比较: == != > >= < <=（数字按数值、字符串按字典序比较；类型不同时只有 != 成立）
+//   - 逻辑: && || !，括号分组；&& 优先于 ||，均为短路求值
+//   - 单独的字段按真值判断: 存在且不为 null / false
+//
+// 不存在的字段视为 null（region == null 对缺失与显式 null 均成立）。
+// 多个表达式在同一个 Fields 上求值时，相同路径只查找一次。
+package where
+
+import (
+	"fmt"
+
+	"github.com/uniyakcom/beat/json"
+)
+
+// Expr 编译后的谓词表达式（只读，可并发求值）
+type Expr struct {
+	src   string
+	root  *node
+	paths []string
+}
+
+// Compile 编译谓词表达式
+func Compile(expr string) (*Expr, error) {
+	p := &parser{src: expr}
+	root, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	return &Expr{src: expr, root: root, paths: p.paths}, nil
+}
+
+// MustCompile 同 Compile，表达式无效时 panic（用于包级变量与字面量表达式）
+func MustCompile(expr string) *Expr {
+	x, err := Compile(expr)
+	if err != nil {
+		panic(err)
+	}
+	return x
+}
+
+// String 返回表达式源码
+func (x *Expr) String() string {
+	return x.src
+}
+
+// Paths 返回表达式读取的字段路径（去重，按首次出现顺序）
+func (x *Expr) Paths() []string {
+	return append([]string(nil), x.paths...)
+}
+
+// Match 对 JSON 负载 data 求值
+func (x *Expr) Match(data []byte) bool {
+	var f Fields
+	f.Reset(data)
+	return x.root.eval(&f)
+}
+
+// MatchFields 在 f 上求值；f 缓存的查找结果供后续表达式复用
+func (x *Expr) MatchFields(f *Fields) bool {
+	return x.root.eval(f)
+}
+
+// op 节点操作
+type op uint8
+
+const (
+	opOr op = iota
+	opAnd
+	opNot
+	opTruth // 单独的字段或字面量
+	opEq
+	opNe
+	opGt
+	opGe
+	opLt
+	opLe
+)
+
+// node 语法树节点
+type node struct {
+	op   op
+	l, r *node   // opOr/opAnd: 左右子树；opNot: l
+	a, b operand // 比较: a op b；opTruth: a
+}
+
+// operand 比较操作数: 字段（path 非空）或字面量
+type operand struct {
+	path string
+	lit  value
+}
+
+// value 参与比较的值
+type value struct {
+	typ json.Type
+	str string  // TypeString: 内容；TypeArray/TypeObject: 原始 JSON
+	num float64 // TypeNumber
+	b   bool    // TypeBool
+}
+
+// eval 求值（短路）
+func (n *node) eval(f *Fields) bool {
+	switch n.op {
+	case opOr:
+		return n.l.eval(f) || n.r.eval(f)
+	case opAnd:
+		return n.l.eval(f) && n.r.eval(f)
+	case opNot:
+		return !n.l.eval(f)
+	case opTruth:
+		v := n.a.get(f)
+		return v.typ != json.TypeNull && (v.typ != json.TypeBool || v.b)
+	}
+	return compare(n.op, n.a.get(f), n.b.get(f))
+}
+
+// get 返回操作数的值
+func (o *operand) get(f *Fields) value {
+	if o.path == "" {
+		return o.lit
+	}
+	return f.lookup(o.path).val
+}
+
+// compare 比较 a 与 b（类型不同时只有 != 成立）
+func compare(o op, a, b value) bool {
+	if a.typ != b.typ {
+		return o == opNe
+	}
+	var c int // a 与 b 的大小关系（-1/0/1）；ok=false 表示不可排序
+	ok := true
+	switch a.typ {
+	case json.TypeNumber:
+		switch {
+		case a.num < b.num:
+			c = -1
+		case a.num > b.num:
+			c = 1
+		}
+	case json.TypeString:
+		switch {
+		case a.str < b.str:
+			c = -1
+		case a.str > b.str:
+			c = 1
+		}
+	case json.TypeBool:
+		if a.b != b.b {
+			c = 1
+		}
+		ok = false
+	case json.TypeNull:
+		ok = false
+	default: // 数组/对象: 按原始 JSON 判等
+		if a.str != b.str {
+			c = 1
+		}
+		ok = false
+	}
+	switch o {
+	case opEq:
+		return c == 0
+	case opNe:
+		return c != 0
+	case opGt:
+		return ok && c > 0
+	case opGe:
+		return ok && c >= 0
+	case opLt:
+		return ok && c < 0
+	case opLe:
+		return ok && c <= 0
+	}
+	return false
+}
+
+// Error 表达式编译错误
+type Error struct {
+	Expr   string // 表达式源码
+	Offset int    // 出错位置（字节偏移）
+	Msg    string
+}
+
+// Error 实现 error
+func (e *Error) Error() string {
+	return fmt.Sprintf("where: %s at offset %d in %q", e.Msg, e.Offset, e.Expr)
+}